@@ -23,9 +23,27 @@ import (
 	"golang.org/x/crypto/acme"
 )
 
+// Generate the certificate and record the result of the attempt
 func Generate(db *database.Database, cert *certificate.Certificate) (
 	err error) {
 
+	err = generate(db, cert)
+
+	e := cert.RecordRenew(db, err)
+	if err != nil {
+		return
+	}
+	if e != nil {
+		err = e
+		return
+	}
+
+	return
+}
+
+func generate(db *database.Database, cert *certificate.Certificate) (
+	err error) {
+
 	acmeType := cert.AcmeType
 	if acmeType == "" {
 		acmeType = certificate.AcmeHTTP
@@ -38,6 +56,7 @@ func Generate(db *database.Database, cert *certificate.Certificate) (
 		"domains":     cert.AcmeDomains,
 		"acme_type":   acmeType,
 		"acme_auth":   acmeAuth,
+		"directory":   cert.AcmeDirectory,
 	}).Info("acme: Generating acme certificate")
 
 	if cert.AcmeDomains == nil || len(cert.AcmeDomains) == 0 {
@@ -70,6 +89,10 @@ func Generate(db *database.Database, cert *certificate.Certificate) (
 			dnsSvc = &dns.Oracle{}
 		} else if acmeAuth == certificate.AcmeGoogleCloud {
 			dnsSvc = &dns.Google{}
+		} else if acmeAuth == certificate.AcmeRfc2136 {
+			dnsSvc = &dns.Rfc2136{}
+		} else if acmeAuth == certificate.AcmePowerDns {
+			dnsSvc = &dns.PowerDns{}
 		} else {
 			err = &errortypes.UnknownError{
 				errors.Wrapf(err,
@@ -123,11 +146,21 @@ func Generate(db *database.Database, cert *certificate.Certificate) (
 		}
 	}
 
+	client, err := newClient(cert, acctKey)
+	if err != nil {
+		return
+	}
+
 	acct := &acme.Account{}
+	if cert.AcmeEmail != "" {
+		acct.Contact = []string{"mailto:" + cert.AcmeEmail}
+	}
 
-	client := &acme.Client{
-		DirectoryURL: AcmeDirectory,
-		Key:          acctKey,
+	if !cert.AcmeEabSecret.IsZero() {
+		acct.ExternalAccountBinding, err = getEab(db, cert.AcmeEabSecret)
+		if err != nil {
+			return
+		}
 	}
 
 	_, err = client.Register(context.Background(), acct, acme.AcceptTOS)
@@ -191,6 +224,11 @@ func Generate(db *database.Database, cert *certificate.Certificate) (
 					authzChal = c
 					break
 				}
+			} else if acmeType == certificate.AcmeTLSALPN {
+				if c.Type == "tls-alpn-01" {
+					authzChal = c
+					break
+				}
 			} else {
 				if c.Type == "http-01" {
 					authzChal = c
@@ -272,6 +310,20 @@ func Generate(db *database.Database, cert *certificate.Certificate) (
 			}
 
 			time.Sleep(time.Duration(settings.Acme.DnsDelay) * time.Second)
+		} else if acmeType == certificate.AcmeTLSALPN {
+			chal, err = newAlpnChallenge(client, authzChal.Token,
+				authz.Identifier.Value)
+			if err != nil {
+				revoke(client, authzUrls)
+				return
+			}
+
+			err = chal.Upsert(db)
+			if err != nil {
+				return
+			}
+
+			time.Sleep(300 * time.Millisecond)
 		} else {
 			resp, e := client.HTTP01ChallengeResponse(authzChal.Token)
 			if e != nil {
@@ -384,7 +436,7 @@ func create(db *database.Database, cert *certificate.Certificate,
 		}
 	}
 
-	derChain, _, err := client.CreateOrderCert(
+	derChain, certUrl, err := client.CreateOrderCert(
 		context.Background(),
 		order.FinalizeURL,
 		csr,
@@ -397,6 +449,13 @@ func create(db *database.Database, cert *certificate.Certificate,
 		return
 	}
 
+	if cert.AcmeChain != "" {
+		derChain, err = selectChain(client, certUrl, derChain, cert.AcmeChain)
+		if err != nil {
+			return
+		}
+	}
+
 	certPem := ""
 
 	for _, der := range derChain {
@@ -1,17 +1,39 @@
 package acme
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
 	"time"
 
+	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/event"
+	"golang.org/x/crypto/acme"
 )
 
 type Challenge struct {
-	Id        string    `bson:"_id"`
-	Resource  string    `bson:"resource"`
-	Timestamp time.Time `bson:"timestamp"`
+	Id          string    `bson:"_id"`
+	Resource    string    `bson:"resource"`
+	Certificate string    `bson:"certificate,omitempty"`
+	Key         string    `bson:"key,omitempty"`
+	Timestamp   time.Time `bson:"timestamp"`
+}
+
+func (c *Challenge) Upsert(db *database.Database) (err error) {
+	coll := db.AcmeChallenges()
+
+	err = coll.Upsert(&bson.M{
+		"_id": c.Id,
+	}, c)
+	if err != nil {
+		return
+	}
+
+	return
 }
 
 func (c *Challenge) Insert(db *database.Database) (err error) {
@@ -58,3 +80,64 @@ func (c *ChallengeMsg) Publish(db *database.Database) (err error) {
 
 	return
 }
+
+func newAlpnChallenge(client *acme.Client, token, domain string) (
+	chal *Challenge, err error) {
+
+	tlsCert, err := client.TLSALPN01ChallengeCert(token, domain)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "acme: Failed to create challenge certificate"),
+		}
+		return
+	}
+
+	keyByte, err := x509.MarshalPKCS8PrivateKey(tlsCert.PrivateKey)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "acme: Failed to marshal challenge key"),
+		}
+		return
+	}
+
+	certPem := ""
+	for _, der := range tlsCert.Certificate {
+		certPem += string(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: der,
+		}))
+	}
+
+	chal = &Challenge{
+		Id:          AlpnPrefix + strings.ToLower(domain),
+		Certificate: certPem,
+		Key: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "PRIVATE KEY",
+			Bytes: keyByte,
+		})),
+		Timestamp: time.Now(),
+	}
+
+	return
+}
+
+// Challenge certificate for tls-alpn-01 validation of a domain, shared
+// across nodes through the challenges collection
+func GetAlpnCertificate(domain string) (cert *tls.Certificate, err error) {
+	chal, err := GetChallenge(AlpnPrefix + strings.ToLower(domain))
+	if err != nil {
+		return
+	}
+
+	keypair, err := tls.X509KeyPair(
+		[]byte(chal.Certificate), []byte(chal.Key))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "acme: Failed to load challenge certificate"),
+		}
+		return
+	}
+	cert = &keypair
+
+	return
+}
@@ -3,4 +3,6 @@ package acme
 const (
 	AcmeDirectory = "https://acme-v02.api.letsencrypt.org/directory"
 	AcmePath      = "/.well-known/acme-challenge/"
+	AlpnProto     = "acme-tls/1"
+	AlpnPrefix    = "tls-alpn-01:"
 )
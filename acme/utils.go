@@ -2,22 +2,30 @@ package acme
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/pem"
 	"net"
+	"net/http"
 	"slices"
 	"strings"
 	"time"
 
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/certificate"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/secret"
 	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/acme"
 )
 
@@ -176,3 +184,145 @@ func newEcCsr(domains []string) (csr []byte, keyPem []byte, err error) {
 
 	return
 }
+
+func newClient(cert *certificate.Certificate, acctKey crypto.Signer) (
+	client *acme.Client, err error) {
+
+	directory := cert.AcmeDirectory
+	if directory == "" {
+		directory = AcmeDirectory
+	}
+
+	client = &acme.Client{
+		DirectoryURL: directory,
+		Key:          acctKey,
+	}
+
+	if cert.AcmeDirectoryCa != "" {
+		rootCas, e := x509.SystemCertPool()
+		if e != nil {
+			rootCas = x509.NewCertPool()
+		}
+
+		if !rootCas.AppendCertsFromPEM([]byte(cert.AcmeDirectoryCa)) {
+			err = &errortypes.ParseError{
+				errors.New("acme: Failed to parse directory CA"),
+			}
+			return
+		}
+
+		client.HTTPClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					MinVersion: tls.VersionTLS12,
+					RootCAs:    rootCas,
+				},
+			},
+		}
+	}
+
+	return
+}
+
+func getEab(db *database.Database, secrId bson.ObjectID) (
+	eab *acme.ExternalAccountBinding, err error) {
+
+	secr, err := secret.Get(db, secrId)
+	if err != nil {
+		return
+	}
+
+	eab, err = parseEab(secr)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func parseEab(secr *secret.Secret) (
+	eab *acme.ExternalAccountBinding, err error) {
+
+	if secr.Type != secret.AcmeEab {
+		err = &errortypes.ParseError{
+			errors.New("acme: Secret type not external account binding"),
+		}
+		return
+	}
+
+	hmacKey := strings.TrimRight(secr.Value, "=")
+	key, err := base64.RawURLEncoding.DecodeString(hmacKey)
+	if err != nil {
+		key, err = base64.RawStdEncoding.DecodeString(hmacKey)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "acme: Failed to decode EAB HMAC key"),
+			}
+			return
+		}
+	}
+
+	eab = &acme.ExternalAccountBinding{
+		KID: secr.Key,
+		Key: key,
+	}
+
+	return
+}
+
+func chainIssuer(derChain [][]byte) string {
+	if len(derChain) == 0 {
+		return ""
+	}
+
+	cert, err := x509.ParseCertificate(derChain[len(derChain)-1])
+	if err != nil {
+		return ""
+	}
+
+	return cert.Issuer.CommonName
+}
+
+// Select the chain with a top certificate issued by the preferred issuer
+// common name, the default chain is used when no alternate matches
+func selectChain(client *acme.Client, certUrl string, derChain [][]byte,
+	preferred string) (chain [][]byte, err error) {
+
+	chain = derChain
+	if chainIssuer(derChain) == preferred {
+		return
+	}
+
+	alternates, err := client.ListCertAlternates(
+		context.Background(), certUrl)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "acme: Failed to list alternate chains"),
+		}
+		return
+	}
+
+	for _, alternate := range alternates {
+		altChain, e := client.FetchCert(
+			context.Background(), alternate, true)
+		if e != nil {
+			err = &errortypes.RequestError{
+				errors.Wrap(e, "acme: Failed to fetch alternate chain"),
+			}
+			return
+		}
+
+		if chainIssuer(altChain) == preferred {
+			chain = altChain
+			return
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"preferred_chain": preferred,
+		"default_chain":   chainIssuer(derChain),
+	}).Warning("acme: Preferred chain not available, using default")
+
+	return
+}
@@ -0,0 +1,335 @@
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pritunl/pritunl-zero/certificate"
+	"github.com/pritunl/pritunl-zero/secret"
+	"golang.org/x/crypto/acme"
+)
+
+type acmeTestJws struct {
+	Protected string `json:"protected"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+type acmeTestServer struct {
+	t        *testing.T
+	server   *httptest.Server
+	accounts []*acmeTestJws
+	chains   map[string][][]byte
+	alts     map[string][]string
+}
+
+func newAcmeTestServer(t *testing.T) (srv *acmeTestServer) {
+	srv = &acmeTestServer{
+		t:      t,
+		chains: map[string][][]byte{},
+		alts:   map[string][]string{},
+	}
+	srv.server = httptest.NewServer(http.HandlerFunc(srv.handle))
+	t.Cleanup(srv.server.Close)
+
+	return
+}
+
+func (s *acmeTestServer) url(path string) string {
+	return s.server.URL + path
+}
+
+func (s *acmeTestServer) readJws(r *http.Request) (jws *acmeTestJws) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+
+	jws = &acmeTestJws{}
+	err = json.Unmarshal(body, jws)
+	if err != nil {
+		s.t.Error(err)
+		return
+	}
+
+	return
+}
+
+func (s *acmeTestServer) handle(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce",
+		fmt.Sprintf("nonce-%d", time.Now().UnixNano()))
+
+	switch r.URL.Path {
+	case "/directory":
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"newNonce":   s.url("/nonce"),
+			"newAccount": s.url("/account"),
+			"newOrder":   s.url("/order"),
+			"revokeCert": s.url("/revoke"),
+			"keyChange":  s.url("/key-change"),
+		})
+		return
+	case "/nonce":
+		w.WriteHeader(http.StatusOK)
+		return
+	case "/account":
+		s.accounts = append(s.accounts, s.readJws(r))
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", s.url("/account/1"))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"status":"valid"}`))
+		return
+	}
+
+	chain, ok := s.chains[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.readJws(r)
+
+	for _, alt := range s.alts[r.URL.Path] {
+		w.Header().Add("Link", fmt.Sprintf(
+			"<%s>;rel=\"alternate\"", s.url(alt)))
+	}
+	w.Header().Set("Content-Type", "application/pem-certificate-chain")
+	for _, der := range chain {
+		_ = pem.Encode(w, &pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: der,
+		})
+	}
+}
+
+func acmeTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func acmeTestCert(t *testing.T, subject, issuer string) []byte {
+	key := acmeTestKey(t)
+
+	templ := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			CommonName: subject,
+		},
+		NotBefore: time.Now().Add(-1 * time.Hour),
+		NotAfter:  time.Now().Add(1 * time.Hour),
+	}
+	parent := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{
+			CommonName: issuer,
+		},
+	}
+
+	der, err := x509.CreateCertificate(
+		rand.Reader, templ, parent, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return der
+}
+
+func TestParseEab(t *testing.T) {
+	hmacKey := []byte("0123456789abcdef0123456789abcdef")
+
+	for _, value := range []string{
+		base64.RawURLEncoding.EncodeToString(hmacKey),
+		base64.StdEncoding.EncodeToString(hmacKey),
+	} {
+		eab, err := parseEab(&secret.Secret{
+			Type:  secret.AcmeEab,
+			Key:   "kid-1",
+			Value: value,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if eab.KID != "kid-1" || string(eab.Key) != string(hmacKey) {
+			t.Fatalf("unexpected binding %#v", eab)
+		}
+	}
+
+	_, err := parseEab(&secret.Secret{
+		Type:  secret.AWS,
+		Key:   "kid-1",
+		Value: base64.RawURLEncoding.EncodeToString(hmacKey),
+	})
+	if err == nil {
+		t.Fatal("secret with wrong type accepted")
+	}
+
+	_, err = parseEab(&secret.Secret{
+		Type:  secret.AcmeEab,
+		Key:   "kid-1",
+		Value: "not base64!",
+	})
+	if err == nil {
+		t.Fatal("invalid hmac key accepted")
+	}
+}
+
+func TestEabRegister(t *testing.T) {
+	srv := newAcmeTestServer(t)
+
+	hmacKey := []byte("0123456789abcdef0123456789abcdef")
+	eab, err := parseEab(&secret.Secret{
+		Type:  secret.AcmeEab,
+		Key:   "kid-1",
+		Value: base64.RawURLEncoding.EncodeToString(hmacKey),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newClient(&certificate.Certificate{
+		AcmeDirectory: srv.url("/directory"),
+	}, acmeTestKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Register(context.Background(), &acme.Account{
+		Contact:                []string{"mailto:admin@example.com"},
+		ExternalAccountBinding: eab,
+	}, acme.AcceptTOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srv.accounts) != 1 {
+		t.Fatalf("expected one account request, got %d", len(srv.accounts))
+	}
+
+	payloadData, err := base64.RawURLEncoding.DecodeString(
+		srv.accounts[0].Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := struct {
+		Contact                []string     `json:"contact"`
+		ExternalAccountBinding *acmeTestJws `json:"externalAccountBinding"`
+	}{}
+	err = json.Unmarshal(payloadData, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binding := payload.ExternalAccountBinding
+	if binding == nil {
+		t.Fatal("account request missing external account binding")
+	}
+
+	protectedData, err := base64.RawURLEncoding.DecodeString(
+		binding.Protected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protected := struct {
+		Algorithm string `json:"alg"`
+		KeyId     string `json:"kid"`
+		Url       string `json:"url"`
+	}{}
+	err = json.Unmarshal(protectedData, &protected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if protected.Algorithm != "HS256" || protected.KeyId != "kid-1" ||
+		protected.Url != srv.url("/account") {
+
+		t.Fatalf("unexpected binding header %s", protectedData)
+	}
+
+	mac := hmac.New(sha256.New, hmacKey)
+	mac.Write([]byte(binding.Protected + "." + binding.Payload))
+	sig, err := base64.RawURLEncoding.DecodeString(binding.Signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		t.Fatal("external account binding signature invalid")
+	}
+}
+
+func TestSelectChain(t *testing.T) {
+	srv := newAcmeTestServer(t)
+
+	leaf := acmeTestCert(t, "zero.example.com", "Intermediate")
+	defaultChain := [][]byte{
+		leaf,
+		acmeTestCert(t, "Intermediate", "Root X1"),
+	}
+	altChain := [][]byte{
+		leaf,
+		acmeTestCert(t, "Intermediate", "Root X2"),
+	}
+
+	srv.chains["/cert/1"] = defaultChain
+	srv.chains["/cert/1/alt"] = altChain
+	srv.alts["/cert/1"] = []string{"/cert/1/alt"}
+
+	client, err := newClient(&certificate.Certificate{
+		AcmeDirectory: srv.url("/directory"),
+	}, acmeTestKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.KID = acme.KeyID(srv.url("/account/1"))
+
+	chain, err := selectChain(client, srv.url("/cert/1"),
+		defaultChain, "Root X2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainIssuer(chain) != "Root X2" {
+		t.Fatalf("expected alternate chain, got %s", chainIssuer(chain))
+	}
+
+	chain, err = selectChain(client, srv.url("/cert/1"),
+		defaultChain, "Root X1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainIssuer(chain) != "Root X1" {
+		t.Fatalf("expected default chain, got %s", chainIssuer(chain))
+	}
+
+	chain, err = selectChain(client, srv.url("/cert/1"),
+		defaultChain, "Root X3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainIssuer(chain) != "Root X1" {
+		t.Fatalf("expected default chain fallback, got %s",
+			chainIssuer(chain))
+	}
+}
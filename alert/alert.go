@@ -9,15 +9,19 @@ import (
 )
 
 type Alert struct {
-	Id        bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name      string        `bson:"name" json:"name"`
-	Roles     []string      `bson:"roles" json:"roles"`
-	Resource  string        `bson:"resource" json:"resource"`
-	Level     int           `bson:"level" json:"level"`
-	Frequency int           `bson:"frequency" json:"frequency"`
-	Ignores   []string      `bson:"ignores" json:"ignores"`
-	ValueInt  int           `bson:"value_int" json:"value_int"`
-	ValueStr  string        `bson:"value_str" json:"value_str"`
+	Id         bson.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name       string          `bson:"name" json:"name"`
+	Roles      []string        `bson:"roles" json:"roles"`
+	Views      []bson.ObjectID `bson:"views" json:"views"`
+	Resource   string          `bson:"resource" json:"resource"`
+	Level      int             `bson:"level" json:"level"`
+	Frequency  int             `bson:"frequency" json:"frequency"`
+	Ignores    []string        `bson:"ignores" json:"ignores"`
+	ValueInt   int             `bson:"value_int" json:"value_int"`
+	ValueStr   string          `bson:"value_str" json:"value_str"`
+	Duration   int             `bson:"duration" json:"duration"`
+	ClearValue int             `bson:"clear_value" json:"clear_value"`
+	Aggregate  string          `bson:"aggregate" json:"aggregate"`
 }
 
 func (a *Alert) Validate(db *database.Database) (
@@ -36,6 +40,27 @@ func (a *Alert) Validate(db *database.Database) (
 		a.Roles = []string{}
 	}
 
+	if a.Views == nil {
+		a.Views = []bson.ObjectID{}
+	}
+
+	views := []bson.ObjectID{}
+	err = db.Views().Distinct(
+		db,
+		"_id",
+		&bson.M{
+			"_id": &bson.M{
+				"$in": a.Views,
+			},
+		},
+	).Decode(&views)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	a.Views = views
+
 	if a.Frequency == 0 {
 		a.Frequency = 300
 	}
@@ -125,10 +150,80 @@ func (a *Alert) Validate(db *database.Database) (
 		}
 		a.ValueInt = 0
 		break
-	case CheckHttpFailed:
+	case CheckHttpFailed, CheckTcpFailed, CheckTlsFailed, CheckDnsFailed:
 		a.ValueInt = 0
 		a.ValueStr = ""
 		break
+	case SystemProcesses:
+		if a.ValueInt < 1 || a.ValueInt > 10000000 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Alert value is invalid",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case SystemPackageUpdates:
+		if a.ValueInt < 1 || a.ValueInt > 100000 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Alert value is invalid",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case LoadPerCore:
+		if a.ValueInt < 1 || a.ValueInt > 10000 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Alert value is invalid",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case NetworkErrorRate:
+		if a.ValueInt < 1 || a.ValueInt > 100 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Alert value is invalid",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case NetworkThroughput:
+		if a.ValueInt < 1 || a.ValueInt > 1000000 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Alert value is invalid",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case DiskIoAwait:
+		if a.ValueInt < 1 || a.ValueInt > 600000 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Alert value is invalid",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
+	case DiskIoUtilization:
+		if a.ValueInt < 1 || a.ValueInt > 100 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_value_invalid",
+				Message: "Alert value is invalid",
+			}
+			return
+		}
+		a.ValueStr = ""
+		break
 	default:
 		errData = &errortypes.ErrorData{
 			Error:   "alert_resource_invalid",
@@ -137,6 +232,48 @@ func (a *Alert) Validate(db *database.Database) (
 		return
 	}
 
+	switch a.Resource {
+	case SystemCpuLevel, SystemMemoryLevel, SystemSwapLevel,
+		SystemHugePagesLevel, DiskUsageLevel, SystemProcesses,
+		LoadPerCore, NetworkErrorRate, NetworkThroughput, DiskIoAwait,
+		DiskIoUtilization:
+
+		if a.Duration < 0 || a.Duration > 1440 {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_duration_invalid",
+				Message: "Alert duration must be between 0 and 1440 minutes",
+			}
+			return
+		}
+
+		if a.ClearValue < 0 || a.ClearValue >= a.ValueInt {
+			errData = &errortypes.ErrorData{
+				Error:   "alert_clear_value_invalid",
+				Message: "Alert clear value must be less than alert value",
+			}
+			return
+		}
+
+		switch a.Aggregate {
+		case Average, Maximum, Percentile95:
+			break
+		case "":
+			a.Aggregate = Average
+			break
+		default:
+			errData = &errortypes.ErrorData{
+				Error:   "alert_aggregate_invalid",
+				Message: "Alert aggregate is invalid",
+			}
+			return
+		}
+		break
+	default:
+		a.Duration = 0
+		a.ClearValue = 0
+		a.Aggregate = ""
+	}
+
 	switch a.Level {
 	case Low, Medium, High:
 		break
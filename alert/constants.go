@@ -16,4 +16,25 @@ const (
 	DiskUsageLevel       = "disk_usage_level"
 	KmsgKeyword          = "kmsg_keyword"
 	CheckHttpFailed      = "check_http_failed"
+	CheckTcpFailed       = "check_tcp_failed"
+	CheckTlsFailed       = "check_tls_failed"
+	CheckDnsFailed       = "check_dns_failed"
+	SystemProcesses      = "system_processes"
+	SystemPackageUpdates = "system_package_updates"
+	LoadPerCore          = "load_per_core"
+	NetworkErrorRate     = "network_error_rate"
+	NetworkThroughput    = "network_throughput"
+	DiskIoAwait          = "diskio_await"
+	DiskIoUtilization    = "diskio_utilization"
+)
+
+const (
+	CertificateExpire      = "certificate_expire"
+	CertificateRenewFailed = "certificate_renew_failed"
+)
+
+const (
+	Average      = "avg"
+	Maximum      = "max"
+	Percentile95 = "p95"
 )
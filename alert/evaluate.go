@@ -0,0 +1,125 @@
+package alert
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+type Sample struct {
+	Timestamp time.Time
+	Value     float64
+}
+
+func AggregateSamples(samples []*Sample, method string) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	switch method {
+	case Maximum:
+		value := samples[0].Value
+		for _, sample := range samples[1:] {
+			value = math.Max(value, sample.Value)
+		}
+		return value
+	case Percentile95:
+		values := make([]float64, len(samples))
+		for i, sample := range samples {
+			values[i] = sample.Value
+		}
+		sort.Float64s(values)
+
+		index := int(math.Ceil(0.95*float64(len(values)))) - 1
+		if index < 0 {
+			index = 0
+		}
+		return values[index]
+	default:
+		total := 0.0
+		for _, sample := range samples {
+			total += sample.Value
+		}
+		return total / float64(len(samples))
+	}
+}
+
+func (a *Alert) GetDuration() time.Duration {
+	return time.Duration(a.Duration) * time.Minute
+}
+
+func (a *Alert) GetClearValue() int {
+	if a.ClearValue > 0 && a.ClearValue < a.ValueInt {
+		return a.ClearValue
+	}
+	return a.ValueInt
+}
+
+// Evaluates a level alert over the samples ending at now. Without a
+// duration only the latest sample is used. A new alert requires samples
+// covering the full duration to reach the alert value and a firing alert
+// remains active until the aggregate drops below the clear value.
+func (a *Alert) Evaluate(samples []*Sample, now time.Time,
+	firing bool) (active bool, value float64) {
+
+	window := []*Sample{}
+
+	if a.Duration <= 0 {
+		var latest *Sample
+		for _, sample := range samples {
+			if sample.Timestamp.After(now) {
+				continue
+			}
+			if latest == nil || sample.Timestamp.After(latest.Timestamp) {
+				latest = sample
+			}
+		}
+		if latest != nil {
+			window = append(window, latest)
+		}
+	} else {
+		start := now.Add(-a.GetDuration())
+		covered := false
+
+		for _, sample := range samples {
+			if !sample.Timestamp.After(start) ||
+				sample.Timestamp.After(now) {
+
+				continue
+			}
+
+			if !sample.Timestamp.After(start.Add(time.Minute)) {
+				covered = true
+			}
+			window = append(window, sample)
+		}
+
+		if !covered && !firing {
+			return
+		}
+	}
+
+	if len(window) == 0 {
+		active = firing
+		return
+	}
+
+	value = AggregateSamples(window, a.Aggregate)
+
+	if firing {
+		active = value >= float64(a.GetClearValue())
+	} else {
+		active = value >= float64(a.ValueInt)
+	}
+
+	return
+}
+
+func MaxDuration(alerts []*Alert) (duration time.Duration) {
+	for _, alrt := range alerts {
+		if alrt.GetDuration() > duration {
+			duration = alrt.GetDuration()
+		}
+	}
+	return
+}
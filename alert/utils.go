@@ -200,6 +200,63 @@ func GetRoles(db *database.Database, roles []string) (
 	return
 }
 
+// Alerts targeting any of the roles or views
+func GetTargets(db *database.Database, roles []string,
+	views []bson.ObjectID) (alerts []*Alert, err error) {
+
+	coll := db.Alerts()
+	alerts = []*Alert{}
+
+	if roles == nil {
+		roles = []string{}
+	}
+	if views == nil {
+		views = []bson.ObjectID{}
+	}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{
+			"$or": []*bson.M{
+				&bson.M{
+					"roles": &bson.M{
+						"$in": roles,
+					},
+				},
+				&bson.M{
+					"views": &bson.M{
+						"$in": views,
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		alrt := &Alert{}
+		err = cursor.Decode(alrt)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		alerts = append(alerts, alrt)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func GetRolesMapped(db *database.Database, rolesSet set.Set) (
 	alertsMap map[string][]*Alert, err error) {
 
@@ -7,6 +7,7 @@ import (
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/device"
+	"github.com/pritunl/pritunl-zero/silence"
 	"github.com/pritunl/pritunl-zero/user"
 	"github.com/sirupsen/logrus"
 )
@@ -22,6 +23,7 @@ type Alert struct {
 	Resource   string        `bson:"resource" json:"resource"`
 	Message    string        `bson:"message" json:"message"`
 	Frequency  time.Duration `bson:"frequency" json:"frequency"`
+	Code       string        `bson:"code" json:"code"`
 }
 
 func (a *Alert) GetFrequency() (frequency time.Duration) {
@@ -45,15 +47,10 @@ func (a *Alert) DocId() string {
 }
 
 func (a *Alert) Key(devc *device.Device) string {
-	timestamp := a.Timestamp.Unix()
-	timekey := timestamp - (timestamp % int64(a.GetFrequency().Seconds()))
-
 	return fmt.Sprintf(
-		"%s-%s-%s-%d",
-		a.Source.Hex(),
-		a.Resource,
+		"%s-%s",
+		a.Id,
 		devc.Id.Hex(),
-		timekey,
 	)
 }
 
@@ -80,6 +77,10 @@ func (a *Alert) Lock(db *database.Database, devc *device.Device) (
 }
 
 func (a *Alert) FormattedTextMessage() string {
+	if a.Code != "" {
+		return fmt.Sprintf("%s:%s == %s (reply ACK %s to acknowledge)",
+			a.Name, a.SourceName, a.Message, a.Code)
+	}
 	return fmt.Sprintf("%s:%s == %s", a.Name, a.SourceName, a.Message)
 }
 
@@ -105,6 +106,16 @@ func (a *Alert) Send(db *database.Database, roles []string) (err error) {
 		return
 	}
 
+	silenced, err := silence.Silenced(db, a.Source, a.SourceName,
+		a.Roles, a.Resource)
+	if err != nil {
+		return
+	}
+
+	if silenced {
+		return
+	}
+
 	users, _, err := user.GetAll(db, &bson.M{
 		"roles": &bson.D{
 			{"$in", roles},
@@ -171,7 +182,7 @@ func (a *Alert) Send(db *database.Database, roles []string) (err error) {
 	return
 }
 
-func New(roles []string, source bson.ObjectID,
+func New(roles []string, source, alertId bson.ObjectID, key,
 	name, sourceName, resource, message string, level int,
 	frequency time.Duration) {
 
@@ -192,7 +203,20 @@ func New(roles []string, source bson.ObjectID,
 
 	alrt.Id = alrt.DocId()
 
-	err := alrt.Send(db, roles)
+	inc, err := fireIncident(db, alrt, alertId, key)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("alert: Failed to update incident")
+		return
+	}
+
+	if inc.State == Acknowledged {
+		return
+	}
+	alrt.Code = inc.Code
+
+	err = alrt.Send(db, roles)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
 			"error": err,
@@ -0,0 +1,7 @@
+package alertevent
+
+const (
+	Firing       = "firing"
+	Acknowledged = "acknowledged"
+	Resolved     = "resolved"
+)
@@ -0,0 +1,503 @@
+package alertevent
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/device"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/user"
+	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type Incident struct {
+	Id               bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Alert            bson.ObjectID `bson:"alert" json:"alert"`
+	Source           bson.ObjectID `bson:"source" json:"source"`
+	SourceName       string        `bson:"source_name" json:"source_name"`
+	Key              string        `bson:"key" json:"key"`
+	Name             string        `bson:"name" json:"name"`
+	Roles            []string      `bson:"roles" json:"roles"`
+	Resource         string        `bson:"resource" json:"resource"`
+	Level            int           `bson:"level" json:"level"`
+	Message          string        `bson:"message" json:"message"`
+	Frequency        time.Duration `bson:"frequency" json:"frequency"`
+	State            string        `bson:"state" json:"state"`
+	Open             bool          `bson:"open" json:"open"`
+	Code             string        `bson:"code" json:"code"`
+	Start            time.Time     `bson:"start" json:"start"`
+	LastSeen         time.Time     `bson:"last_seen" json:"last_seen"`
+	Acknowledged     time.Time     `bson:"acknowledged" json:"acknowledged"`
+	AcknowledgedBy   bson.ObjectID `bson:"acknowledged_by" json:"acknowledged_by"`
+	AcknowledgedName string        `bson:"acknowledged_name" json:"acknowledged_name"`
+	Resolved         time.Time     `bson:"resolved" json:"resolved"`
+}
+
+func (i *Incident) Acknowledge(db *database.Database, usr *user.User) (
+	errData *errortypes.ErrorData, err error) {
+
+	if i.State != Firing {
+		errData = &errortypes.ErrorData{
+			Error:   "incident_not_firing",
+			Message: "Only firing incidents can be acknowledged",
+		}
+		return
+	}
+
+	coll := db.AlertsIncident()
+	now := time.Now()
+
+	resp, err := coll.UpdateOne(db, &bson.M{
+		"_id":   i.Id,
+		"state": Firing,
+	}, &bson.M{
+		"$set": &bson.M{
+			"state":             Acknowledged,
+			"acknowledged":      now,
+			"acknowledged_by":   usr.Id,
+			"acknowledged_name": usr.Username,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if resp.MatchedCount == 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "incident_not_firing",
+			Message: "Only firing incidents can be acknowledged",
+		}
+		return
+	}
+
+	i.State = Acknowledged
+	i.Acknowledged = now
+	i.AcknowledgedBy = usr.Id
+	i.AcknowledgedName = usr.Username
+
+	return
+}
+
+func (i *Incident) Resolve(db *database.Database) (err error) {
+	if !i.Open {
+		return
+	}
+
+	i.State = Resolved
+	i.Open = false
+	i.Resolved = time.Now()
+
+	err = i.CommitFields(db, set.NewSet(
+		"state",
+		"open",
+		"resolved",
+	))
+	if err != nil {
+		return
+	}
+
+	alrt := &Alert{
+		Id:         i.Id.Hex() + "-resolved",
+		Name:       i.Name,
+		Timestamp:  i.Resolved,
+		Roles:      i.Roles,
+		Source:     i.Source,
+		SourceName: i.SourceName,
+		Level:      i.Level,
+		Resource:   i.Resource,
+		Message:    "Resolved: " + i.Message,
+		Frequency:  i.Frequency,
+	}
+
+	err = alrt.Send(db, i.Roles)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (i *Incident) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.AlertsIncident()
+
+	err = coll.CommitFields(i.Id, i, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (i *Incident) Insert(db *database.Database) (err error) {
+	coll := db.AlertsIncident()
+
+	_, err = coll.InsertOne(db, i)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// Generate an acknowledge code not used by another open incident
+func newCode(db *database.Database) (code string, err error) {
+	coll := db.AlertsIncident()
+
+	for n := 0; n < 20; n++ {
+		code = strconv.Itoa(utils.RandInt(1000, 9999))
+
+		count, e := coll.CountDocuments(db, &bson.M{
+			"code": code,
+			"open": true,
+		})
+		if e != nil {
+			err = database.ParseError(e)
+			return
+		}
+
+		if count == 0 {
+			return
+		}
+	}
+
+	err = &errortypes.UnknownError{
+		errors.New("alertevent: Failed to generate unique code"),
+	}
+	return
+}
+
+// Opens an incident for the alert or updates the existing open incident
+func fireIncident(db *database.Database, alrt *Alert,
+	alertId bson.ObjectID, key string) (inc *Incident, err error) {
+
+	coll := db.AlertsIncident()
+	now := time.Now()
+
+	for n := 0; n < 2; n++ {
+		inc = &Incident{}
+		err = coll.FindOneAndUpdate(db, &bson.M{
+			"source": alrt.Source,
+			"alert":  alertId,
+			"key":    key,
+			"open":   true,
+		}, &bson.M{
+			"$set": &bson.M{
+				"message":   alrt.Message,
+				"last_seen": now,
+			},
+		}, options.FindOneAndUpdate().SetReturnDocument(
+			options.After)).Decode(inc)
+		if err == nil {
+			return
+		}
+
+		err = database.ParseError(err)
+		if _, ok := err.(*database.NotFoundError); !ok {
+			return
+		}
+
+		code, e := newCode(db)
+		if e != nil {
+			err = e
+			return
+		}
+
+		inc = &Incident{
+			Id:         bson.NewObjectID(),
+			Alert:      alertId,
+			Source:     alrt.Source,
+			SourceName: alrt.SourceName,
+			Key:        key,
+			Name:       alrt.Name,
+			Roles:      alrt.Roles,
+			Resource:   alrt.Resource,
+			Level:      alrt.Level,
+			Message:    alrt.Message,
+			Frequency:  alrt.GetFrequency(),
+			State:      Firing,
+			Open:       true,
+			Code:       code,
+			Start:      now,
+			LastSeen:   now,
+		}
+
+		err = inc.Insert(db)
+		if err == nil {
+			return
+		}
+
+		if _, ok := err.(*database.DuplicateKeyError); !ok {
+			return
+		}
+	}
+
+	return
+}
+
+func GetIncident(db *database.Database, incId bson.ObjectID) (
+	inc *Incident, err error) {
+
+	coll := db.AlertsIncident()
+	inc = &Incident{}
+
+	err = coll.FindOneId(incId, inc)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetIncidents(db *database.Database, query *bson.M,
+	page, pageCount int64) (incidents []*Incident, count int64, err error) {
+
+	coll := db.AlertsIncident()
+	incidents = []*Incident{}
+
+	count, err = coll.CountDocuments(db, query)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"start", -1}})
+
+	if pageCount != 0 {
+		maxPage := count / pageCount
+		if count == pageCount {
+			maxPage = 0
+		}
+		page = min(page, maxPage)
+		skip := min(page*pageCount, count)
+		opts.SetSkip(skip).SetLimit(pageCount)
+	}
+
+	cursor, err := coll.Find(db, query, opts)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		inc := &Incident{}
+		err = cursor.Decode(inc)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		incidents = append(incidents, inc)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func getOpenIncidents(db *database.Database, query *bson.M) (
+	incidents []*Incident, err error) {
+
+	(*query)["open"] = true
+
+	incidents, _, err = GetIncidents(db, query, 0, 0)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Alert ids with an open incident for a source
+func GetOpenAlerts(db *database.Database, source bson.ObjectID,
+	key string) (alertIds set.Set, err error) {
+
+	alertIds = set.NewSet()
+
+	incidents, err := getOpenIncidents(db, &bson.M{
+		"source": source,
+		"key":    key,
+	})
+	if err != nil {
+		return
+	}
+
+	for _, inc := range incidents {
+		alertIds.Add(inc.Alert)
+	}
+
+	return
+}
+
+// Source ids with an open incident matching the query
+func GetOpenSources(db *database.Database, query *bson.M) (
+	sourceIds []bson.ObjectID, err error) {
+
+	sourceIds = []bson.ObjectID{}
+
+	incidents, err := getOpenIncidents(db, query)
+	if err != nil {
+		return
+	}
+
+	sourcesSet := set.NewSet()
+	for _, inc := range incidents {
+		if sourcesSet.Contains(inc.Source) {
+			continue
+		}
+		sourcesSet.Add(inc.Source)
+		sourceIds = append(sourceIds, inc.Source)
+	}
+
+	return
+}
+
+// Acknowledges the open incident with the code for the user of a phone
+// device replying to an alert
+func AcknowledgeNumber(db *database.Database, number, code string) (
+	inc *Incident, err error) {
+
+	devices, err := device.GetAllNumber(db, number)
+	if err != nil {
+		return
+	}
+
+	incidents, err := getOpenIncidents(db, &bson.M{
+		"code":  code,
+		"state": Firing,
+	})
+	if err != nil {
+		return
+	}
+
+	for _, devc := range devices {
+		if devc.Disabled {
+			continue
+		}
+
+		usr, e := user.Get(db, devc.User)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if usr.Disabled {
+			continue
+		}
+
+		for _, incident := range incidents {
+			if !usr.RolesMatch(incident.Roles) {
+				continue
+			}
+
+			errData, e := incident.Acknowledge(db, usr)
+			if e != nil {
+				err = e
+				return
+			}
+			if errData != nil {
+				continue
+			}
+
+			inc = incident
+			return
+		}
+	}
+
+	return
+}
+
+// Resolves open incidents for resources evaluated by a source that are no
+// longer active
+func Clear(source bson.ObjectID, key string, resources []string,
+	active []bson.ObjectID) {
+
+	if len(resources) == 0 {
+		return
+	}
+
+	db := database.GetDatabase()
+	defer db.Close()
+
+	if active == nil {
+		active = []bson.ObjectID{}
+	}
+
+	incidents, err := getOpenIncidents(db, &bson.M{
+		"source": source,
+		"key":    key,
+		"resource": &bson.M{
+			"$in": resources,
+		},
+		"alert": &bson.M{
+			"$nin": active,
+		},
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("alert: Failed to get open incidents")
+		return
+	}
+
+	for _, inc := range incidents {
+		err = inc.Resolve(db)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"incident_id": inc.Id.Hex(),
+				"error":       err,
+			}).Error("alert: Failed to resolve incident")
+		}
+	}
+
+	return
+}
+
+// Resolves open incidents for event resources that have not been seen
+// within twice the alert frequency
+func ResolveStale(db *database.Database, resources []string) (
+	count int, err error) {
+
+	incidents, err := getOpenIncidents(db, &bson.M{
+		"resource": &bson.M{
+			"$in": resources,
+		},
+	})
+	if err != nil {
+		return
+	}
+
+	for _, inc := range incidents {
+		frequency := inc.Frequency
+		if frequency == 0 {
+			frequency = 5 * time.Minute
+		}
+
+		if time.Since(inc.LastSeen) < 2*frequency {
+			continue
+		}
+
+		err = inc.Resolve(db)
+		if err != nil {
+			return
+		}
+		count += 1
+	}
+
+	return
+}
@@ -1,5 +1,9 @@
 package audit
 
+import (
+	"github.com/dropbox/godropbox/container/set"
+)
+
 const (
 	AdminLogin                 = "admin_login"
 	AdminLoginFailed           = "admin_login_failed"
@@ -32,6 +36,8 @@ const (
 	UserDeviceRegisterRequest = "user_device_register_request"
 	UserDeviceRegister        = "user_device_register"
 	UserAccountDisable        = "user_account_disable"
+	UserLocked                = "user_locked"
+	UserUnlocked              = "user_unlocked"
 
 	DeviceRegister       = "device_register"
 	DeviceRegisterFailed = "device_register_failed"
@@ -43,4 +49,28 @@ const (
 	OktaDeny             = "okta_deny"
 	SshApprove           = "ssh_approve"
 	SshDeny              = "ssh_deny"
+
+	BastionForward       = "bastion_forward"
+	BastionForwardDenied = "bastion_forward_denied"
+	BastionForwardFailed = "bastion_forward_failed"
+	RecordingDownload    = "recording_download"
+
+	AlertAcknowledge = "alert_acknowledge"
+
+	EndpointCommand       = "endpoint_command"
+	EndpointCommandFailed = "endpoint_command_failed"
+
+	RateLimited = "rate_limited"
+)
+
+var loginTypes = set.NewSet(
+	AdminLogin,
+	AdminLoginFailed,
+	AdminAuthFailed,
+	ProxyLogin,
+	ProxyLoginFailed,
+	ProxyAuthFailed,
+	UserLogin,
+	UserLoginFailed,
+	UserAuthFailed,
 )
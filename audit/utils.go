@@ -7,6 +7,7 @@ import (
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/node"
 	"github.com/pritunl/pritunl-zero/settings"
 	"github.com/pritunl/pritunl-zero/useragent"
 )
@@ -84,9 +85,17 @@ func GetAll(db *database.Database, userId bson.ObjectID,
 	return
 }
 
+func countLogin(typ string) {
+	if node.Self != nil && loginTypes.Contains(typ) {
+		node.Self.AddLogin(typ)
+	}
+}
+
 func New(db *database.Database, r *http.Request,
 	userId bson.ObjectID, typ string, fields Fields) (err error) {
 
+	countLogin(typ)
+
 	if settings.System.Demo {
 		return
 	}
@@ -111,3 +120,33 @@ func New(db *database.Database, r *http.Request,
 
 	return
 }
+
+func NewAddr(db *database.Database, addr string,
+	userId bson.ObjectID, typ string, fields Fields) (err error) {
+
+	countLogin(typ)
+
+	if settings.System.Demo {
+		return
+	}
+
+	agnt, err := useragent.ParseAddr(db, addr)
+	if err != nil {
+		return
+	}
+
+	adt := &Audit{
+		User:      userId,
+		Timestamp: time.Now(),
+		Type:      typ,
+		Fields:    fields,
+		Agent:     agnt,
+	}
+
+	err = adt.Insert(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha512"
 	"crypto/subtle"
 	"encoding/base64"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/lockout"
 	"github.com/pritunl/pritunl-zero/node"
 	"github.com/pritunl/pritunl-zero/service"
 	"github.com/pritunl/pritunl-zero/settings"
@@ -23,8 +25,9 @@ import (
 	"github.com/pritunl/pritunl-zero/utils"
 )
 
-func Local(db *database.Database, username, password string) (
-	usr *user.User, errData *errortypes.ErrorData, err error) {
+func Local(db *database.Database, r *http.Request,
+	username, password string) (usr *user.User,
+	errData *errortypes.ErrorData, err error) {
 
 	username = strings.ToLower(username)
 
@@ -36,12 +39,21 @@ func Local(db *database.Database, username, password string) (
 		return
 	}
 
+	errData, err = lockout.CheckAddr(db, r)
+	if err != nil || errData != nil {
+		return
+	}
+
 	usr, err = user.GetUsername(db, user.Local, username)
 	if err != nil {
 		switch err.(type) {
 		case *database.NotFoundError:
 			usr = nil
-			err = nil
+			err = lockout.Failed(db, r, nil)
+			if err != nil {
+				return
+			}
+
 			errData = &errortypes.ErrorData{
 				Error:   "auth_invalid",
 				Message: "Authentication credentials are invalid",
@@ -53,6 +65,12 @@ func Local(db *database.Database, username, password string) (
 
 	valid := usr.CheckPassword(password)
 	if !valid {
+		err = lockout.Failed(db, r, usr)
+		usr = nil
+		if err != nil {
+			return
+		}
+
 		errData = &errortypes.ErrorData{
 			Error:   "auth_invalid",
 			Message: "Authentication credentials are invalid",
@@ -60,6 +78,19 @@ func Local(db *database.Database, username, password string) (
 		return
 	}
 
+	// Lock is checked after the password so a locked account can not be
+	// distinguished from invalid credentials
+	errData = lockout.CheckUser(usr)
+	if errData != nil {
+		usr = nil
+		return
+	}
+
+	err = lockout.Success(db, r, usr)
+	if err != nil {
+		return
+	}
+
 	return
 }
 
@@ -1,6 +1,7 @@
 package authority
 
 import (
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/ecdsa"
@@ -34,6 +35,7 @@ import (
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/identity"
 	"github.com/pritunl/pritunl-zero/node"
 	"github.com/pritunl/pritunl-zero/nonce"
 	"github.com/pritunl/pritunl-zero/requires"
@@ -79,6 +81,7 @@ type Authority struct {
 	ProxyHosting       bool          `bson:"proxy_hosting" json:"proxy_hosting"`
 	ProxyHostname      string        `bson:"proxy_hostname" json:"proxy_hostname"`
 	ProxyPort          int           `bson:"proxy_port" json:"proxy_port"`
+	ProxyRecording     bool          `bson:"proxy_recording" json:"proxy_recording"`
 	HostDomain         string        `bson:"host_domain" json:"host_domain"`
 	HostSubnets        []string      `bson:"host_subnets" json:"host_subnets"`
 	HostMatches        []string      `bson:"host_matches" json:"host_matches"`
@@ -86,6 +89,13 @@ type Authority struct {
 	HostCertificates   bool          `bson:"host_certificates" json:"host_certificates"`
 	StrictHostChecking bool          `bson:"strict_host_checking" json:"strict_host_checking"`
 	HostTokens         []string      `bson:"host_tokens" json:"host_tokens"`
+	HostIdentity       bool          `bson:"host_identity" json:"host_identity"`
+	HostIdentityType   string        `bson:"host_identity_type" json:"host_identity_type"`
+	HostIdentityCerts  string        `bson:"host_identity_certs" json:"host_identity_certs"`
+	HostIdentityAud    string        `bson:"host_identity_aud" json:"host_identity_aud"`
+	HostIdentityAccts  []string      `bson:"host_identity_accts" json:"host_identity_accts"`
+	HostIdentityMatch  []string      `bson:"host_identity_match" json:"host_identity_match"`
+	HostPrincipals     []string      `bson:"host_principals" json:"host_principals"`
 	HsmToken           string        `bson:"hsm_token" json:"hsm_token"`
 	HsmSecret          string        `bson:"hsm_secret" json:"hsm_secret"`
 	HsmSerial          string        `bson:"hsm_serial" json:"hsm_serial"`
@@ -1219,6 +1229,113 @@ func (a *Authority) CreateClientCertificate(db *database.Database) (
 	return
 }
 
+func (a *Authority) GetRootCertificate() (rootCert *x509.Certificate,
+	err error) {
+
+	block, _ := pem.Decode([]byte(a.RootCertificate))
+	if block == nil {
+		err = &errortypes.ParseError{
+			errors.New("authority: Failed to decode root certificate"),
+		}
+		return
+	}
+
+	rootCert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "authority: Failed to parse root certificate"),
+		}
+		return
+	}
+
+	return
+}
+
+func (a *Authority) createUserCertificateLocal(usr *user.User,
+	pubKey crypto.PublicKey, serial *big.Int, expires time.Time) (
+	certPem string, err error) {
+
+	privateKey, err := ParsePemKey(a.PrivateKey)
+	if err != nil {
+		return
+	}
+
+	rootCert, err := a.GetRootCertificate()
+	if err != nil {
+		return
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: usr.Username,
+		},
+		NotBefore:             time.Now().Add(-120 * time.Second),
+		NotAfter:              expires,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageClientAuth,
+		},
+	}
+
+	if strings.Contains(usr.Username, "@") {
+		template.EmailAddresses = []string{usr.Username}
+	}
+
+	certBytes, err := x509.CreateCertificate(
+		rand.Reader,
+		template,
+		rootCert,
+		pubKey,
+		privateKey,
+	)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err,
+				"authority: Failed to create user certificate"),
+		}
+		return
+	}
+
+	certBlock := &pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: certBytes,
+	}
+
+	certPem = strings.TrimSpace(string(pem.EncodeToMemory(certBlock)))
+
+	return
+}
+
+// Sign an X.509 client certificate for the user with the authority root
+// certificate, used for mutual TLS authentication on proxy services
+func (a *Authority) CreateUserCertificate(db *database.Database,
+	usr *user.User, pubKey crypto.PublicKey, serial *big.Int,
+	expires time.Time) (certPem string, err error) {
+
+	if a.Type == PritunlHsm {
+		err = &errortypes.UnknownError{
+			errors.New("authority: User certificate not available on HSM"),
+		}
+		return
+	}
+
+	if a.RootCertificate == "" {
+		err = &errortypes.UnknownError{
+			errors.New("authority: Authority missing root certificate"),
+		}
+		return
+	}
+
+	certPem, err = a.createUserCertificateLocal(usr, pubKey, serial, expires)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
 func (a *Authority) TokenNew() (err error) {
 	if a.HostTokens == nil {
 		a.HostTokens = []string{}
@@ -1250,6 +1367,48 @@ func (a *Authority) TokenDelete(token string) (err error) {
 	return
 }
 
+func (a *Authority) HostIdentityVerify(db *database.Database,
+	doc *identity.Document, hostname, pubKey string) (
+	ident *identity.Identity, err error) {
+
+	if !a.HostIdentity || doc.Type != a.HostIdentityType {
+		err = &errortypes.AuthenticationError{
+			errors.New("authority: Host identity type not allowed"),
+		}
+		return
+	}
+
+	verifier, err := identity.New(a.HostIdentityType,
+		a.HostIdentityCerts, a.HostIdentityAud)
+	if err != nil {
+		return
+	}
+
+	ident, err = verifier.Verify(doc, hostname, pubKey)
+	if err != nil {
+		return
+	}
+
+	if !ident.Match(hostname, a.HostIdentityAccts, a.HostIdentityMatch) {
+		err = &errortypes.AuthenticationError{
+			errors.New("authority: Host identity does not match hostname"),
+		}
+		ident = nil
+		return
+	}
+
+	// Aws documents are static for the life of the instance
+	if ident.Provider == identity.Aws {
+		err = identity.Bind(db, ident, hostname, pubKey)
+		if err != nil {
+			ident = nil
+			return
+		}
+	}
+
+	return
+}
+
 func (a *Authority) HandleHsmStatus(db *database.Database,
 	payload *HsmPayload) (err error) {
 
@@ -1448,6 +1607,65 @@ func (a *Authority) GetMatches() (matches []string, err error) {
 	return
 }
 
+// Check if the hostname is covered by the authority host matches, names
+// without a domain are qualified with the authority host domain
+func (a *Authority) MatchHostname(hostname string) bool {
+	if hostname == "" {
+		return false
+	}
+
+	names := []string{hostname}
+	if a.HostDomain != "" && !strings.Contains(hostname, ".") {
+		names = append(names, a.GetDomain(hostname))
+	}
+
+	matches, err := a.GetMatches()
+	if err != nil {
+		return false
+	}
+
+	matched := false
+	for _, match := range matches {
+		for _, pattern := range strings.Fields(match) {
+			negate := strings.HasPrefix(pattern, "!")
+			pattern = strings.TrimPrefix(pattern, "!")
+
+			for _, name := range names {
+				if utils.Match(pattern, name) {
+					if negate {
+						return false
+					}
+					matched = true
+				}
+			}
+		}
+	}
+
+	return matched
+}
+
+// Principals permitted per unix account on hosts
+func (a *Authority) GetHostPrincipals() (principals map[string][]string) {
+	principals = map[string][]string{}
+
+	for _, hostPrincipal := range a.HostPrincipals {
+		account, accountPrincipals, e := parseHostPrincipal(hostPrincipal)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"authority_id":   a.Id.Hex(),
+				"host_principal": hostPrincipal,
+				"error":          e,
+			}).Error("authority: Failed to parse host principal")
+			continue
+		}
+
+		principals[account] = append(principals[account],
+			accountPrincipals...)
+	}
+
+	return
+}
+
 func (a *Authority) SetPublicKeyPem() (err error) {
 	pubKey, err := ParseSshPubKey(a.PublicKey)
 	if err != nil {
@@ -1627,6 +1845,7 @@ func (a *Authority) Validate(db *database.Database) (
 	if !a.ProxyHosting {
 		a.ProxyPort = 0
 		a.ProxyHostname = ""
+		a.ProxyRecording = false
 
 		err = RemoveNode(db, a.Id)
 		if err != nil {
@@ -1668,6 +1887,91 @@ func (a *Authority) Validate(db *database.Database) (
 		a.HostTokens = []string{}
 	}
 
+	if !a.HostCertificates {
+		a.HostIdentity = false
+	}
+
+	if a.HostIdentityAccts == nil {
+		a.HostIdentityAccts = []string{}
+	}
+
+	if a.HostIdentityMatch == nil {
+		a.HostIdentityMatch = []string{}
+	}
+
+	if a.HostIdentity {
+		switch a.HostIdentityType {
+		case identity.Aws, identity.Oracle:
+			a.HostIdentityAud = ""
+
+			_, e := identity.ParseCertificates(a.HostIdentityCerts)
+			if e != nil {
+				errData = &errortypes.ErrorData{
+					Error:   "host_identity_certs_invalid",
+					Message: "Host identity certificates are invalid",
+				}
+				return
+			}
+
+			break
+		case identity.Google:
+			a.HostIdentityCerts = ""
+
+			if a.HostIdentityAud == "" {
+				errData = &errortypes.ErrorData{
+					Error:   "host_identity_aud_missing",
+					Message: "Host identity audience required",
+				}
+				return
+			}
+
+			break
+		default:
+			errData = &errortypes.ErrorData{
+				Error:   "host_identity_type_invalid",
+				Message: "Host identity type is invalid",
+			}
+			return
+		}
+
+		if len(a.HostIdentityAccts) == 0 {
+			errData = &errortypes.ErrorData{
+				Error:   "host_identity_accts_missing",
+				Message: "Host identity account required",
+			}
+			return
+		}
+
+		if len(a.HostIdentityMatch) == 0 {
+			errData = &errortypes.ErrorData{
+				Error:   "host_identity_match_missing",
+				Message: "Host identity hostname match required",
+			}
+			return
+		}
+	} else {
+		a.HostIdentityType = ""
+		a.HostIdentityCerts = ""
+		a.HostIdentityAud = ""
+		a.HostIdentityAccts = []string{}
+		a.HostIdentityMatch = []string{}
+	}
+
+	if a.HostPrincipals == nil {
+		a.HostPrincipals = []string{}
+	}
+
+	for _, hostPrincipal := range a.HostPrincipals {
+		_, _, e := parseHostPrincipal(hostPrincipal)
+		if e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "host_principals_invalid",
+				Message: "Host principals must be account:principal,principal",
+			}
+			return
+		}
+	}
+
 	for _, hostSubnet := range a.HostSubnets {
 		_, e := parseSubnetMatch(hostSubnet)
 		if e != nil {
@@ -30,6 +30,50 @@ type Named struct {
 	ProxyHosting bool          `bson:"proxy_hosting" json:"proxy_hosting"`
 }
 
+func parseHostPrincipal(hostPrincipal string) (
+	account string, principals []string, err error) {
+
+	parts := strings.SplitN(hostPrincipal, ":", 2)
+	if len(parts) != 2 {
+		err = &errortypes.ParseError{
+			errors.New("authority: Host principal missing account"),
+		}
+		return
+	}
+
+	account = strings.TrimSpace(parts[0])
+	if account == "" || strings.ContainsAny(account, " \t/") {
+		err = &errortypes.ParseError{
+			errors.New("authority: Host principal account invalid"),
+		}
+		return
+	}
+
+	principals = []string{}
+	for _, principal := range strings.Split(parts[1], ",") {
+		principal = strings.TrimSpace(principal)
+		if principal == "" {
+			continue
+		}
+		if strings.ContainsAny(principal, " \t") {
+			err = &errortypes.ParseError{
+				errors.New("authority: Host principal invalid"),
+			}
+			return
+		}
+		principals = append(principals, principal)
+	}
+
+	if len(principals) == 0 {
+		err = &errortypes.ParseError{
+			errors.New("authority: Host principal empty"),
+		}
+		return
+	}
+
+	return
+}
+
 func parseSubnetMatch(subnetMatch string) (
 	match string, err error) {
 
@@ -628,6 +672,42 @@ func GetTokens(db *database.Database, tokens []string) (
 	return
 }
 
+func GetHostIdentity(db *database.Database, identityType string) (
+	authrs []*Authority, err error) {
+
+	coll := db.Authorities()
+	authrs = []*Authority{}
+
+	cursor, err := coll.Find(db, &bson.M{
+		"host_identity":      true,
+		"host_identity_type": identityType,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		authr := &Authority{}
+		err = cursor.Decode(authr)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		authrs = append(authrs, authr)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func Remove(db *database.Database, authrId bson.ObjectID) (
 	errData *errortypes.ErrorData, err error) {
 
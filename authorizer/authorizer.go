@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/clientcert"
 	"github.com/pritunl/pritunl-zero/cookie"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/service"
@@ -17,6 +18,7 @@ type Authorizer struct {
 	cook *cookie.Cookie
 	sess *session.Session
 	sig  *signature.Signature
+	cert *clientcert.Certificate
 	srvc *service.Service
 	usr  *user.User
 }
@@ -26,7 +28,7 @@ func (a *Authorizer) IsApi() bool {
 }
 
 func (a *Authorizer) IsValid() bool {
-	return a.sess != nil || a.sig != nil
+	return a.sess != nil || a.sig != nil || a.cert != nil
 }
 
 func (a *Authorizer) AddSignature(db *database.Database,
@@ -51,11 +53,21 @@ func (a *Authorizer) AddCookie(cook *cookie.Cookie,
 	return
 }
 
+func (a *Authorizer) AddCertificate(cert *clientcert.Certificate,
+	usr *user.User) (err error) {
+
+	a.cert = cert
+	a.usr = usr
+
+	return
+}
+
 func (a *Authorizer) Clear(db *database.Database, w http.ResponseWriter,
 	r *http.Request) (err error) {
 
 	a.sess = nil
 	a.sig = nil
+	a.cert = nil
 
 	if a.cook != nil {
 		err = a.cook.Remove(db)
@@ -140,6 +152,8 @@ func (a *Authorizer) GetUser(db *database.Database) (
 		} else {
 			a.usr = usr
 		}
+	} else if a.cert != nil {
+		usr = a.usr
 	}
 
 	return
@@ -156,6 +170,10 @@ func (a *Authorizer) GetSession() *session.Session {
 	return a.sess
 }
 
+func (a *Authorizer) GetCertificate() *clientcert.Certificate {
+	return a.cert
+}
+
 func (a *Authorizer) SessionId() string {
 	if a.sess != nil {
 		return a.sess.Id
@@ -3,7 +3,10 @@ package authorizer
 import (
 	"net/http"
 
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/audit"
 	"github.com/pritunl/pritunl-zero/auth"
+	"github.com/pritunl/pritunl-zero/clientcert"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/service"
 	"github.com/pritunl/pritunl-zero/signature"
@@ -59,6 +62,18 @@ func AuthorizeProxy(db *database.Database, srvc *service.Service,
 
 	authr = NewProxy(srvc)
 
+	if srvc.MtlsMode != "" {
+		valid, e := authorizeCertificate(db, authr, srvc, r)
+		if e != nil {
+			err = e
+			return
+		}
+
+		if valid || srvc.MtlsMode == service.MtlsRequired {
+			return
+		}
+	}
+
 	token := r.Header.Get("Pritunl-Zero-Token")
 	sigStr := r.Header.Get("Pritunl-Zero-Signature")
 
@@ -99,6 +114,66 @@ func AuthorizeProxy(db *database.Database, srvc *service.Service,
 	return
 }
 
+// Authenticate the client certificate verified during the TLS handshake
+// against the certificates issued by the service authority
+func authorizeCertificate(db *database.Database, authr *Authorizer,
+	srvc *service.Service, r *http.Request) (valid bool, err error) {
+
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 ||
+		len(r.TLS.VerifiedChains[0]) == 0 {
+
+		return
+	}
+
+	usr, cert, errData, err := clientcert.Authenticate(
+		db, srvc.MtlsAuthority, r.TLS.VerifiedChains[0][0])
+	if err != nil {
+		return
+	}
+
+	if errData != nil {
+		userId := bson.NilObjectID
+		if usr != nil {
+			userId = usr.Id
+		} else if cert != nil {
+			userId = cert.UserId
+		}
+
+		if !userId.IsZero() {
+			err = audit.New(
+				db,
+				r,
+				userId,
+				audit.ProxyAuthFailed,
+				audit.Fields{
+					"method":  "certificate",
+					"error":   errData.Error,
+					"message": errData.Message,
+				},
+			)
+			if err != nil {
+				return
+			}
+		}
+
+		return
+	}
+
+	err = cert.UpdateActive(db)
+	if err != nil {
+		return
+	}
+
+	err = authr.AddCertificate(cert, usr)
+	if err != nil {
+		return
+	}
+
+	valid = true
+
+	return
+}
+
 func AuthorizeUser(db *database.Database, w http.ResponseWriter,
 	r *http.Request) (authr *Authorizer, err error) {
 
@@ -26,6 +26,8 @@ type Bastion struct {
 	certExpire time.Time
 	state      bool
 	kill       bool
+	native     bool
+	server     *server
 	path       string
 }
 
@@ -77,6 +79,16 @@ func (b *Bastion) wait() {
 	}
 }
 
+func (b *Bastion) waitNative() {
+	defer func() {
+		_ = os.RemoveAll(b.path)
+		b.state = false
+		delete(state, b.Authority)
+	}()
+
+	b.server.Wait()
+}
+
 func (b *Bastion) renewHost(db *database.Database) (err error) {
 	if db == nil {
 		db = database.GetDatabase()
@@ -92,6 +104,9 @@ func (b *Bastion) renewHost(db *database.Database) (err error) {
 	if e != nil {
 		b.state = false
 		_ = os.RemoveAll(b.path)
+		if b.native {
+			b.server.Close()
+		}
 		err = e
 		return
 	}
@@ -106,6 +121,17 @@ func (b *Bastion) renewHost(db *database.Database) (err error) {
 		return
 	}
 
+	if b.native {
+		err = b.server.SetCertificate(cert.Certificates[0])
+		if err != nil {
+			return
+		}
+
+		b.certExpire = cert.CertificatesInfo[0].Expires
+
+		return
+	}
+
 	err = utils.CreateWrite(hostCertPath, cert.Certificates[0], 0644)
 	if err != nil {
 		return
@@ -153,6 +179,14 @@ func (b *Bastion) Start(db *database.Database,
 		}
 	}
 
+	b.native = settings.System.BastionNative
+	if b.native {
+		b.server, err = newServer(authr)
+		if err != nil {
+			return
+		}
+	}
+
 	b.state = true
 
 	err = utils.ExistsMkdir(b.path, 0755)
@@ -171,6 +205,29 @@ func (b *Bastion) Start(db *database.Database,
 		}
 	}
 
+	if b.native {
+		err = b.server.Start(authr.ProxyPort)
+		if err != nil {
+			b.state = false
+			_ = os.RemoveAll(b.path)
+			return
+		}
+
+		if !settings.System.DisableBastionHostCertificates {
+			go b.syncCert()
+		}
+
+		go b.waitNative()
+
+		return
+	}
+
+	if authr.ProxyRecording {
+		logrus.WithFields(logrus.Fields{
+			"authority_id": b.Authority.Hex(),
+		}).Warn("bastion: Session recording requires native bastion server")
+	}
+
 	output, err := utils.ExecOutput("",
 		GetRuntime(),
 		"run",
@@ -218,6 +275,11 @@ func (b *Bastion) Stop() (err error) {
 		"authority_id": b.Authority.Hex(),
 	}).Info("bastion: Stopping bastion server")
 
+	if b.native {
+		b.server.Close()
+		return
+	}
+
 	_, err = utils.ExecOutputLogged(nil,
 		GetRuntime(), "stop", "-t", "3", b.Container)
 	if err != nil {
@@ -245,7 +307,10 @@ func (b *Bastion) Diff(authr *authority.Authority) bool {
 		b.authr.ProxyPrivateKey != authr.ProxyPrivateKey ||
 		b.authr.HostCertificates != authr.HostCertificates ||
 		b.authr.ProxyPort != authr.ProxyPort ||
-		b.authr.PublicKey != authr.PublicKey {
+		b.authr.ProxyRecording != authr.ProxyRecording ||
+		b.authr.StrictHostChecking != authr.StrictHostChecking ||
+		b.authr.PublicKey != authr.PublicKey ||
+		b.native != settings.System.BastionNative {
 
 		return true
 	}
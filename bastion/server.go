@@ -0,0 +1,374 @@
+package bastion
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/authority"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+type directTcpipData struct {
+	DestAddr string
+	DestPort uint32
+	OrigAddr string
+	OrigPort uint32
+}
+
+type server struct {
+	authr    *authority.Authority
+	trusted  ssh.PublicKey
+	hostKey  ssh.Signer
+	hostCert ssh.Signer
+	listener net.Listener
+	conns    map[*ssh.ServerConn]bool
+	closed   atomic.Bool
+	lock     sync.Mutex
+	waiter   sync.WaitGroup
+}
+
+func newServer(authr *authority.Authority) (srv *server, err error) {
+	trusted, _, _, _, err := ssh.ParseAuthorizedKey(
+		[]byte(authr.PublicKey))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "bastion: Failed to parse authority key"),
+		}
+		return
+	}
+
+	hostKey, err := ssh.ParsePrivateKey([]byte(authr.ProxyPrivateKey))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "bastion: Failed to parse host key"),
+		}
+		return
+	}
+
+	srv = &server{
+		authr:   authr,
+		trusted: trusted,
+		hostKey: hostKey,
+		conns:   map[*ssh.ServerConn]bool{},
+	}
+
+	return
+}
+
+func (s *server) SetCertificate(certStr string) (err error) {
+	pubKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(certStr))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "bastion: Failed to parse host certificate"),
+		}
+		return
+	}
+
+	cert, ok := pubKey.(*ssh.Certificate)
+	if !ok {
+		err = &errortypes.ParseError{
+			errors.New("bastion: Host certificate invalid type"),
+		}
+		return
+	}
+
+	certSigner, err := ssh.NewCertSigner(cert, s.hostKey)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "bastion: Failed to load host certificate"),
+		}
+		return
+	}
+
+	s.lock.Lock()
+	s.hostCert = certSigner
+	s.lock.Unlock()
+
+	return
+}
+
+func (s *server) getConfig() (config *ssh.ServerConfig) {
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return bytes.Equal(auth.Marshal(), s.trusted.Marshal())
+		},
+	}
+
+	config = &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata,
+			key ssh.PublicKey) (perms *ssh.Permissions, err error) {
+
+			if conn.User() != "bastion" {
+				err = &errortypes.AuthenticationError{
+					errors.New("bastion: Invalid login user"),
+				}
+				return
+			}
+
+			cert, ok := key.(*ssh.Certificate)
+			if !ok || !slices.Contains(cert.ValidPrincipals, "bastion") {
+				err = &errortypes.AuthenticationError{
+					errors.New("bastion: Missing bastion principal"),
+				}
+				return
+			}
+
+			perms, err = checker.Authenticate(conn, key)
+			if err != nil {
+				return
+			}
+
+			perms.ExtraData = map[any]any{
+				"serial": cert.Serial,
+				"key_id": cert.KeyId,
+			}
+
+			return
+		},
+	}
+
+	s.lock.Lock()
+	if s.hostCert != nil {
+		config.AddHostKey(s.hostCert)
+	}
+	s.lock.Unlock()
+	config.AddHostKey(s.hostKey)
+
+	return
+}
+
+func (s *server) Start(port int) (err error) {
+	s.listener, err = net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "bastion: Failed to listen on bastion port"),
+		}
+		return
+	}
+
+	s.waiter.Add(1)
+	go s.serve()
+
+	return
+}
+
+func (s *server) serve() {
+	defer s.waiter.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if s.closed.Load() {
+				return
+			}
+
+			logrus.WithFields(logrus.Fields{
+				"authority_id": s.authr.Id.Hex(),
+				"error":        err,
+			}).Error("bastion: Failed to accept connection")
+
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *server) handleConn(netConn net.Conn) {
+	defer func() {
+		_ = netConn.Close()
+	}()
+
+	_ = netConn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	conn, chans, reqs, err := ssh.NewServerConn(netConn, s.getConfig())
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"authority_id": s.authr.Id.Hex(),
+			"remote":       netConn.RemoteAddr().String(),
+			"error":        err,
+		}).Info("bastion: Connection handshake failed")
+		return
+	}
+
+	_ = netConn.SetDeadline(time.Time{})
+
+	s.lock.Lock()
+	s.conns[conn] = true
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+		_ = conn.Close()
+	}()
+
+	go ssh.DiscardRequests(reqs)
+
+	for newChan := range chans {
+		switch newChan.ChannelType() {
+		case "direct-tcpip":
+			go s.handleDirectTcpip(conn, newChan)
+			break
+		case "session":
+			if !s.authr.ProxyRecording {
+				_ = newChan.Reject(ssh.Prohibited,
+					"Only port forwarding is permitted")
+				break
+			}
+			go s.handleSession(conn, newChan)
+			break
+		default:
+			_ = newChan.Reject(ssh.Prohibited,
+				"Only port forwarding is permitted")
+		}
+	}
+}
+
+func (s *server) handleDirectTcpip(conn *ssh.ServerConn,
+	newChan ssh.NewChannel) {
+
+	data := &directTcpipData{}
+	err := ssh.Unmarshal(newChan.ExtraData(), data)
+	if err != nil {
+		_ = newChan.Reject(ssh.ConnectionFailed, "Invalid request")
+		return
+	}
+
+	serial, _ := conn.Permissions.ExtraData["serial"].(uint64)
+	keyId, _ := conn.Permissions.ExtraData["key_id"].(string)
+	remoteAddr := conn.RemoteAddr().String()
+	destAddr := net.JoinHostPort(
+		data.DestAddr, strconv.Itoa(int(data.DestPort)))
+
+	// Forwarded connections can not be recorded
+	if s.authr.ProxyRecording {
+		_ = newChan.Reject(ssh.Prohibited,
+			"Port forwarding not permitted with session recording")
+
+		forwardAudit(s.authr, serial, keyId, remoteAddr, destAddr,
+			audit.BastionForwardDenied, nil)
+
+		return
+	}
+
+	_, permitted := conn.Permissions.Extensions["permit-port-forwarding"]
+	if !permitted || !PermitOpen(settings.System.BastionPermitOpen,
+		data.DestAddr, int(data.DestPort)) {
+
+		_ = newChan.Reject(ssh.Prohibited, "Destination not permitted")
+
+		forwardAudit(s.authr, serial, keyId, remoteAddr, destAddr,
+			audit.BastionForwardDenied, nil)
+
+		return
+	}
+
+	destConn, err := net.DialTimeout("tcp", destAddr, 10*time.Second)
+	if err != nil {
+		_ = newChan.Reject(ssh.ConnectionFailed, "Connection failed")
+
+		forwardAudit(s.authr, serial, keyId, remoteAddr, destAddr,
+			audit.BastionForwardFailed, err)
+
+		return
+	}
+	defer destConn.Close()
+
+	channel, chanReqs, err := newChan.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	go ssh.DiscardRequests(chanReqs)
+
+	forwardAudit(s.authr, serial, keyId, remoteAddr, destAddr,
+		audit.BastionForward, nil)
+
+	waiter := sync.WaitGroup{}
+	waiter.Add(2)
+
+	go func() {
+		defer waiter.Done()
+		_, _ = io.Copy(destConn, channel)
+		if tcpConn, ok := destConn.(*net.TCPConn); ok {
+			_ = tcpConn.CloseWrite()
+		}
+	}()
+
+	go func() {
+		defer waiter.Done()
+		_, _ = io.Copy(channel, destConn)
+		_ = channel.CloseWrite()
+	}()
+
+	waiter.Wait()
+}
+
+func (s *server) Close() {
+	s.closed.Store(true)
+
+	if s.listener != nil {
+		_ = s.listener.Close()
+	}
+
+	s.lock.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.lock.Unlock()
+}
+
+func (s *server) Wait() {
+	s.waiter.Wait()
+}
+
+// Matches a destination against a space separated sshd PermitOpen list
+func PermitOpen(permitOpen, host string, port int) bool {
+	portStr := strconv.Itoa(port)
+
+	for _, permit := range strings.Fields(permitOpen) {
+		if permit == "any" {
+			return true
+		}
+		if permit == "none" {
+			return false
+		}
+
+		i := strings.LastIndex(permit, ":")
+		if i < 0 {
+			continue
+		}
+
+		permitHost := strings.Trim(permit[:i], "[]")
+		permitPort := permit[i+1:]
+
+		if permitHost != "*" && !strings.EqualFold(permitHost, host) {
+			continue
+		}
+
+		if permitPort != "*" && permitPort != portStr {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
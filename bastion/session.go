@@ -0,0 +1,352 @@
+package bastion
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/recording"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+type ptyRequestData struct {
+	Term     string
+	Width    uint32
+	Height   uint32
+	PxWidth  uint32
+	PxHeight uint32
+	Modes    string
+}
+
+type windowChangeData struct {
+	Width    uint32
+	Height   uint32
+	PxWidth  uint32
+	PxHeight uint32
+}
+
+type execData struct {
+	Command string
+}
+
+type exitStatusData struct {
+	Status uint32
+}
+
+type target struct {
+	Username string
+	Host     string
+	Port     int
+}
+
+func (t *target) Address() string {
+	return net.JoinHostPort(t.Host, strconv.Itoa(t.Port))
+}
+
+// Parses a user@host[:port] session target
+func parseTarget(command string) (tgt *target, err error) {
+	command = strings.TrimSpace(command)
+	if command == "" || strings.ContainsAny(command, " \t\r\n") {
+		err = &errortypes.ParseError{
+			errors.New("bastion: Invalid session target"),
+		}
+		return
+	}
+
+	tgt = &target{
+		Port: 22,
+	}
+
+	if i := strings.LastIndex(command, "@"); i >= 0 {
+		tgt.Username = command[:i]
+		command = command[i+1:]
+	}
+
+	host, portStr, e := net.SplitHostPort(command)
+	if e != nil {
+		tgt.Host = strings.Trim(command, "[]")
+	} else {
+		tgt.Host = host
+		tgt.Port, e = strconv.Atoi(portStr)
+		if e != nil || tgt.Port < 1 || tgt.Port > 65535 {
+			err = &errortypes.ParseError{
+				errors.New("bastion: Invalid session target port"),
+			}
+			return
+		}
+	}
+
+	if tgt.Host == "" || tgt.Username == "" {
+		err = &errortypes.ParseError{
+			errors.New("bastion: Invalid session target"),
+		}
+		return
+	}
+
+	return
+}
+
+func (s *server) hostKeyCallback() ssh.HostKeyCallback {
+	if !s.authr.HostCertificates {
+		return ssh.InsecureIgnoreHostKey()
+	}
+
+	checker := &ssh.CertChecker{
+		IsHostAuthority: func(auth ssh.PublicKey, address string) bool {
+			return bytes.Equal(auth.Marshal(), s.trusted.Marshal())
+		},
+	}
+
+	if !s.authr.StrictHostChecking {
+		checker.HostKeyFallback = ssh.InsecureIgnoreHostKey()
+	}
+
+	return checker.CheckHostKey
+}
+
+// Terminates an interactive session on the bastion and proxies it to the
+// target host to allow recording. The client must forward an ssh agent
+// which is used to authenticate to the target host and pass the target
+// as the command such as "ssh -A -t bastion@bastion.com root@host".
+func (s *server) handleSession(conn *ssh.ServerConn,
+	newChan ssh.NewChannel) {
+
+	channel, reqs, err := newChan.Accept()
+	if err != nil {
+		return
+	}
+	defer channel.Close()
+
+	var pty *ptyRequestData
+	agentForward := false
+
+	for req := range reqs {
+		switch req.Type {
+		case "pty-req":
+			pty = &ptyRequestData{}
+			e := ssh.Unmarshal(req.Payload, pty)
+			if e != nil {
+				pty = nil
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			break
+		case "auth-agent-req@openssh.com":
+			agentForward = true
+			_ = req.Reply(true, nil)
+			break
+		case "env":
+			_ = req.Reply(true, nil)
+			break
+		case "shell":
+			_ = req.Reply(true, nil)
+			sessionError(channel, "Session target required, "+
+				"use: ssh -A -t bastion@host user@target")
+			return
+		case "exec":
+			data := &execData{}
+			e := ssh.Unmarshal(req.Payload, data)
+			if e != nil {
+				_ = req.Reply(false, nil)
+				return
+			}
+			_ = req.Reply(true, nil)
+
+			if !agentForward {
+				sessionError(channel, "Agent forwarding required, "+
+					"use: ssh -A -t bastion@host user@target")
+				return
+			}
+
+			s.proxySession(conn, channel, reqs, pty, data.Command)
+			return
+		default:
+			_ = req.Reply(false, nil)
+		}
+	}
+}
+
+func (s *server) proxySession(conn *ssh.ServerConn, channel ssh.Channel,
+	reqs <-chan *ssh.Request, pty *ptyRequestData, command string) {
+
+	serial, _ := conn.Permissions.ExtraData["serial"].(uint64)
+	keyId, _ := conn.Permissions.ExtraData["key_id"].(string)
+	remoteAddr := conn.RemoteAddr().String()
+
+	tgt, err := parseTarget(command)
+	if err != nil {
+		sessionError(channel, "Invalid session target")
+		return
+	}
+
+	_, permitted := conn.Permissions.Extensions["permit-port-forwarding"]
+	if !permitted || !PermitOpen(settings.System.BastionPermitOpen,
+		tgt.Host, tgt.Port) {
+
+		forwardAudit(s.authr, serial, keyId, remoteAddr,
+			tgt.Address(), audit.BastionForwardDenied, nil)
+		sessionError(channel, "Destination not permitted")
+		return
+	}
+
+	agentChan, agentReqs, err := conn.OpenChannel(
+		"auth-agent@openssh.com", nil)
+	if err != nil {
+		sessionError(channel, "Failed to open forwarded agent")
+		return
+	}
+	defer agentChan.Close()
+	go ssh.DiscardRequests(agentReqs)
+
+	agentClient := agent.NewClient(agentChan)
+
+	client, err := ssh.Dial("tcp", tgt.Address(), &ssh.ClientConfig{
+		User: tgt.Username,
+		Auth: []ssh.AuthMethod{
+			ssh.PublicKeysCallback(agentClient.Signers),
+		},
+		HostKeyCallback: s.hostKeyCallback(),
+		Timeout:         10 * time.Second,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"authority_id": s.authr.Id.Hex(),
+			"destination":  tgt.Address(),
+			"error":        err,
+		}).Info("bastion: Failed to connect session target")
+
+		forwardAudit(s.authr, serial, keyId, remoteAddr,
+			tgt.Address(), audit.BastionForwardFailed, err)
+
+		sessionError(channel, "Failed to connect to target")
+		return
+	}
+	defer client.Close()
+
+	sess, err := client.NewSession()
+	if err != nil {
+		sessionError(channel, "Failed to open target session")
+		return
+	}
+	defer sess.Close()
+
+	forwardAudit(s.authr, serial, keyId, remoteAddr, tgt.Address(),
+		audit.BastionForward, nil)
+
+	var output io.Writer = channel
+	var outputErr io.Writer = channel.Stderr()
+	var rec *recording.Writer
+
+	if s.authr.ProxyRecording {
+		width := 80
+		height := 24
+		term := ""
+		if pty != nil {
+			width = int(pty.Width)
+			height = int(pty.Height)
+			term = pty.Term
+		}
+
+		rec, err = newRecording(s.authr, serial, keyId, remoteAddr,
+			tgt, width, height, term)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"authority_id": s.authr.Id.Hex(),
+				"error":        err,
+			}).Error("bastion: Failed to start session recording")
+			sessionError(channel, "Failed to start session recording")
+			return
+		}
+		defer func() {
+			e := rec.Close()
+			if e != nil {
+				logrus.WithFields(logrus.Fields{
+					"authority_id": s.authr.Id.Hex(),
+					"error":        e,
+				}).Error("bastion: Failed to close session recording")
+			}
+		}()
+
+		output = io.MultiWriter(channel, rec)
+		outputErr = io.MultiWriter(channel.Stderr(), rec)
+	}
+
+	go func() {
+		for req := range reqs {
+			if req.Type == "window-change" {
+				data := &windowChangeData{}
+				if ssh.Unmarshal(req.Payload, data) == nil {
+					if rec != nil {
+						rec.Resize(int(data.Width), int(data.Height))
+					}
+					_ = sess.WindowChange(
+						int(data.Height), int(data.Width))
+				}
+			}
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+		}
+	}()
+
+	if pty != nil {
+		err = sess.RequestPty(pty.Term, int(pty.Height), int(pty.Width),
+			ssh.TerminalModes{})
+		if err != nil {
+			sessionError(channel, "Failed to request target pty")
+			return
+		}
+	}
+
+	stdin, err := sess.StdinPipe()
+	if err != nil {
+		return
+	}
+	sess.Stdout = output
+	sess.Stderr = outputErr
+
+	err = sess.Shell()
+	if err != nil {
+		sessionError(channel, "Failed to start target shell")
+		return
+	}
+
+	go func() {
+		_, _ = io.Copy(stdin, channel)
+		_ = stdin.Close()
+	}()
+
+	status := uint32(0)
+	err = sess.Wait()
+	if err != nil {
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			status = uint32(exitErr.ExitStatus())
+		} else {
+			status = 255
+		}
+	}
+
+	_, _ = channel.SendRequest("exit-status", false,
+		ssh.Marshal(&exitStatusData{
+			Status: status,
+		}))
+}
+
+func sessionError(channel ssh.Channel, msg string) {
+	_, _ = fmt.Fprintf(channel.Stderr(), "pritunl-zero: %s\r\n", msg)
+	_, _ = channel.SendRequest("exit-status", false,
+		ssh.Marshal(&exitStatusData{
+			Status: 1,
+		}))
+}
@@ -2,12 +2,21 @@ package bastion
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/authority"
+	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/recording"
+	"github.com/pritunl/pritunl-zero/ssh"
+	"github.com/pritunl/pritunl-zero/user"
 	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/sirupsen/logrus"
 )
 
 func GetRuntime() string {
@@ -73,3 +82,155 @@ func DockerRemove(containerId string) (err error) {
 
 	return
 }
+
+// Get the user of a certificate from the issued certificate or from the
+// key id when the certificate record has expired
+func getUser(db *database.Database, authr *authority.Authority,
+	serial, keyId string) (userId bson.ObjectID, err error) {
+
+	cert, err := ssh.GetCertificateSerial(db, serial)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); !ok {
+			return
+		}
+		err = nil
+	} else {
+		userId = cert.UserId
+		return
+	}
+
+	if keyId == "" {
+		return
+	}
+
+	var query *bson.M
+	switch authr.KeyIdFormat {
+	case authority.Username:
+		query = &bson.M{
+			"username": keyId,
+		}
+		break
+	case authority.UsernameId:
+		userId, _ = utils.ParseObjectId(strings.SplitN(keyId, "-", 2)[0])
+		break
+	case authority.UsernameStripDomain:
+		query = &bson.M{
+			"username": &bson.M{
+				"$regex": "^" + regexp.QuoteMeta(keyId) + "(@|$)",
+			},
+		}
+		break
+	default:
+		userId, _ = utils.ParseObjectId(keyId)
+	}
+
+	if query != nil {
+		usrs, _, e := user.GetAll(db, query, 0, 0)
+		if e != nil {
+			err = e
+			return
+		}
+
+		// Stripped or filtered usernames may match multiple users
+		if len(usrs) == 1 {
+			userId = usrs[0].Id
+		}
+		return
+	}
+
+	if !userId.IsZero() {
+		_, err = user.Get(db, userId)
+		if err != nil {
+			userId = bson.NilObjectID
+			if _, ok := err.(*database.NotFoundError); ok {
+				err = nil
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func forwardAudit(authr *authority.Authority, serial uint64,
+	keyId, remoteAddr, destAddr, typ string, dialErr error) {
+
+	db := database.GetDatabase()
+	defer db.Close()
+
+	serialStr := fmt.Sprintf("%d", serial)
+
+	userId, err := getUser(db, authr, serialStr, keyId)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"authority_id": authr.Id.Hex(),
+			"serial":       serialStr,
+			"error":        err,
+		}).Error("bastion: Failed to find certificate user")
+		err = nil
+	}
+
+	fields := audit.Fields{
+		"authority_id": authr.Id,
+		"key_id":       keyId,
+		"serial":       serialStr,
+		"destination":  destAddr,
+	}
+	if dialErr != nil {
+		fields["error"] = dialErr.Error()
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"authority_id": authr.Id.Hex(),
+		"user_id":      userId.Hex(),
+		"key_id":       keyId,
+		"serial":       serialStr,
+		"remote":       remoteAddr,
+		"destination":  destAddr,
+		"type":         typ,
+		"error":        dialErr,
+	}).Info("bastion: Forwarded connection")
+
+	err = audit.NewAddr(db, utils.StripPort(remoteAddr), userId, typ,
+		fields)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"authority_id": authr.Id.Hex(),
+			"error":        err,
+		}).Error("bastion: Failed to audit forwarded connection")
+	}
+}
+
+func newRecording(authr *authority.Authority, serial uint64,
+	keyId, remoteAddr string, tgt *target, width, height int,
+	term string) (rec *recording.Writer, err error) {
+
+	db := database.GetDatabase()
+	defer db.Close()
+
+	serialStr := fmt.Sprintf("%d", serial)
+
+	recd := &recording.Recording{
+		Authority:   authr.Id,
+		Node:        node.Self.Id,
+		Serial:      serialStr,
+		KeyId:       keyId,
+		RemoteAddr:  utils.StripPort(remoteAddr),
+		Destination: tgt.Address(),
+		Username:    tgt.Username,
+		Width:       width,
+		Height:      height,
+	}
+
+	recd.User, err = getUser(db, authr, serialStr, keyId)
+	if err != nil {
+		return
+	}
+
+	rec, err = recording.NewWriter(db, recd, term)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,635 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type base struct {
+	names []string
+	keys  map[string]bool
+}
+
+// Shared HTTP cache for a service, entries are kept in a size bounded
+// memory tier and moved to a size bounded disk tier when evicted from
+// memory, the least recently used entries are evicted from disk
+type Cache struct {
+	serviceId  bson.ObjectID
+	path       string
+	memoryMax  int64
+	diskMax    int64
+	lock       sync.Mutex
+	entries    map[string]*entry
+	bases      map[string]*base
+	memory     *list.List
+	memorySize int64
+	disk       *list.List
+	diskSize   int64
+}
+
+func baseKey(scope string, r *http.Request) string {
+	return scope + "\x00" + strings.ToLower(utils.StripPort(r.Host)) +
+		"\x00" + r.URL.RequestURI()
+}
+
+func variantKey(baseKey string, names []string, r *http.Request) string {
+	hash := sha256.New()
+	_, _ = io.WriteString(hash, baseKey)
+	for _, name := range names {
+		_, _ = io.WriteString(hash, "\x00"+name+":"+
+			strings.Join(r.Header.Values(name), ","))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func varyNames(header http.Header) (names []string, ok bool) {
+	names = []string{}
+
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == "*" {
+				return
+			}
+
+			name = http.CanonicalHeaderKey(name)
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	ok = true
+
+	return
+}
+
+func cacheableStatus(status int) bool {
+	switch status {
+	case 200, 203, 204, 300, 301, 308, 404, 405, 410, 414, 501:
+		return true
+	}
+	return false
+}
+
+func removeFiles(files []string) {
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+
+		err := os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			logrus.WithFields(logrus.Fields{
+				"path":  file,
+				"error": err,
+			}).Error("cache: Failed to remove cache file")
+		}
+	}
+}
+
+func (c *Cache) writeFile(key string, body []byte) (
+	file string, err error) {
+
+	err = os.MkdirAll(c.path, 0700)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "cache: Failed to create cache directory"),
+		}
+		return
+	}
+
+	file = filepath.Join(c.path, key+"-"+bson.NewObjectID().Hex())
+
+	err = os.WriteFile(file, body, 0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "cache: Failed to write cache file"),
+		}
+		return
+	}
+
+	return
+}
+
+func (c *Cache) touch(ent *entry) {
+	if ent.elem == nil {
+		return
+	}
+
+	if ent.file == "" {
+		c.memory.MoveToFront(ent.elem)
+	} else {
+		c.disk.MoveToFront(ent.elem)
+	}
+}
+
+// Remove an entry from the index, must be called with the lock held and
+// the returned file removed after the lock is released
+func (c *Cache) unlink(ent *entry) (file string) {
+	if c.entries[ent.key] == ent {
+		delete(c.entries, ent.key)
+	}
+
+	if ent.elem != nil {
+		if ent.file == "" {
+			c.memory.Remove(ent.elem)
+			c.memorySize -= ent.size
+		} else {
+			c.disk.Remove(ent.elem)
+			c.diskSize -= ent.size
+		}
+		ent.elem = nil
+	}
+
+	bse := c.bases[ent.base]
+	if bse != nil {
+		delete(bse.keys, ent.key)
+		if len(bse.keys) == 0 {
+			delete(c.bases, ent.base)
+		}
+	}
+
+	file = ent.file
+	return
+}
+
+func (c *Cache) evictMemory() (demote []*entry, files []string) {
+	for c.memorySize > c.memoryMax {
+		elem := c.memory.Back()
+		if elem == nil {
+			break
+		}
+
+		ent := elem.Value.(*entry)
+		c.memory.Remove(elem)
+		c.memorySize -= ent.size
+		ent.elem = nil
+
+		if ent.size <= c.diskMax {
+			demote = append(demote, ent)
+		} else {
+			files = append(files, c.unlink(ent))
+		}
+	}
+
+	return
+}
+
+func (c *Cache) evictDisk() (files []string) {
+	for c.diskSize > c.diskMax {
+		elem := c.disk.Back()
+		if elem == nil {
+			break
+		}
+
+		files = append(files, c.unlink(elem.Value.(*entry)))
+	}
+
+	return
+}
+
+// Move entries evicted from memory to disk, entries replaced or removed
+// while the file was written are discarded
+func (c *Cache) demote(ents []*entry) {
+	for _, ent := range ents {
+		file, err := c.writeFile(ent.key, ent.body)
+
+		c.lock.Lock()
+		if err != nil || c.entries[ent.key] != ent || ent.elem != nil {
+			files := []string{file}
+			if c.entries[ent.key] == ent && ent.elem == nil {
+				files = append(files, c.unlink(ent))
+			}
+			c.lock.Unlock()
+
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"service_id": c.serviceId.Hex(),
+					"error":      err,
+				}).Error("cache: Failed to move cache entry to disk")
+			}
+
+			removeFiles(files)
+			continue
+		}
+
+		ent.file = file
+		ent.body = nil
+		ent.elem = c.disk.PushFront(ent)
+		c.diskSize += ent.size
+		files := c.evictDisk()
+		c.lock.Unlock()
+
+		removeFiles(files)
+	}
+}
+
+// Add the entry to the index replacing any existing entry with the same
+// key, must be called with the lock held
+func (c *Cache) link(ent *entry, names []string) (files []string) {
+	old := c.entries[ent.key]
+	if old != nil {
+		files = append(files, c.unlink(old))
+	}
+
+	bse := c.bases[ent.base]
+	if bse != nil && !slices.Equal(bse.names, names) {
+		for key := range bse.keys {
+			files = append(files, c.unlink(c.entries[key]))
+		}
+		bse = nil
+	}
+
+	if bse == nil {
+		bse = &base{
+			names: names,
+			keys:  map[string]bool{},
+		}
+		c.bases[ent.base] = bse
+	}
+
+	bse.keys[ent.key] = true
+	c.entries[ent.key] = ent
+
+	return
+}
+
+func (c *Cache) store(ent *entry, names []string) {
+	if ent.size > c.memoryMax && ent.size > c.diskMax {
+		return
+	}
+
+	if ent.size <= c.memoryMax {
+		c.lock.Lock()
+		files := c.link(ent, names)
+		ent.elem = c.memory.PushFront(ent)
+		c.memorySize += ent.size
+		demote, evicted := c.evictMemory()
+		c.lock.Unlock()
+
+		removeFiles(append(files, evicted...))
+		c.demote(demote)
+		return
+	}
+
+	file, err := c.writeFile(ent.key, ent.body)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"service_id": c.serviceId.Hex(),
+			"error":      err,
+		}).Error("cache: Failed to write cache entry")
+		return
+	}
+	ent.file = file
+	ent.body = nil
+
+	c.lock.Lock()
+	files := c.link(ent, names)
+	ent.elem = c.disk.PushFront(ent)
+	c.diskSize += ent.size
+	files = append(files, c.evictDisk()...)
+	c.lock.Unlock()
+
+	removeFiles(files)
+}
+
+func (c *Cache) lookup(r *http.Request, userKey string) (ent *entry) {
+	scopes := []string{""}
+	if userKey != "" {
+		scopes = append(scopes, userKey)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, scope := range scopes {
+		bseKey := baseKey(scope, r)
+		bse := c.bases[bseKey]
+		if bse == nil {
+			continue
+		}
+
+		cached := c.entries[variantKey(bseKey, bse.names, r)]
+		if cached == nil {
+			continue
+		}
+
+		c.touch(cached)
+
+		snapshot := *cached
+		snapshot.elem = nil
+		ent = &snapshot
+		return
+	}
+
+	return
+}
+
+// Update the stored headers and freshness of an entry from a not
+// modified revalidation response
+func (c *Cache) refresh(ent *entry, header http.Header,
+	responseTime time.Time) (updated *entry) {
+
+	merged := ent.header.Clone()
+	for key, vals := range header {
+		switch key {
+		case "Content-Length", "Content-Encoding", "Content-Type",
+			"Content-Range":
+
+			continue
+		}
+		merged[key] = vals
+	}
+
+	dirs := parseDirectives(merged)
+	life, _ := lifetime(merged, dirs, responseTime)
+
+	snapshot := *ent
+	snapshot.header = merged
+	snapshot.stored = responseTime.Add(-initialAge(header))
+	snapshot.lifetime = life
+	updated = &snapshot
+
+	var files []string
+	c.lock.Lock()
+	cached := c.entries[ent.key]
+	if cached != nil && cached.stored.Equal(ent.stored) {
+		if dirs.Has("no-store") {
+			files = append(files, c.unlink(cached))
+		} else {
+			cached.header = updated.header
+			cached.stored = updated.stored
+			cached.lifetime = updated.lifetime
+		}
+	}
+	c.lock.Unlock()
+
+	removeFiles(files)
+
+	return
+}
+
+func (c *Cache) save(r *http.Request, userKey string, rec *recorder,
+	responseTime time.Time) {
+
+	if !cacheableStatus(rec.status) || rec.overflow || rec.body == nil {
+		return
+	}
+
+	header := rec.cacheHeader
+	dirs := parseDirectives(header)
+
+	if dirs.Has("no-store") || header.Get("Set-Cookie") != "" ||
+		header.Get("Trailer") != "" {
+
+		return
+	}
+
+	// Responses to authenticated requests are only shared between users
+	// when the upstream explicitly allows it
+	authenticated := userKey != "" || r.Header.Get("Authorization") != ""
+	shared := dirs.Has("public") || dirs.Has("s-maxage")
+
+	scope := ""
+	if dirs.Has("private") || (authenticated && !shared) {
+		if userKey == "" {
+			return
+		}
+		scope = userKey
+	}
+
+	// Responses without an explicit lifetime are only stored for the user
+	life, ok := lifetime(header, dirs, responseTime)
+	if !ok && (scope == "" || !hasValidators(header)) {
+		return
+	}
+
+	names, ok := varyNames(header)
+	if !ok {
+		return
+	}
+
+	size := int64(rec.body.Len())
+	for key, vals := range header {
+		size += int64(len(key))
+		for _, val := range vals {
+			size += int64(len(val))
+		}
+	}
+
+	bseKey := baseKey(scope, r)
+	ent := &entry{
+		key:      variantKey(bseKey, names, r),
+		base:     bseKey,
+		path:     r.URL.Path,
+		status:   rec.status,
+		header:   header,
+		body:     bytes.Clone(rec.body.Bytes()),
+		size:     size,
+		stored:   responseTime.Add(-initialAge(header)),
+		lifetime: life,
+	}
+
+	c.store(ent, names)
+}
+
+func (c *Cache) serve(w http.ResponseWriter, r *http.Request,
+	ent *entry, state string) bool {
+
+	reader, err := ent.Open()
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+
+	header := w.Header()
+	for key, vals := range ent.header {
+		header[key] = vals
+	}
+	header.Set("Age", strconv.FormatInt(int64(ent.Age()/time.Second), 10))
+	header.Set("X-Cache", state)
+
+	if notModified(r, ent.header) {
+		header.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+
+	w.WriteHeader(ent.status)
+	if r.Method != http.MethodHead {
+		_, _ = io.Copy(w, reader)
+	}
+
+	return true
+}
+
+// Remove the cached responses for the request url after an unsafe
+// request
+func (c *Cache) Invalidate(r *http.Request, userKey string) {
+	var files []string
+
+	c.lock.Lock()
+	for _, scope := range []string{"", userKey} {
+		bse := c.bases[baseKey(scope, r)]
+		if bse == nil {
+			continue
+		}
+
+		for key := range bse.keys {
+			files = append(files, c.unlink(c.entries[key]))
+		}
+	}
+	c.lock.Unlock()
+
+	removeFiles(files)
+}
+
+// Remove cached responses with a path matching the pattern, all
+// responses are removed when the pattern is empty
+func (c *Cache) Purge(pattern string) {
+	var files []string
+
+	c.lock.Lock()
+	for _, ent := range c.entries {
+		if pattern == "" || ent.path == pattern ||
+			utils.Match(pattern, ent.path) {
+
+			files = append(files, c.unlink(ent))
+		}
+	}
+	c.lock.Unlock()
+
+	removeFiles(files)
+}
+
+func (c *Cache) Resize(memoryMax, diskMax int64) {
+	c.lock.Lock()
+	if c.memoryMax == memoryMax && c.diskMax == diskMax {
+		c.lock.Unlock()
+		return
+	}
+
+	c.memoryMax = memoryMax
+	c.diskMax = diskMax
+	demote, files := c.evictMemory()
+	files = append(files, c.evictDisk()...)
+	c.lock.Unlock()
+
+	removeFiles(files)
+	c.demote(demote)
+}
+
+func (c *Cache) Close() {
+	c.Purge("")
+
+	err := os.RemoveAll(c.path)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"service_id": c.serviceId.Hex(),
+			"error":      err,
+		}).Error("cache: Failed to remove cache directory")
+	}
+}
+
+func (c *Cache) ServeHTTP(w http.ResponseWriter, r *http.Request,
+	userKey string, next func(http.ResponseWriter, *http.Request)) {
+
+	switch r.Method {
+	case http.MethodGet:
+		break
+	case http.MethodHead, http.MethodOptions, http.MethodTrace:
+		next(w, r)
+		return
+	default:
+		c.Invalidate(r, userKey)
+		next(w, r)
+		return
+	}
+
+	reqDirs := parseDirectives(r.Header)
+	if reqDirs.Has("no-store") || r.Header.Get("Range") != "" {
+		next(w, r)
+		return
+	}
+
+	ent := c.lookup(r, userKey)
+	if ent != nil && ent.Fresh() && !reqDirs.Has("no-cache") {
+		maxAge, ok := reqDirs.Seconds("max-age")
+		if (!ok || ent.Age() <= maxAge) && c.serve(w, r, ent, "HIT") {
+			node.Self.AddServiceCacheHit(c.serviceId)
+			return
+		}
+	}
+
+	req := r
+	revalidate := false
+	if ent != nil && hasValidators(ent.header) {
+		revalidate = true
+		req = r.Clone(r.Context())
+		req.Header.Del("If-None-Match")
+		req.Header.Del("If-Modified-Since")
+
+		etag := ent.header.Get("ETag")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		lastModified := ent.header.Get("Last-Modified")
+		if lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	rec := &recorder{
+		w:          w,
+		header:     http.Header{},
+		body:       &bytes.Buffer{},
+		maxSize:    settings.Router.CacheMaxObjectSize,
+		revalidate: revalidate,
+	}
+
+	next(rec, req)
+	responseTime := time.Now()
+
+	if rec.held {
+		ent = c.refresh(ent, rec.cacheHeader, responseTime)
+		if c.serve(w, r, ent, "REVALIDATED") {
+			node.Self.AddServiceCacheHit(c.serviceId)
+			return
+		}
+
+		// The upstream has already handled the request, it is not sent
+		// again to avoid repeating side effects
+		node.Self.AddServiceCacheMiss(c.serviceId)
+		utils.WriteStatus(w, 502)
+		return
+	}
+
+	node.Self.AddServiceCacheMiss(c.serviceId)
+	c.save(r, userKey, rec, responseTime)
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type directives map[string]string
+
+func parseDirectives(header http.Header) (dirs directives) {
+	dirs = directives{}
+
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+
+			name, val, _ := strings.Cut(part, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			val = strings.Trim(strings.TrimSpace(val), "\"")
+
+			dirs[name] = val
+		}
+	}
+
+	if strings.EqualFold(header.Get("Pragma"), "no-cache") {
+		if _, ok := dirs["no-cache"]; !ok {
+			dirs["no-cache"] = ""
+		}
+	}
+
+	return
+}
+
+func (d directives) Has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+func (d directives) Seconds(name string) (dur time.Duration, ok bool) {
+	val, ok := d[name]
+	if !ok {
+		return
+	}
+
+	secs, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || secs < 0 {
+		ok = false
+		return
+	}
+
+	dur = time.Duration(secs) * time.Second
+	return
+}
+
+// Freshness lifetime of a response for a shared cache, s-maxage takes
+// precedence over max-age which takes precedence over Expires
+func lifetime(header http.Header, dirs directives,
+	responseTime time.Time) (life time.Duration, ok bool) {
+
+	if dirs.Has("no-cache") {
+		ok = true
+		return
+	}
+
+	life, ok = dirs.Seconds("s-maxage")
+	if ok {
+		return
+	}
+
+	life, ok = dirs.Seconds("max-age")
+	if ok {
+		return
+	}
+
+	expiresStr := header.Get("Expires")
+	if expiresStr == "" {
+		return
+	}
+
+	ok = true
+
+	expires, err := http.ParseTime(expiresStr)
+	if err != nil {
+		return
+	}
+
+	date := responseTime
+	dateStr := header.Get("Date")
+	if dateStr != "" {
+		parsed, e := http.ParseTime(dateStr)
+		if e == nil {
+			date = parsed
+		}
+	}
+
+	life = max(expires.Sub(date), 0)
+
+	return
+}
+
+func initialAge(header http.Header) time.Duration {
+	age, err := strconv.ParseInt(header.Get("Age"), 10, 64)
+	if err != nil || age < 0 {
+		return 0
+	}
+
+	return time.Duration(age) * time.Second
+}
+
+func hasValidators(header http.Header) bool {
+	return header.Get("ETag") != "" || header.Get("Last-Modified") != ""
+}
+
+func etagMatch(etag, match string) bool {
+	if etag == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, val := range strings.Split(match, ",") {
+		val = strings.TrimSpace(val)
+		if val == "*" || strings.TrimPrefix(val, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the conditional headers of the client request match the
+// cached response
+func notModified(r *http.Request, header http.Header) bool {
+	match := r.Header.Get("If-None-Match")
+	if match != "" {
+		return etagMatch(header.Get("ETag"), match)
+	}
+
+	sinceStr := r.Header.Get("If-Modified-Since")
+	modifiedStr := header.Get("Last-Modified")
+	if sinceStr == "" || modifiedStr == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(sinceStr)
+	if err != nil {
+		return false
+	}
+
+	modified, err := http.ParseTime(modifiedStr)
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
+}
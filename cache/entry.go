@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+type entry struct {
+	key      string
+	base     string
+	path     string
+	status   int
+	header   http.Header
+	body     []byte
+	file     string
+	size     int64
+	stored   time.Time
+	lifetime time.Duration
+	elem     *list.Element
+}
+
+func (e *entry) Age() time.Duration {
+	return max(time.Since(e.stored), 0)
+}
+
+func (e *entry) Fresh() bool {
+	return e.Age() < e.lifetime
+}
+
+func (e *entry) Open() (reader io.ReadCloser, err error) {
+	if e.file == "" {
+		reader = io.NopCloser(bytes.NewReader(e.body))
+		return
+	}
+
+	reader, err = os.Open(e.file)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/requires"
+	"github.com/sirupsen/logrus"
+)
+
+func callback(evt *event.EventPublish) {
+	purge := &purgeEvent{}
+
+	data, err := bson.Marshal(evt.Data)
+	if err == nil {
+		err = bson.Unmarshal(data, purge)
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("cache: Failed to parse purge event")
+		return
+	}
+
+	Purge(purge.Service, purge.Path)
+}
+
+func init() {
+	module := requires.New("cache")
+	module.After("settings")
+	module.Before("event")
+
+	module.Handler = func() (err error) {
+		event.Register("proxy_cache_purge", callback)
+		return
+	}
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Response writer that passes the upstream response to the client while
+// keeping a copy of the body for the cache, a not modified response to a
+// revalidation request is held back and served from the cache
+type recorder struct {
+	w           http.ResponseWriter
+	header      http.Header
+	cacheHeader http.Header
+	status      int
+	body        *bytes.Buffer
+	maxSize     int
+	overflow    bool
+	revalidate  bool
+	held        bool
+	wroteHeader bool
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.wroteHeader {
+		return
+	}
+
+	if status >= 100 && status < 200 &&
+		status != http.StatusSwitchingProtocols {
+
+		header := r.w.Header()
+		for key, vals := range r.header {
+			header[key] = vals
+		}
+		r.w.WriteHeader(status)
+		return
+	}
+	r.wroteHeader = true
+	r.status = status
+	r.cacheHeader = r.header.Clone()
+
+	if r.revalidate && status == http.StatusNotModified {
+		r.held = true
+		return
+	}
+
+	header := r.w.Header()
+	for key, vals := range r.header {
+		header[key] = vals
+	}
+	header.Set("X-Cache", "MISS")
+	// Trailers set after the body are written to the client headers
+	r.header = header
+
+	r.w.WriteHeader(status)
+}
+
+func (r *recorder) Write(data []byte) (n int, err error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+
+	if r.held {
+		n = len(data)
+		return
+	}
+
+	if !r.overflow {
+		if r.body.Len()+len(data) > r.maxSize {
+			r.overflow = true
+			r.body = nil
+		} else {
+			r.body.Write(data)
+		}
+	}
+
+	n, err = r.w.Write(data)
+	return
+}
+
+func (r *recorder) Flush() {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+
+	if r.held {
+		return
+	}
+
+	if flusher, ok := r.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (r *recorder) Unwrap() http.ResponseWriter {
+	return r.w
+}
@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"container/list"
+	"path/filepath"
+	"sync"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/service"
+	"github.com/pritunl/pritunl-zero/settings"
+)
+
+var (
+	caches     = map[bson.ObjectID]*Cache{}
+	cachesLock = sync.Mutex{}
+)
+
+type purgeEvent struct {
+	Service bson.ObjectID `bson:"service"`
+	Path    string        `bson:"path"`
+}
+
+func newCache(srvcId bson.ObjectID, memoryMax, diskMax int64) (c *Cache) {
+	c = &Cache{
+		serviceId: srvcId,
+		path: filepath.Join(settings.System.LibPath,
+			"cache", srvcId.Hex()),
+		memoryMax: memoryMax,
+		diskMax:   diskMax,
+		entries:   map[string]*entry{},
+		bases:     map[string]*base{},
+		memory:    list.New(),
+		disk:      list.New(),
+	}
+
+	// Disk entries from a previous process are not indexed
+	c.Close()
+
+	return
+}
+
+// Get the cache for the service, nil when caching is disabled
+func Get(srvc *service.Service) (c *Cache) {
+	memoryMax := int64(srvc.CacheMemorySize) * 1048576
+	diskMax := int64(srvc.CacheDiskSize) * 1048576
+
+	cachesLock.Lock()
+	c = caches[srvc.Id]
+	if !srvc.Cache {
+		delete(caches, srvc.Id)
+		cachesLock.Unlock()
+
+		if c != nil {
+			c.Close()
+		}
+		c = nil
+		return
+	}
+
+	if c == nil {
+		c = newCache(srvc.Id, memoryMax, diskMax)
+		caches[srvc.Id] = c
+		cachesLock.Unlock()
+		return
+	}
+	cachesLock.Unlock()
+
+	c.Resize(memoryMax, diskMax)
+
+	return
+}
+
+// Remove caches for services no longer served by the node
+func Prune(srvcIds set.Set) {
+	removed := []*Cache{}
+
+	cachesLock.Lock()
+	for srvcId, c := range caches {
+		if !srvcIds.Contains(srvcId) {
+			delete(caches, srvcId)
+			removed = append(removed, c)
+		}
+	}
+	cachesLock.Unlock()
+
+	for _, c := range removed {
+		c.Close()
+	}
+}
+
+func Purge(srvcId bson.ObjectID, pattern string) {
+	cachesLock.Lock()
+	c := caches[srvcId]
+	cachesLock.Unlock()
+
+	if c != nil {
+		c.Purge(pattern)
+	}
+}
+
+// Purge the service cache on all nodes
+func PublishPurge(db *database.Database, srvcId bson.ObjectID,
+	pattern string) (err error) {
+
+	err = event.Publish(db, "proxy_cache_purge", &purgeEvent{
+		Service: srvcId,
+		Path:    pattern,
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/pem"
 	"fmt"
 	"io"
+	"net/mail"
+	"net/url"
 	"strings"
 	"time"
 
@@ -29,19 +31,27 @@ type Info struct {
 }
 
 type Certificate struct {
-	Id          bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name        string        `bson:"name" json:"name"`
-	Comment     string        `bson:"comment" json:"comment"`
-	Type        string        `bson:"type" json:"type"`
-	Key         string        `bson:"key" json:"key"`
-	Certificate string        `bson:"certificate" json:"certificate"`
-	Info        *Info         `bson:"info" json:"info"`
-	AcmeHash    string        `bson:"acme_hash" json:"-"`
-	AcmeAccount string        `bson:"acme_account" json:"-"`
-	AcmeDomains []string      `bson:"acme_domains" json:"acme_domains"`
-	AcmeType    string        `bson:"acme_type" json:"acme_type"`
-	AcmeAuth    string        `bson:"acme_auth" json:"acme_auth"`
-	AcmeSecret  bson.ObjectID `bson:"acme_secret,omitempty" json:"acme_secret"`
+	Id              bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name            string        `bson:"name" json:"name"`
+	Comment         string        `bson:"comment" json:"comment"`
+	Type            string        `bson:"type" json:"type"`
+	Key             string        `bson:"key" json:"key"`
+	Certificate     string        `bson:"certificate" json:"certificate"`
+	Info            *Info         `bson:"info" json:"info"`
+	AcmeHash        string        `bson:"acme_hash" json:"-"`
+	AcmeAccount     string        `bson:"acme_account" json:"-"`
+	AcmeDomains     []string      `bson:"acme_domains" json:"acme_domains"`
+	AcmeType        string        `bson:"acme_type" json:"acme_type"`
+	AcmeAuth        string        `bson:"acme_auth" json:"acme_auth"`
+	AcmeSecret      bson.ObjectID `bson:"acme_secret,omitempty" json:"acme_secret"`
+	AcmeDirectory   string        `bson:"acme_directory" json:"acme_directory"`
+	AcmeDirectoryCa string        `bson:"acme_directory_ca" json:"acme_directory_ca"`
+	AcmeEabSecret   bson.ObjectID `bson:"acme_eab_secret,omitempty" json:"acme_eab_secret"`
+	AcmeChain       string        `bson:"acme_chain" json:"acme_chain"`
+	AcmeEmail       string        `bson:"acme_email" json:"acme_email"`
+	AcmeLastAttempt time.Time     `bson:"acme_last_attempt" json:"acme_last_attempt"`
+	AcmeLastError   string        `bson:"acme_last_error" json:"acme_last_error"`
+	AcmeFailures    int           `bson:"acme_failures" json:"acme_failures"`
 }
 
 func (c *Certificate) Validate(db *database.Database) (
@@ -61,6 +71,8 @@ func (c *Certificate) Validate(db *database.Database) (
 		case AcmeHTTP, "":
 			c.AcmeType = AcmeHTTP
 			break
+		case AcmeTLSALPN:
+			break
 		case AcmeDNS:
 			if c.AcmeSecret.IsZero() {
 				errData = &errortypes.ErrorData{
@@ -88,6 +100,10 @@ func (c *Certificate) Validate(db *database.Database) (
 			break
 		case AcmeGoogleCloud:
 			break
+		case AcmeRfc2136:
+			break
+		case AcmePowerDns:
+			break
 		default:
 			errData = &errortypes.ErrorData{
 				Error:   "acme_auth_invalid",
@@ -95,12 +111,56 @@ func (c *Certificate) Validate(db *database.Database) (
 			}
 			return
 		}
+
+		c.AcmeDirectory = strings.TrimSpace(c.AcmeDirectory)
+		if c.AcmeDirectory != "" {
+			dirUrl, e := url.Parse(c.AcmeDirectory)
+			if e != nil || dirUrl.Scheme != "https" || dirUrl.Host == "" {
+				errData = &errortypes.ErrorData{
+					Error:   "acme_directory_invalid",
+					Message: "ACME directory must be a valid HTTPS URL",
+				}
+				return
+			}
+			c.AcmeDirectory = dirUrl.String()
+		}
+
+		c.AcmeDirectoryCa = strings.TrimSpace(c.AcmeDirectoryCa)
+		if c.AcmeDirectoryCa != "" &&
+			!x509.NewCertPool().AppendCertsFromPEM(
+				[]byte(c.AcmeDirectoryCa)) {
+
+			errData = &errortypes.ErrorData{
+				Error:   "acme_directory_ca_invalid",
+				Message: "ACME directory CA certificate invalid",
+			}
+			return
+		}
+
+		c.AcmeChain = strings.TrimSpace(utils.FilterStrExt(c.AcmeChain, 128))
+
+		c.AcmeEmail = strings.TrimSpace(c.AcmeEmail)
+		if c.AcmeEmail != "" {
+			addr, e := mail.ParseAddress(c.AcmeEmail)
+			if e != nil || addr.Address != c.AcmeEmail {
+				errData = &errortypes.ErrorData{
+					Error:   "acme_email_invalid",
+					Message: "ACME account email invalid",
+				}
+				return
+			}
+		}
 	} else {
 		c.AcmeAccount = ""
 		c.AcmeDomains = []string{}
 		c.AcmeType = ""
 		c.AcmeAuth = ""
 		c.AcmeSecret = bson.NilObjectID
+		c.AcmeDirectory = ""
+		c.AcmeDirectoryCa = ""
+		c.AcmeEabSecret = bson.NilObjectID
+		c.AcmeChain = ""
+		c.AcmeEmail = ""
 	}
 
 	if c.AcmeDomains == nil {
@@ -197,6 +257,29 @@ func (c *Certificate) UpdateInfo() (err error) {
 	return
 }
 
+// Record the result of an acme renewal attempt, consecutive failures are
+// counted until the next successful renewal
+func (c *Certificate) RecordRenew(db *database.Database, renewErr error) (
+	err error) {
+
+	c.AcmeLastAttempt = time.Now()
+	if renewErr != nil {
+		c.AcmeLastError = errors.GetMessage(renewErr)
+		c.AcmeFailures += 1
+	} else {
+		c.AcmeLastError = ""
+		c.AcmeFailures = 0
+	}
+
+	err = c.CommitFields(db, set.NewSet(
+		"acme_last_attempt", "acme_last_error", "acme_failures"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
 func (c *Certificate) Commit(db *database.Database) (err error) {
 	coll := db.Certificates()
 
@@ -248,6 +331,12 @@ func (c *Certificate) Hash() string {
 	hash.Write([]byte(c.Key))
 	hash.Write([]byte(c.Certificate))
 	hash.Write([]byte(c.AcmeAccount))
+	if c.AcmeDirectory != "" {
+		_, _ = io.WriteString(hash, c.AcmeDirectory)
+	}
+	if c.AcmeChain != "" {
+		_, _ = io.WriteString(hash, c.AcmeChain)
+	}
 	if c.AcmeDomains != nil {
 		for _, domain := range c.AcmeDomains {
 			_, _ = io.WriteString(hash, domain)
@@ -4,11 +4,14 @@ const (
 	Text        = "text"
 	LetsEncrypt = "lets_encrypt"
 
-	AcmeHTTP = "acme_http"
-	AcmeDNS  = "acme_dns"
+	AcmeHTTP    = "acme_http"
+	AcmeDNS     = "acme_dns"
+	AcmeTLSALPN = "acme_tls_alpn"
 
 	AcmeAWS         = "acme_aws"
 	AcmeCloudflare  = "acme_cloudflare"
 	AcmeOracleCloud = "acme_oracle_cloud"
 	AcmeGoogleCloud = "acme_google_cloud"
+	AcmeRfc2136     = "acme_rfc2136"
+	AcmePowerDns    = "acme_powerdns"
 )
@@ -1,6 +1,8 @@
 package check
 
 import (
+	"net"
+	"regexp"
 	"strings"
 	"time"
 
@@ -12,17 +14,26 @@ import (
 )
 
 type Check struct {
-	Id         bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name       string        `bson:"name" json:"name"`
-	Roles      []string      `bson:"roles" json:"roles"`
-	Frequency  int           `bson:"frequency" json:"frequency"`
-	Type       string        `bson:"type" json:"type"`
-	Targets    []string      `bson:"targets" json:"targets"`
-	Timeout    int           `bson:"timeout" json:"timeout"`
-	Method     string        `bson:"method" json:"method"`
-	StatusCode int           `bson:"status_code" json:"status_code"`
-	Headers    []*Header     `bson:"headers" json:"headers"`
-	States     []*State      `bson:"states" json:"states"`
+	Id         bson.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name       string          `bson:"name" json:"name"`
+	Roles      []string        `bson:"roles" json:"roles"`
+	Views      []bson.ObjectID `bson:"views" json:"views"`
+	Frequency  int             `bson:"frequency" json:"frequency"`
+	Type       string          `bson:"type" json:"type"`
+	Targets    []string        `bson:"targets" json:"targets"`
+	Timeout    int             `bson:"timeout" json:"timeout"`
+	Method     string          `bson:"method" json:"method"`
+	StatusCode int             `bson:"status_code" json:"status_code"`
+	Headers    []*Header       `bson:"headers" json:"headers"`
+	Body       string          `bson:"body" json:"body"`
+	BodyMatch  string          `bson:"body_match" json:"body_match"`
+	BodyRegex  string          `bson:"body_regex" json:"body_regex"`
+	ServerName string          `bson:"server_name" json:"server_name"`
+	ExpiryDays int             `bson:"expiry_days" json:"expiry_days"`
+	DnsServer  string          `bson:"dns_server" json:"dns_server"`
+	DnsType    string          `bson:"dns_type" json:"dns_type"`
+	DnsAnswer  string          `bson:"dns_answer" json:"dns_answer"`
+	States     []*State        `bson:"states" json:"states"`
 }
 
 type State struct {
@@ -54,6 +65,27 @@ func (c *Check) Validate(db *database.Database) (
 		c.Roles = []string{}
 	}
 
+	if c.Views == nil {
+		c.Views = []bson.ObjectID{}
+	}
+
+	views := []bson.ObjectID{}
+	err = db.Views().Distinct(
+		db,
+		"_id",
+		&bson.M{
+			"_id": &bson.M{
+				"$in": c.Views,
+			},
+		},
+	).Decode(&views)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	c.Views = views
+
 	if c.Frequency == 0 {
 		c.Frequency = 30
 	}
@@ -80,10 +112,46 @@ func (c *Check) Validate(db *database.Database) (
 
 	switch c.Type {
 	case Http:
+		c.ServerName = ""
+		c.ExpiryDays = 0
+		c.DnsServer = ""
+		c.DnsType = ""
+		c.DnsAnswer = ""
 		break
 	case Ping:
 		c.Method = ""
 		c.Headers = []*Header{}
+		c.clearHttp()
+		c.ServerName = ""
+		c.ExpiryDays = 0
+		c.DnsServer = ""
+		c.DnsType = ""
+		c.DnsAnswer = ""
+		break
+	case Tcp:
+		c.Method = ""
+		c.Headers = []*Header{}
+		c.clearHttp()
+		c.ServerName = ""
+		c.ExpiryDays = 0
+		c.DnsServer = ""
+		c.DnsType = ""
+		c.DnsAnswer = ""
+		break
+	case Tls:
+		c.Method = ""
+		c.Headers = []*Header{}
+		c.clearHttp()
+		c.DnsServer = ""
+		c.DnsType = ""
+		c.DnsAnswer = ""
+		break
+	case Dns:
+		c.Method = ""
+		c.Headers = []*Header{}
+		c.clearHttp()
+		c.ServerName = ""
+		c.ExpiryDays = 0
 		break
 	default:
 		errData = &errortypes.ErrorData{
@@ -93,7 +161,8 @@ func (c *Check) Validate(db *database.Database) (
 		return
 	}
 
-	if c.Type == Http {
+	switch c.Type {
+	case Http:
 		switch strings.ToUpper(c.Method) {
 		case "":
 			c.Method = "GET"
@@ -104,6 +173,9 @@ func (c *Check) Validate(db *database.Database) (
 		case "HEAD":
 			c.Method = "HEAD"
 			break
+		case "POST":
+			c.Method = "POST"
+			break
 		default:
 			errData = &errortypes.ErrorData{
 				Error:   "check_method_invalid",
@@ -111,6 +183,117 @@ func (c *Check) Validate(db *database.Database) (
 			}
 			return
 		}
+
+		if c.Method != "POST" {
+			c.Body = ""
+		}
+		if c.Method == "HEAD" {
+			c.BodyMatch = ""
+			c.BodyRegex = ""
+		}
+
+		if len(c.Body) > 65536 {
+			errData = &errortypes.ErrorData{
+				Error:   "check_body_invalid",
+				Message: "Check request body too large",
+			}
+			return
+		}
+
+		if len(c.BodyMatch) > 1024 || len(c.BodyRegex) > 1024 {
+			errData = &errortypes.ErrorData{
+				Error:   "check_body_match_invalid",
+				Message: "Check body match too large",
+			}
+			return
+		}
+
+		if c.BodyRegex != "" {
+			_, e := regexp.Compile(c.BodyRegex)
+			if e != nil {
+				errData = &errortypes.ErrorData{
+					Error:   "check_body_regex_invalid",
+					Message: "Check body regex is invalid",
+				}
+				return
+			}
+		}
+		break
+	case Tcp, Tls:
+		for i, target := range c.Targets {
+			target = strings.TrimSpace(target)
+			if c.Type == Tls && !strings.Contains(target, ":") {
+				target += ":443"
+			}
+
+			host, port, e := net.SplitHostPort(target)
+			if e != nil || host == "" || port == "" {
+				errData = &errortypes.ErrorData{
+					Error:   "check_target_invalid",
+					Message: "Check target must be host:port",
+				}
+				return
+			}
+
+			c.Targets[i] = target
+		}
+
+		if c.Type == Tls {
+			c.ServerName = strings.TrimSpace(c.ServerName)
+
+			if c.ExpiryDays == 0 {
+				c.ExpiryDays = 14
+			}
+
+			if c.ExpiryDays < 1 || c.ExpiryDays > 365 {
+				errData = &errortypes.ErrorData{
+					Error:   "check_expiry_days_invalid",
+					Message: "Check expiry days is invalid",
+				}
+				return
+			}
+		}
+		break
+	case Dns:
+		switch strings.ToUpper(c.DnsType) {
+		case "":
+			c.DnsType = DnsA
+			break
+		case DnsA, DnsAaaa, DnsCname, DnsTxt, DnsMx, DnsNs:
+			c.DnsType = strings.ToUpper(c.DnsType)
+			break
+		default:
+			errData = &errortypes.ErrorData{
+				Error:   "check_dns_type_invalid",
+				Message: "Check DNS record type is invalid",
+			}
+			return
+		}
+
+		c.DnsServer = strings.TrimSpace(c.DnsServer)
+		if c.DnsServer != "" {
+			if net.ParseIP(c.DnsServer) != nil {
+				c.DnsServer = net.JoinHostPort(c.DnsServer, "53")
+			} else if _, _, e := net.SplitHostPort(
+				c.DnsServer); e != nil {
+
+				errData = &errortypes.ErrorData{
+					Error:   "check_dns_server_invalid",
+					Message: "Check DNS server is invalid",
+				}
+				return
+			}
+		}
+
+		c.DnsAnswer = strings.TrimSpace(c.DnsAnswer)
+		if len(c.DnsAnswer) > 1024 {
+			errData = &errortypes.ErrorData{
+				Error:   "check_dns_answer_invalid",
+				Message: "Check DNS answer too large",
+			}
+			return
+		}
+		break
 	}
 
 	if c.Headers == nil {
@@ -139,6 +322,12 @@ func (c *Check) Validate(db *database.Database) (
 	return
 }
 
+func (c *Check) clearHttp() {
+	c.Body = ""
+	c.BodyMatch = ""
+	c.BodyRegex = ""
+}
+
 func (c *Check) UpdateState(db *database.Database, state *State) (
 	updated bool, err error) {
 
@@ -3,4 +3,16 @@ package check
 const (
 	Http = "http"
 	Ping = "ping"
+	Tcp  = "tcp"
+	Tls  = "tls"
+	Dns  = "dns"
+)
+
+const (
+	DnsA     = "A"
+	DnsAaaa  = "AAAA"
+	DnsCname = "CNAME"
+	DnsTxt   = "TXT"
+	DnsMx    = "MX"
+	DnsNs    = "NS"
 )
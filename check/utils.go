@@ -200,6 +200,63 @@ func GetRoles(db *database.Database, roles []string) (
 	return
 }
 
+// Checks targeting any of the roles or views
+func GetTargets(db *database.Database, roles []string,
+	views []bson.ObjectID) (checks []*Check, err error) {
+
+	coll := db.Checks()
+	checks = []*Check{}
+
+	if roles == nil {
+		roles = []string{}
+	}
+	if views == nil {
+		views = []bson.ObjectID{}
+	}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{
+			"$or": []*bson.M{
+				&bson.M{
+					"roles": &bson.M{
+						"$in": roles,
+					},
+				},
+				&bson.M{
+					"views": &bson.M{
+						"$in": views,
+					},
+				},
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		chck := &Check{}
+		err = cursor.Decode(chck)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		checks = append(checks, chck)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func GetRolesMapped(db *database.Database, rolesSet set.Set) (
 	checksMap map[string][]*Check, err error) {
 
@@ -249,6 +306,24 @@ func RemoveData(db *database.Database, checkId bson.ObjectID) (
 		return
 	}
 
+	coll = db.EndpointsCheck5m()
+	_, err = coll.DeleteMany(db, &bson.M{
+		"c": checkId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	coll = db.EndpointsCheck1h()
+	_, err = coll.DeleteMany(db, &bson.M{
+		"c": checkId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
 	return
 }
 
@@ -0,0 +1,97 @@
+package clientcert
+
+import (
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+)
+
+type Certificate struct {
+	Id               bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserId           bson.ObjectID `bson:"user_id" json:"user_id"`
+	AuthorityId      bson.ObjectID `bson:"authority_id" json:"authority_id"`
+	Serial           string        `bson:"serial" json:"serial"`
+	CommonName       string        `bson:"common_name" json:"common_name"`
+	Comment          string        `bson:"comment" json:"comment"`
+	Timestamp        time.Time     `bson:"timestamp" json:"timestamp"`
+	Expires          time.Time     `bson:"expires" json:"expires"`
+	LastActive       time.Time     `bson:"last_active" json:"last_active"`
+	Revoked          bool          `bson:"revoked" json:"revoked"`
+	RevokedTimestamp time.Time     `bson:"revoked_timestamp" json:"revoked_timestamp"`
+	Certificate      string        `bson:"certificate" json:"certificate"`
+}
+
+func (c *Certificate) IsActive() bool {
+	return !c.Revoked && time.Now().Before(c.Expires)
+}
+
+func (c *Certificate) Revoke(db *database.Database) (err error) {
+	if c.Revoked {
+		return
+	}
+
+	c.Revoked = true
+	c.RevokedTimestamp = time.Now()
+
+	err = c.CommitFields(db, set.NewSet("revoked", "revoked_timestamp"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Update the last active time at most once per minute to limit writes
+// from certificate authenticated requests
+func (c *Certificate) UpdateActive(db *database.Database) (err error) {
+	if time.Since(c.LastActive) < 1*time.Minute {
+		return
+	}
+
+	c.LastActive = time.Now()
+
+	err = c.CommitFields(db, set.NewSet("last_active"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (c *Certificate) Commit(db *database.Database) (err error) {
+	coll := db.ClientCertificates()
+
+	err = coll.Commit(c.Id, c)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (c *Certificate) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.ClientCertificates()
+
+	err = coll.CommitFields(c.Id, c, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (c *Certificate) Insert(db *database.Database) (err error) {
+	coll := db.ClientCertificates()
+
+	_, err = coll.InsertOne(db, c)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,6 @@
+package clientcert
+
+const (
+	DefaultExpire = 365
+	MaxExpire     = 3650
+)
@@ -0,0 +1,303 @@
+package clientcert
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/authority"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/lockout"
+	"github.com/pritunl/pritunl-zero/user"
+)
+
+func Get(db *database.Database, certId bson.ObjectID) (
+	cert *Certificate, err error) {
+
+	coll := db.ClientCertificates()
+	cert = &Certificate{}
+
+	err = coll.FindOneId(certId, cert)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetSerial(db *database.Database, authrId bson.ObjectID,
+	serial string) (cert *Certificate, err error) {
+
+	coll := db.ClientCertificates()
+	cert = &Certificate{}
+
+	err = coll.FindOne(db, &bson.M{
+		"authority_id": authrId,
+		"serial":       serial,
+	}).Decode(cert)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAll(db *database.Database, userId bson.ObjectID) (
+	certs []*Certificate, err error) {
+
+	coll := db.ClientCertificates()
+	certs = []*Certificate{}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{
+			"user_id": userId,
+		},
+		options.Find().
+			SetSort(bson.D{{"timestamp", -1}}),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		cert := &Certificate{}
+		err = cursor.Decode(cert)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		certs = append(certs, cert)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func parseCsr(csrPem string) (pubKey crypto.PublicKey, err error) {
+	block, _ := pem.Decode([]byte(csrPem))
+	if block == nil {
+		err = &errortypes.ParseError{
+			errors.New("clientcert: Failed to decode certificate request"),
+		}
+		return
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err,
+				"clientcert: Failed to parse certificate request"),
+		}
+		return
+	}
+
+	err = csr.CheckSignature()
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err,
+				"clientcert: Invalid certificate request signature"),
+		}
+		return
+	}
+
+	pubKey = csr.PublicKey
+
+	return
+}
+
+// Issue a client certificate for the user from the authority, a private
+// key is generated and returned once when no certificate request is
+// provided, only the certificate and serial are stored
+func New(db *database.Database, authr *authority.Authority,
+	usr *user.User, csrPem, comment string, expireDays int) (
+	cert *Certificate, keyPem string, errData *errortypes.ErrorData,
+	err error) {
+
+	if expireDays == 0 {
+		expireDays = DefaultExpire
+	}
+
+	if expireDays < 1 || expireDays > MaxExpire {
+		errData = &errortypes.ErrorData{
+			Error:   "client_certificate_expire_invalid",
+			Message: "Client certificate expiration is invalid",
+		}
+		return
+	}
+
+	if authr.Type == authority.PritunlHsm || authr.RootCertificate == "" {
+		errData = &errortypes.ErrorData{
+			Error:   "client_certificate_authority_invalid",
+			Message: "Authority cannot issue client certificates",
+		}
+		return
+	}
+
+	if !authr.UserHasAccess(usr) {
+		errData = &errortypes.ErrorData{
+			Error:   "client_certificate_unauthorized",
+			Message: "User does not have roles required by authority",
+		}
+		return
+	}
+
+	var pubKey crypto.PublicKey
+	csrPem = strings.TrimSpace(csrPem)
+	if csrPem != "" {
+		pubKey, err = parseCsr(csrPem)
+		if err != nil {
+			err = nil
+			errData = &errortypes.ErrorData{
+				Error:   "client_certificate_request_invalid",
+				Message: "Certificate signing request is invalid",
+			}
+			return
+		}
+	} else {
+		privateKey, e := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if e != nil {
+			err = &errortypes.ReadError{
+				errors.Wrap(e, "clientcert: Failed to generate ec key"),
+			}
+			return
+		}
+
+		keyBytes, e := x509.MarshalECPrivateKey(privateKey)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "clientcert: Failed to marshal ec key"),
+			}
+			return
+		}
+
+		keyPem = strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: keyBytes,
+		})))
+		pubKey = privateKey.Public()
+	}
+
+	serialMax := new(big.Int).Lsh(big.NewInt(1), 128)
+	serial, err := rand.Int(rand.Reader, serialMax)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "clientcert: Failed to generate serial"),
+		}
+		return
+	}
+
+	expires := time.Now().Add(time.Duration(expireDays) * 24 * time.Hour)
+
+	certPem, err := authr.CreateUserCertificate(
+		db, usr, pubKey, serial, expires)
+	if err != nil {
+		return
+	}
+
+	cert = &Certificate{
+		Id:          bson.NewObjectID(),
+		UserId:      usr.Id,
+		AuthorityId: authr.Id,
+		Serial:      serial.Text(16),
+		CommonName:  usr.Username,
+		Comment:     comment,
+		Timestamp:   time.Now(),
+		Expires:     expires,
+		Certificate: certPem,
+	}
+
+	err = cert.Insert(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Map a verified client certificate to the user it was issued for, the
+// serial must be a known unrevoked certificate from the authority and the
+// subject must match the username
+func Authenticate(db *database.Database, authrId bson.ObjectID,
+	leaf *x509.Certificate) (usr *user.User, cert *Certificate,
+	errData *errortypes.ErrorData, err error) {
+
+	cert, err = GetSerial(db, authrId, leaf.SerialNumber.Text(16))
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+			cert = nil
+			errData = &errortypes.ErrorData{
+				Error:   "client_certificate_unknown",
+				Message: "Client certificate not issued by authority",
+			}
+		}
+		return
+	}
+
+	if cert.Revoked {
+		errData = &errortypes.ErrorData{
+			Error:   "client_certificate_revoked",
+			Message: "Client certificate has been revoked",
+		}
+		return
+	}
+
+	if !cert.IsActive() {
+		errData = &errortypes.ErrorData{
+			Error:   "client_certificate_expired",
+			Message: "Client certificate has expired",
+		}
+		return
+	}
+
+	usr, err = user.Get(db, cert.UserId)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+			usr = nil
+			errData = &errortypes.ErrorData{
+				Error:   "client_certificate_user_invalid",
+				Message: "Client certificate user not found",
+			}
+		}
+		return
+	}
+
+	if leaf.Subject.CommonName != usr.Username &&
+		!slices.Contains(leaf.EmailAddresses, usr.Username) {
+
+		errData = &errortypes.ErrorData{
+			Error:   "client_certificate_subject_invalid",
+			Message: "Client certificate subject does not match user",
+		}
+		return
+	}
+
+	errData = lockout.CheckUser(usr)
+	if errData != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/authority"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const emergencyMaxValidity = 24 * time.Hour
+
+type emergencyAudit struct {
+	Timestamp   time.Time `json:"timestamp"`
+	OsUser      string    `json:"os_user"`
+	Hostname    string    `json:"hostname"`
+	Authority   string    `json:"authority"`
+	KeyId       string    `json:"key_id"`
+	Principals  []string  `json:"principals"`
+	Serial      uint64    `json:"serial"`
+	ValidAfter  time.Time `json:"valid_after"`
+	ValidBefore time.Time `json:"valid_before"`
+	Fingerprint string    `json:"fingerprint"`
+	Output      string    `json:"output"`
+}
+
+func init() {
+	EmergencySignCmd.PersistentFlags().String(
+		"authority",
+		"",
+		"Authority name, ID or index in export, required with "+
+			"multiple authorities",
+	)
+	EmergencySignCmd.PersistentFlags().StringSlice(
+		"principals",
+		[]string{},
+		"Certificate principals",
+	)
+	EmergencySignCmd.PersistentFlags().Duration(
+		"validity",
+		time.Hour,
+		"Certificate validity, maximum 24h",
+	)
+	EmergencySignCmd.PersistentFlags().String(
+		"key-id",
+		"",
+		"Certificate key ID",
+	)
+	EmergencySignCmd.PersistentFlags().String(
+		"output",
+		"",
+		"Certificate output path, defaults to public key path "+
+			"with -cert.pub suffix",
+	)
+	EmergencySignCmd.PersistentFlags().String(
+		"audit-log",
+		"pritunl-zero-emergency.log",
+		"Local audit log path",
+	)
+	RootCmd.AddCommand(EmergencySignCmd)
+}
+
+func emergencyLoadAuthority(exportPath, authrName string) (
+	authr *exportAuthority, err error) {
+
+	exportByt, err := ioutil.ReadFile(exportPath)
+	if err != nil {
+		err = &errortypes.ReadError{
+			errors.Wrap(err, "cmd.emergency: Failed to read export file"),
+		}
+		return
+	}
+
+	data := &exportData{}
+	err = json.Unmarshal(exportByt, data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "cmd.emergency: Failed to parse export file"),
+		}
+		return
+	}
+
+	authrs := data.Authorities
+	if len(authrs) == 0 {
+		// Exports from older versions only contain keys
+		for i, key := range data.Keys {
+			authrs = append(authrs, &exportAuthority{
+				Name: strconv.Itoa(i),
+				Key:  key,
+			})
+		}
+	}
+
+	if len(authrs) == 0 {
+		err = &errortypes.NotFoundError{
+			errors.New("cmd.emergency: Export file has no authorities"),
+		}
+		return
+	}
+
+	if authrName == "" {
+		if len(authrs) > 1 {
+			err = &errortypes.ParseError{
+				errors.New("cmd.emergency: Export file has multiple " +
+					"authorities, authority must be specified"),
+			}
+			return
+		}
+
+		authr = authrs[0]
+		return
+	}
+
+	for i, exportAuthr := range authrs {
+		if exportAuthr.Id == authrName || exportAuthr.Name == authrName ||
+			strconv.Itoa(i) == authrName {
+
+			authr = exportAuthr
+			return
+		}
+	}
+
+	err = &errortypes.NotFoundError{
+		errors.Newf("cmd.emergency: Authority '%s' not found", authrName),
+	}
+
+	return
+}
+
+func emergencyDecryptKey(encKey, passphrase string) (
+	signer ssh.Signer, err error) {
+
+	block, _ := pem.Decode([]byte(encKey))
+	if block == nil {
+		err = &errortypes.ParseError{
+			errors.New("cmd.emergency: Failed to decode authority key"),
+		}
+		return
+	}
+
+	keyByt, err := x509.DecryptPEMBlock(block, []byte(passphrase))
+	if err != nil {
+		err = &errortypes.AuthenticationError{
+			errors.Wrap(err, "cmd.emergency: Failed to decrypt authority key"),
+		}
+		return
+	}
+
+	privateKey, err := authority.ParsePemKey(string(pem.EncodeToMemory(
+		&pem.Block{
+			Type:  block.Type,
+			Bytes: keyByt,
+		},
+	)))
+	if err != nil {
+		return
+	}
+
+	signer, err = ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "cmd.emergency: Failed to load authority key"),
+		}
+		return
+	}
+
+	return
+}
+
+func emergencyWriteAudit(path string, adt *emergencyAudit) (err error) {
+	adtByt, err := json.Marshal(adt)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "cmd.emergency: Failed to marshal audit"),
+		}
+		return
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0600)
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "cmd.emergency: Failed to open audit log"),
+		}
+		return
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(adtByt, '\n'))
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "cmd.emergency: Failed to write audit log"),
+		}
+		return
+	}
+
+	return
+}
+
+var EmergencySignCmd = &cobra.Command{
+	Use:   "emergency-sign [export_path] [public_key_path]",
+	Short: "Sign SSH public key with exported authority without database",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) < 2 || args[0] == "" || args[1] == "" {
+			fmt.Fprintln(os.Stderr, "Missing required args")
+			os.Exit(1)
+			return
+		}
+
+		exportPath := args[0]
+		pubKeyPath := args[1]
+
+		authrName, _ := cmd.Flags().GetString("authority")
+		principals, _ := cmd.Flags().GetStringSlice("principals")
+		validity, _ := cmd.Flags().GetDuration("validity")
+		keyId, _ := cmd.Flags().GetString("key-id")
+		outputPath, _ := cmd.Flags().GetString("output")
+		auditPath, _ := cmd.Flags().GetString("audit-log")
+
+		if len(principals) == 0 {
+			fmt.Fprintln(os.Stderr, "Certificate principals required")
+			os.Exit(1)
+			return
+		}
+
+		if validity <= 0 || validity > emergencyMaxValidity {
+			fmt.Fprintf(
+				os.Stderr,
+				"Invalid validity, must be between 0 and %s\n",
+				emergencyMaxValidity,
+			)
+			os.Exit(1)
+			return
+		}
+
+		if outputPath == "" {
+			outputPath = strings.TrimSuffix(pubKeyPath, ".pub") +
+				"-cert.pub"
+		}
+
+		authr, err := emergencyLoadAuthority(exportPath, authrName)
+		if err != nil {
+			cobra.CheckErr(err)
+			return
+		}
+
+		pubKeyByt, err := ioutil.ReadFile(pubKeyPath)
+		if err != nil {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "cmd.emergency: Failed to read public key"),
+			}
+			cobra.CheckErr(err)
+			return
+		}
+
+		pubKey, comment, _, _, err := ssh.ParseAuthorizedKey(pubKeyByt)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "cmd.emergency: Failed to parse public key"),
+			}
+			cobra.CheckErr(err)
+			return
+		}
+
+		fmt.Print("Enter decryption passphrase: ")
+		passByt, err := terminal.ReadPassword(int(syscall.Stdin))
+		if err != nil {
+			err = &errortypes.ReadError{
+				errors.Wrap(err, "cmd.emergency: Failed to read passphrase"),
+			}
+			cobra.CheckErr(err)
+			return
+		}
+		fmt.Println("")
+
+		signer, err := emergencyDecryptKey(authr.Key, string(passByt))
+		if err != nil {
+			cobra.CheckErr(err)
+			return
+		}
+
+		osUser := ""
+		curUser, e := user.Current()
+		if e == nil {
+			osUser = curUser.Username
+		}
+		hostname, _ := os.Hostname()
+
+		if keyId == "" {
+			keyId = fmt.Sprintf("emergency-%s@%s", osUser, hostname)
+		}
+
+		serialHash := fnv.New64a()
+		_, _ = serialHash.Write([]byte(bson.NewObjectID().Hex()))
+		serial := serialHash.Sum64()
+
+		now := time.Now()
+		validAfter := now.Add(-3 * time.Minute)
+		validBefore := now.Add(validity)
+
+		cert := &ssh.Certificate{
+			Key:             pubKey,
+			Serial:          serial,
+			CertType:        ssh.UserCert,
+			KeyId:           keyId,
+			ValidPrincipals: principals,
+			ValidAfter:      uint64(validAfter.Unix()),
+			ValidBefore:     uint64(validBefore.Unix()),
+			Permissions: ssh.Permissions{
+				Extensions: map[string]string{
+					"permit-X11-forwarding":   "",
+					"permit-agent-forwarding": "",
+					"permit-port-forwarding":  "",
+					"permit-pty":              "",
+					"permit-user-rc":          "",
+				},
+			},
+		}
+
+		err = cert.SignCert(rand.Reader, signer)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "cmd.emergency: Failed to sign certificate"),
+			}
+			cobra.CheckErr(err)
+			return
+		}
+
+		certByt, err := authority.MarshalCertificate(cert, comment)
+		if err != nil {
+			cobra.CheckErr(err)
+			return
+		}
+
+		// Audit is written before the certificate to prevent unrecorded use
+		err = emergencyWriteAudit(auditPath, &emergencyAudit{
+			Timestamp:   now,
+			OsUser:      osUser,
+			Hostname:    hostname,
+			Authority:   authr.Name,
+			KeyId:       keyId,
+			Principals:  principals,
+			Serial:      serial,
+			ValidAfter:  validAfter,
+			ValidBefore: validBefore,
+			Fingerprint: ssh.FingerprintSHA256(pubKey),
+			Output:      outputPath,
+		})
+		if err != nil {
+			cobra.CheckErr(err)
+			return
+		}
+
+		err = ioutil.WriteFile(outputPath, certByt, 0644)
+		if err != nil {
+			err = &errortypes.WriteError{
+				errors.Wrap(err, "cmd.emergency: Failed to write certificate"),
+			}
+			cobra.CheckErr(err)
+			return
+		}
+
+		fmt.Printf("Successfully signed certificate to %s\n", outputPath)
+	},
+}
@@ -15,8 +15,16 @@ import (
 	"golang.org/x/crypto/ssh/terminal"
 )
 
+type exportAuthority struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"`
+	Key       string `json:"key"`
+}
+
 type exportData struct {
-	Keys []string `json:"keys"`
+	Keys        []string           `json:"keys"`
+	Authorities []*exportAuthority `json:"authorities"`
 }
 
 func init() {
@@ -63,6 +71,7 @@ var ExportSshCmd = &cobra.Command{
 		}
 
 		keys := []string{}
+		exportAuthrs := []*exportAuthority{}
 
 		for _, authr := range authrs {
 			key, e := authr.Export(pass)
@@ -73,10 +82,17 @@ var ExportSshCmd = &cobra.Command{
 			}
 
 			keys = append(keys, key)
+			exportAuthrs = append(exportAuthrs, &exportAuthority{
+				Id:        authr.Id.Hex(),
+				Name:      authr.Name,
+				PublicKey: authr.PublicKey,
+				Key:       key,
+			})
 		}
 
 		data := &exportData{
-			Keys: keys,
+			Keys:        keys,
+			Authorities: exportAuthrs,
 		}
 
 		marhData, err := json.Marshal(data)
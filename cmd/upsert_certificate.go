@@ -49,18 +49,44 @@ func init() {
 	UpsertCertificateCmd.PersistentFlags().String(
 		"acme-type",
 		"",
-		"ACME verification method (http, dns)",
+		"ACME verification method (http, dns, tls_alpn)",
 	)
 	UpsertCertificateCmd.PersistentFlags().String(
 		"acme-api",
 		"",
-		"ACME DNS provider (aws, cloudflare, oracle_cloud, google_cloud)",
+		"ACME DNS provider (aws, cloudflare, oracle_cloud, google_cloud, "+
+			"rfc2136, powerdns)",
 	)
 	UpsertCertificateCmd.PersistentFlags().String(
 		"acme-secret",
 		"",
 		"Secret by name with API key for ACME DNS",
 	)
+	UpsertCertificateCmd.PersistentFlags().String(
+		"acme-directory",
+		"",
+		"ACME directory URL, defaults to Let's Encrypt",
+	)
+	UpsertCertificateCmd.PersistentFlags().String(
+		"acme-directory-ca",
+		"",
+		"PEM CA certificate trusted for the ACME directory",
+	)
+	UpsertCertificateCmd.PersistentFlags().String(
+		"acme-eab-secret",
+		"",
+		"Secret by name with ACME external account binding",
+	)
+	UpsertCertificateCmd.PersistentFlags().String(
+		"acme-chain",
+		"",
+		"Preferred ACME chain by issuer common name",
+	)
+	UpsertCertificateCmd.PersistentFlags().String(
+		"acme-email",
+		"",
+		"ACME account contact email",
+	)
 	UpsertCmd.AddCommand(UpsertCertificateCmd)
 }
 
@@ -148,6 +174,8 @@ var UpsertCertificateCmd = &cobra.Command{
 				cert.AcmeType = certificate.AcmeHTTP
 			case "dns":
 				cert.AcmeType = certificate.AcmeDNS
+			case "tls_alpn":
+				cert.AcmeType = certificate.AcmeTLSALPN
 			case "":
 				cert.AcmeAuth = ""
 			default:
@@ -173,6 +201,10 @@ var UpsertCertificateCmd = &cobra.Command{
 				cert.AcmeAuth = certificate.AcmeOracleCloud
 			case "google_cloud":
 				cert.AcmeAuth = certificate.AcmeGoogleCloud
+			case "rfc2136":
+				cert.AcmeAuth = certificate.AcmeRfc2136
+			case "powerdns":
+				cert.AcmeAuth = certificate.AcmePowerDns
 			case "":
 				cert.AcmeAuth = ""
 			default:
@@ -209,6 +241,51 @@ var UpsertCertificateCmd = &cobra.Command{
 			}
 		}
 
+		if cmd.Flags().Changed("acme-directory") {
+			fields.Add("acme_directory")
+			cert.AcmeDirectory, _ = cmd.Flags().GetString("acme-directory")
+		}
+
+		if cmd.Flags().Changed("acme-directory-ca") {
+			fields.Add("acme_directory_ca")
+			cert.AcmeDirectoryCa, _ = cmd.Flags().GetString(
+				"acme-directory-ca")
+		}
+
+		if cmd.Flags().Changed("acme-eab-secret") {
+			eabSecret, _ := cmd.Flags().GetString("acme-eab-secret")
+
+			fields.Add("acme_eab_secret")
+			if eabSecret == "" {
+				cert.AcmeEabSecret = bson.NilObjectID
+			} else {
+				secr, e := secret.GetOne(db, &bson.M{
+					"name": eabSecret,
+				})
+				if e != nil {
+					err = e
+					if _, ok := err.(*database.NotFoundError); ok {
+						fmt.Fprintf(os.Stderr,
+							"Failed to find secret '%s'\n", eabSecret)
+						os.Exit(1)
+					}
+					return
+				}
+
+				cert.AcmeEabSecret = secr.Id
+			}
+		}
+
+		if cmd.Flags().Changed("acme-chain") {
+			fields.Add("acme_chain")
+			cert.AcmeChain, _ = cmd.Flags().GetString("acme-chain")
+		}
+
+		if cmd.Flags().Changed("acme-email") {
+			fields.Add("acme_email")
+			cert.AcmeEmail, _ = cmd.Flags().GetString("acme-email")
+		}
+
 		errData, err := cert.Validate(db)
 		if err != nil {
 			return
@@ -88,6 +88,16 @@ func init() {
 		"",
 		"Forwarded proto header",
 	)
+	UpsertNodeCmd.PersistentFlags().Bool(
+		"proxy-protocol",
+		false,
+		"Enable PROXY protocol from trusted sources",
+	)
+	UpsertNodeCmd.PersistentFlags().StringSlice(
+		"proxy-protocol-source",
+		[]string{},
+		"Trusted PROXY protocol source subnet",
+	)
 	UpsertNodeCmd.PersistentFlags().String(
 		"hostname",
 		"",
@@ -291,6 +301,17 @@ var UpsertNodeCmd = &cobra.Command{
 				"forwarded-proto-header")
 		}
 
+		if cmd.Flags().Changed("proxy-protocol") {
+			fields.Add("proxy_protocol")
+			nde.ProxyProtocol, _ = cmd.Flags().GetBool("proxy-protocol")
+		}
+
+		if cmd.Flags().Changed("proxy-protocol-source") {
+			fields.Add("proxy_protocol_sources")
+			nde.ProxyProtocolSources, _ = cmd.Flags().GetStringSlice(
+				"proxy-protocol-source")
+		}
+
 		if cmd.Flags().Changed("hostname") {
 			fields.Add("hostname")
 			nde.Hostname, _ = cmd.Flags().GetString("hostname")
@@ -68,6 +68,51 @@ func init() {
 		"",
 		"GCP service account JSON credentials",
 	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"eab-kid",
+		"",
+		"ACME external account binding key ID",
+	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"eab-hmac",
+		"",
+		"ACME external account binding HMAC key",
+	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"rfc2136-server",
+		"",
+		"RFC 2136 DNS server address",
+	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"rfc2136-key-name",
+		"",
+		"RFC 2136 TSIG key name",
+	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"rfc2136-key-secret",
+		"",
+		"RFC 2136 base64 TSIG key secret",
+	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"rfc2136-algorithm",
+		"",
+		"RFC 2136 TSIG algorithm (hmac-sha256, hmac-sha512, hmac-sha1)",
+	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"powerdns-url",
+		"",
+		"PowerDNS API URL",
+	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"powerdns-key",
+		"",
+		"PowerDNS API key",
+	)
+	UpsertSecretCmd.PersistentFlags().String(
+		"powerdns-server",
+		"",
+		"PowerDNS server ID",
+	)
 	UpsertCmd.AddCommand(UpsertSecretCmd)
 }
 
@@ -115,6 +160,7 @@ var UpsertSecretCmd = &cobra.Command{
 		if cmd.Flags().Changed("type") {
 			secrType, _ := cmd.Flags().GetString("type")
 			fields.Add("type")
+			fields.Add("algorithm")
 			switch secrType {
 			case "aws":
 				secr.Type = secret.AWS
@@ -124,6 +170,12 @@ var UpsertSecretCmd = &cobra.Command{
 				secr.Type = secret.OracleCloud
 			case "gcp":
 				secr.Type = secret.GoogleCloud
+			case "acme_eab":
+				secr.Type = secret.AcmeEab
+			case "rfc2136":
+				secr.Type = secret.Rfc2136
+			case "powerdns":
+				secr.Type = secret.PowerDns
 			default:
 				fmt.Fprintf(
 					os.Stderr,
@@ -178,6 +230,52 @@ var UpsertSecretCmd = &cobra.Command{
 			}
 		}
 
+		if secr.Type == secret.AcmeEab {
+			if cmd.Flags().Changed("eab-kid") {
+				fields.Add("key")
+				secr.Key, _ = cmd.Flags().GetString("eab-kid")
+			}
+			if cmd.Flags().Changed("eab-hmac") {
+				fields.Add("value")
+				secr.Value, _ = cmd.Flags().GetString("eab-hmac")
+			}
+		}
+
+		if secr.Type == secret.Rfc2136 {
+			if cmd.Flags().Changed("rfc2136-key-name") {
+				fields.Add("key")
+				secr.Key, _ = cmd.Flags().GetString("rfc2136-key-name")
+			}
+			if cmd.Flags().Changed("rfc2136-key-secret") {
+				fields.Add("value")
+				secr.Value, _ = cmd.Flags().GetString("rfc2136-key-secret")
+			}
+			if cmd.Flags().Changed("rfc2136-server") {
+				fields.Add("region")
+				secr.Region, _ = cmd.Flags().GetString("rfc2136-server")
+			}
+			if cmd.Flags().Changed("rfc2136-algorithm") {
+				fields.Add("algorithm")
+				secr.Algorithm, _ = cmd.Flags().GetString(
+					"rfc2136-algorithm")
+			}
+		}
+
+		if secr.Type == secret.PowerDns {
+			if cmd.Flags().Changed("powerdns-key") {
+				fields.Add("key")
+				secr.Key, _ = cmd.Flags().GetString("powerdns-key")
+			}
+			if cmd.Flags().Changed("powerdns-server") {
+				fields.Add("value")
+				secr.Value, _ = cmd.Flags().GetString("powerdns-server")
+			}
+			if cmd.Flags().Changed("powerdns-url") {
+				fields.Add("region")
+				secr.Region, _ = cmd.Flags().GetString("powerdns-url")
+			}
+		}
+
 		errData, err := secr.Validate(db)
 		if err != nil {
 			return
@@ -77,6 +77,41 @@ func init() {
 		false,
 		"Enable whitelist options",
 	)
+	UpsertServiceCmd.PersistentFlags().String(
+		"mtls-mode",
+		"",
+		"Client certificate authentication mode (optional, required, none)",
+	)
+	UpsertServiceCmd.PersistentFlags().String(
+		"mtls-authority",
+		"",
+		"Client certificate authentication authority ID",
+	)
+	UpsertServiceCmd.PersistentFlags().Bool(
+		"cache",
+		false,
+		"Enable response caching",
+	)
+	UpsertServiceCmd.PersistentFlags().Int(
+		"cache-memory-size",
+		0,
+		"Response cache memory size in megabytes",
+	)
+	UpsertServiceCmd.PersistentFlags().Int(
+		"cache-disk-size",
+		0,
+		"Response cache disk size in megabytes",
+	)
+	UpsertServiceCmd.PersistentFlags().Bool(
+		"maintenance",
+		false,
+		"Serve maintenance page without contacting servers",
+	)
+	UpsertServiceCmd.PersistentFlags().Bool(
+		"replace-error-pages",
+		false,
+		"Replace upstream 404, 502 and 503 html responses with service pages",
+	)
 	UpsertCmd.AddCommand(UpsertServiceCmd)
 }
 
@@ -273,6 +308,69 @@ var UpsertServiceCmd = &cobra.Command{
 			}
 		}
 
+		if cmd.Flags().Changed("cache") {
+			fields.Add("cache")
+			fields.Add("cache_memory_size")
+			srvc.Cache, _ = cmd.Flags().GetBool("cache")
+		}
+
+		if cmd.Flags().Changed("cache-memory-size") {
+			fields.Add("cache_memory_size")
+			srvc.CacheMemorySize, _ = cmd.Flags().GetInt(
+				"cache-memory-size")
+		}
+
+		if cmd.Flags().Changed("cache-disk-size") {
+			fields.Add("cache_disk_size")
+			srvc.CacheDiskSize, _ = cmd.Flags().GetInt("cache-disk-size")
+		}
+
+		if cmd.Flags().Changed("maintenance") {
+			fields.Add("maintenance")
+			srvc.Maintenance, _ = cmd.Flags().GetBool("maintenance")
+		}
+
+		if cmd.Flags().Changed("replace-error-pages") {
+			fields.Add("replace_error_pages")
+			srvc.ReplaceErrorPages, _ = cmd.Flags().GetBool(
+				"replace-error-pages")
+		}
+
+		if cmd.Flags().Changed("mtls-mode") {
+			fields.Add("mtls_mode")
+			fields.Add("mtls_authority")
+			mtlsMode, _ := cmd.Flags().GetString("mtls-mode")
+			switch mtlsMode {
+			case service.MtlsOptional:
+				srvc.MtlsMode = service.MtlsOptional
+				break
+			case service.MtlsRequired:
+				srvc.MtlsMode = service.MtlsRequired
+				break
+			case "none", "":
+				srvc.MtlsMode = ""
+				break
+			default:
+				fmt.Fprintln(os.Stderr, "Service mtls mode invalid")
+				os.Exit(1)
+			}
+		}
+
+		mtlsAuthority, _ := cmd.Flags().GetString("mtls-authority")
+		if mtlsAuthority != "" {
+			fields.Add("mtls_authority")
+			mtlsAuthorityId, e := bson.ObjectIDFromHex(mtlsAuthority)
+			if e != nil {
+				fmt.Fprintf(
+					os.Stderr,
+					"Invalid mtls authority ID '%s'\n",
+					mtlsAuthority,
+				)
+				os.Exit(1)
+			}
+			srvc.MtlsAuthority = mtlsAuthorityId
+		}
+
 		errData, err := srvc.Validate(db)
 		if err != nil {
 			return
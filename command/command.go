@@ -0,0 +1,143 @@
+package command
+
+import (
+	"path"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+type Command struct {
+	Id      bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name    string        `bson:"name" json:"name"`
+	Comment string        `bson:"comment" json:"comment"`
+	Roles   []string      `bson:"roles" json:"roles"`
+	Exec    string        `bson:"exec" json:"exec"`
+	Args    []string      `bson:"args" json:"args"`
+	Timeout int           `bson:"timeout" json:"timeout"`
+}
+
+// Request sent to an endpoint to execute a command for a run
+type Request struct {
+	Run     bson.ObjectID `json:"run"`
+	Exec    string        `json:"exec"`
+	Args    []string      `json:"args"`
+	Timeout int           `json:"timeout"`
+}
+
+func (c *Command) Validate(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	c.Name = utils.FilterName(c.Name)
+
+	if c.Id.IsZero() {
+		c.Id, err = utils.RandObjectId()
+		if err != nil {
+			return
+		}
+	}
+
+	if c.Roles == nil {
+		c.Roles = []string{}
+	}
+
+	if c.Args == nil {
+		c.Args = []string{}
+	}
+
+	c.Exec = strings.TrimSpace(c.Exec)
+	if c.Exec == "" || !path.IsAbs(c.Exec) ||
+		path.Clean(c.Exec) != c.Exec {
+
+		errData = &errortypes.ErrorData{
+			Error:   "command_exec_invalid",
+			Message: "Command executable must be an absolute path",
+		}
+		return
+	}
+
+	if len(c.Args) > 64 {
+		errData = &errortypes.ErrorData{
+			Error:   "command_args_invalid",
+			Message: "Command has too many arguments",
+		}
+		return
+	}
+
+	for _, arg := range c.Args {
+		if len(arg) > 1024 || strings.ContainsRune(arg, 0) {
+			errData = &errortypes.ErrorData{
+				Error:   "command_args_invalid",
+				Message: "Command argument is invalid",
+			}
+			return
+		}
+	}
+
+	if c.Timeout == 0 {
+		c.Timeout = 60
+	}
+
+	if c.Timeout < 1 || c.Timeout > 3600 {
+		errData = &errortypes.ErrorData{
+			Error:   "command_timeout_invalid",
+			Message: "Command timeout must be between 1 and 3600 seconds",
+		}
+		return
+	}
+
+	return
+}
+
+// Check if a user with the roles is permitted to run the command
+func (c *Command) HasRole(roles []string) bool {
+	for _, role := range roles {
+		for _, cmdRole := range c.Roles {
+			if role == cmdRole {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *Command) Commit(db *database.Database) (err error) {
+	coll := db.Commands()
+
+	err = coll.Commit(c.Id, c)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (c *Command) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.Commands()
+
+	err = coll.CommitFields(c.Id, c, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (c *Command) Insert(db *database.Database) (err error) {
+	coll := db.Commands()
+
+	_, err = coll.InsertOne(db, c)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
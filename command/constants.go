@@ -0,0 +1,18 @@
+package command
+
+const (
+	Pending   = "pending"
+	Running   = "running"
+	Completed = "completed"
+	Failed    = "failed"
+	Timeout   = "timeout"
+
+	Stdout = "stdout"
+	Stderr = "stderr"
+
+	// Output stored per endpoint for a run, further output is discarded
+	OutputLimit = 1048576
+
+	// Seconds a run waits for secondary authentication before expiring
+	PendingTimeout = 300
+)
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"sync"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/requires"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	registry     = map[bson.ObjectID]map[bson.ObjectID]func(bson.ObjectID){}
+	registryLock = sync.Mutex{}
+)
+
+// Register a callback for runs dispatched to an endpoint connected to
+// this node
+func Register(endpointId bson.ObjectID,
+	callback func(runId bson.ObjectID)) bson.ObjectID {
+
+	listernerId := bson.NewObjectID()
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	callbacks, ok := registry[endpointId]
+	if !ok {
+		callbacks = map[bson.ObjectID]func(bson.ObjectID){}
+	}
+	callbacks[listernerId] = callback
+	registry[endpointId] = callbacks
+
+	return listernerId
+}
+
+func Unregister(endpointId, listenerId bson.ObjectID) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	callbacks, ok := registry[endpointId]
+	if ok {
+		delete(callbacks, listenerId)
+		if len(callbacks) == 0 {
+			delete(registry, endpointId)
+		} else {
+			registry[endpointId] = callbacks
+		}
+	}
+}
+
+func callback(evt *event.EventPublish) {
+	dispatch := &dispatch{}
+
+	data, err := bson.Marshal(evt.Data)
+	if err == nil {
+		err = bson.Unmarshal(data, dispatch)
+	}
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("command: Failed to parse dispatch event")
+		return
+	}
+
+	if dispatch.Run.IsZero() {
+		return
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	callbacks, ok := registry[dispatch.Endpoint]
+	if ok {
+		for _, callback := range callbacks {
+			go callback(dispatch.Run)
+		}
+	}
+}
+
+func init() {
+	module := requires.New("command")
+	module.After("settings")
+	module.Before("event")
+
+	module.Handler = func() (err error) {
+		event.Register("endpoint_command", callback)
+		return
+	}
+}
@@ -0,0 +1,225 @@
+package command
+
+import (
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/event"
+)
+
+type Run struct {
+	Id          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Command     bson.ObjectID `bson:"command" json:"command"`
+	CommandName string        `bson:"command_name" json:"command_name"`
+	Exec        string        `bson:"exec" json:"exec"`
+	Args        []string      `bson:"args" json:"args"`
+	Timeout     int           `bson:"timeout" json:"timeout"`
+	User        bson.ObjectID `bson:"user" json:"user"`
+	Username    string        `bson:"username" json:"username"`
+	State       string        `bson:"state" json:"state"`
+	Timestamp   time.Time     `bson:"timestamp" json:"timestamp"`
+	Started     time.Time     `bson:"started" json:"started"`
+	Results     []*Result     `bson:"results" json:"results"`
+}
+
+type Result struct {
+	Endpoint     bson.ObjectID `bson:"endpoint" json:"endpoint"`
+	EndpointName string        `bson:"endpoint_name" json:"endpoint_name"`
+	State        string        `bson:"state" json:"state"`
+	ExitCode     int           `bson:"exit_code" json:"exit_code"`
+	Output       int           `bson:"output" json:"output"`
+	Finished     time.Time     `bson:"finished" json:"finished"`
+}
+
+type Output struct {
+	Id        bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Run       bson.ObjectID `bson:"r" json:"run"`
+	Endpoint  bson.ObjectID `bson:"e" json:"endpoint"`
+	Sequence  int           `bson:"s" json:"sequence"`
+	Stream    string        `bson:"o" json:"stream"`
+	Data      string        `bson:"d" json:"data"`
+	Timestamp time.Time     `bson:"t" json:"timestamp"`
+}
+
+type dispatch struct {
+	Run      bson.ObjectID `bson:"run"`
+	Endpoint bson.ObjectID `bson:"endpoint"`
+}
+
+func (r *Run) Request() *Request {
+	return &Request{
+		Run:     r.Id,
+		Exec:    r.Exec,
+		Args:    r.Args,
+		Timeout: r.Timeout,
+	}
+}
+
+func (r *Run) Deadline() time.Time {
+	if r.State == Pending {
+		return r.Timestamp.Add(PendingTimeout * time.Second)
+	}
+
+	return r.Started.Add(time.Duration(r.Timeout)*time.Second +
+		30*time.Second)
+}
+
+func (r *Run) HasEndpoint(endpointId bson.ObjectID) bool {
+	for _, result := range r.Results {
+		if result.Endpoint == endpointId {
+			return true
+		}
+	}
+	return false
+}
+
+// Start the run after secondary authentication and send the command to
+// the node holding each endpoint connection, only a pending run that has
+// not expired can be started
+func (r *Run) Start(db *database.Database) (err error) {
+	if r.State != Pending || !time.Now().Before(r.Deadline()) {
+		err = &errortypes.ParseError{
+			errors.New("command: Run is not pending"),
+		}
+		return
+	}
+
+	coll := db.CommandsRun()
+	now := time.Now()
+
+	resp, err := coll.UpdateOne(db, &bson.M{
+		"_id":   r.Id,
+		"state": Pending,
+	}, &bson.M{
+		"$set": &bson.M{
+			"state":                   Running,
+			"started":                 now,
+			"results.$[result].state": Running,
+		},
+	}, options.UpdateOne().SetArrayFilters([]interface{}{
+		&bson.M{
+			"result.state": Pending,
+		},
+	}))
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if resp.MatchedCount == 0 {
+		err = &errortypes.ParseError{
+			errors.New("command: Run is not pending"),
+		}
+		return
+	}
+
+	r.State = Running
+	r.Started = now
+	for _, result := range r.Results {
+		if result.State == Pending {
+			result.State = Running
+		}
+	}
+
+	for _, result := range r.Results {
+		err = event.Publish(db, "endpoint_command", &dispatch{
+			Run:      r.Id,
+			Endpoint: result.Endpoint,
+		})
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Mark endpoints that did not respond before the deadline as timed out,
+// only results still running are updated to preserve concurrent output.
+// Runs never authorized by secondary authentication are timed out.
+func (r *Run) Expire(db *database.Database) (err error) {
+	if (r.State != Running && r.State != Pending) ||
+		time.Now().Before(r.Deadline()) {
+
+		return
+	}
+
+	coll := db.CommandsRun()
+	now := time.Now()
+	prevState := r.State
+
+	state := Completed
+	if prevState == Pending {
+		state = Timeout
+	}
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id":   r.Id,
+		"state": prevState,
+	}, &bson.M{
+		"$set": &bson.M{
+			"state":                      state,
+			"results.$[result].state":    Timeout,
+			"results.$[result].finished": now,
+		},
+	}, options.UpdateOne().SetArrayFilters([]interface{}{
+		&bson.M{
+			"result.state": prevState,
+		},
+	}))
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	r.State = state
+	for _, result := range r.Results {
+		if result.State == prevState {
+			result.State = Timeout
+			result.Finished = now
+		}
+	}
+
+	return
+}
+
+func (r *Run) Commit(db *database.Database) (err error) {
+	coll := db.CommandsRun()
+
+	err = coll.Commit(r.Id, r)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Run) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.CommandsRun()
+
+	err = coll.CommitFields(r.Id, r, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Run) Insert(db *database.Database) (err error) {
+	coll := db.CommandsRun()
+
+	_, err = coll.InsertOne(db, r)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
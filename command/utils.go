@@ -0,0 +1,434 @@
+package command
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/event"
+)
+
+func Get(db *database.Database, commandId bson.ObjectID) (
+	cmd *Command, err error) {
+
+	coll := db.Commands()
+	cmd = &Command{}
+
+	err = coll.FindOneId(commandId, cmd)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetAllPaged(db *database.Database, query *bson.M,
+	page, pageCount int64) (commands []*Command, count int64, err error) {
+
+	coll := db.Commands()
+	commands = []*Command{}
+
+	if len(*query) == 0 {
+		count, err = coll.EstimatedDocumentCount(db)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	} else {
+		count, err = coll.CountDocuments(db, query)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	if pageCount == 0 {
+		pageCount = 20
+	}
+	maxPage := count / pageCount
+	if count == pageCount {
+		maxPage = 0
+	}
+	page = min(page, maxPage)
+	skip := min(page*pageCount, count)
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		options.Find().
+			SetSort(bson.D{{"name", 1}}).
+			SetSkip(skip).
+			SetLimit(pageCount),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		cmd := &Command{}
+		err = cursor.Decode(cmd)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		commands = append(commands, cmd)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func Remove(db *database.Database, commandId bson.ObjectID) (err error) {
+	coll := db.Commands()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"_id": commandId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func RemoveMulti(db *database.Database, commandIds []bson.ObjectID) (
+	err error) {
+
+	coll := db.Commands()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"_id": &bson.M{
+			"$in": commandIds,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetRun(db *database.Database, runId bson.ObjectID) (
+	run *Run, err error) {
+
+	coll := db.CommandsRun()
+	run = &Run{}
+
+	err = coll.FindOneId(runId, run)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetRunsPaged(db *database.Database, query *bson.M,
+	page, pageCount int64) (runs []*Run, count int64, err error) {
+
+	coll := db.CommandsRun()
+	runs = []*Run{}
+
+	count, err = coll.CountDocuments(db, query)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if pageCount == 0 {
+		pageCount = 20
+	}
+	maxPage := count / pageCount
+	if count == pageCount {
+		maxPage = 0
+	}
+	page = min(page, maxPage)
+	skip := min(page*pageCount, count)
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		options.Find().
+			SetSort(bson.D{{"timestamp", -1}}).
+			SetSkip(skip).
+			SetLimit(pageCount),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		run := &Run{}
+		err = cursor.Decode(run)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		runs = append(runs, run)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// Expire pending and running runs past the deadline, returns the number
+// expired
+func ExpireRuns(db *database.Database) (count int, err error) {
+	coll := db.CommandsRun()
+	runs := []*Run{}
+
+	cursor, err := coll.Find(db, &bson.M{
+		"state": &bson.M{
+			"$in": []string{
+				Pending,
+				Running,
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		run := &Run{}
+		err = cursor.Decode(run)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		runs = append(runs, run)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	for _, run := range runs {
+		if time.Now().Before(run.Deadline()) {
+			continue
+		}
+
+		err = run.Expire(db)
+		if err != nil {
+			return
+		}
+		count += 1
+	}
+
+	if count > 0 {
+		_ = event.PublishDispatch(db, "command_run.change")
+	}
+
+	return
+}
+
+// Output of a run for an endpoint after the sequence number, used to
+// incrementally stream output to the client
+func GetOutput(db *database.Database, runId, endpointId bson.ObjectID,
+	after int) (output []*Output, err error) {
+
+	coll := db.CommandsOutput()
+	output = []*Output{}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{
+			"r": runId,
+			"e": endpointId,
+			"s": &bson.M{
+				"$gt": after,
+			},
+		},
+		options.Find().
+			SetSort(bson.D{{"s", 1}}).
+			SetLimit(1000),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		out := &Output{}
+		err = cursor.Decode(out)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		output = append(output, out)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// Store output received from an endpoint, output is only accepted from
+// endpoints targeted by a running run
+func AppendOutput(db *database.Database, runId, endpointId bson.ObjectID,
+	sequence int, stream, data string, done bool, exitCode int) (
+	err error) {
+
+	coll := db.CommandsRun()
+
+	if stream != Stderr {
+		stream = Stdout
+	}
+
+	if data != "" {
+		query := &bson.M{
+			"_id":   runId,
+			"state": Running,
+			"results": &bson.M{
+				"$elemMatch": &bson.M{
+					"endpoint": endpointId,
+					"state":    Running,
+					"output": &bson.M{
+						"$lt": OutputLimit,
+					},
+				},
+			},
+		}
+
+		run := &Run{}
+		err = coll.FindOneAndUpdate(db, query, &bson.M{
+			"$inc": &bson.M{
+				"results.$.output": len(data),
+			},
+		}, options.FindOneAndUpdate().
+			SetReturnDocument(options.Before),
+		).Decode(run)
+		if err != nil {
+			err = database.ParseError(err)
+
+			switch err.(type) {
+			case *database.NotFoundError:
+				run = nil
+				err = nil
+				break
+			default:
+				return
+			}
+		}
+
+		if run != nil {
+			stored := 0
+			for _, result := range run.Results {
+				if result.Endpoint == endpointId {
+					stored = result.Output
+					break
+				}
+			}
+
+			// Truncate to the limit and clamp the stored size, output
+			// from an endpoint is received sequentially
+			if stored+len(data) > OutputLimit {
+				data = data[:OutputLimit-stored]
+
+				_, err = coll.UpdateOne(db, &bson.M{
+					"_id":              runId,
+					"results.endpoint": endpointId,
+				}, &bson.M{
+					"$min": &bson.M{
+						"results.$.output": OutputLimit,
+					},
+				})
+				if err != nil {
+					err = database.ParseError(err)
+					return
+				}
+			}
+
+			_, err = db.CommandsOutput().InsertOne(db, &Output{
+				Id:        bson.NewObjectID(),
+				Run:       runId,
+				Endpoint:  endpointId,
+				Sequence:  sequence,
+				Stream:    stream,
+				Data:      data,
+				Timestamp: time.Now(),
+			})
+			if err != nil {
+				err = database.ParseError(err)
+				return
+			}
+		}
+	}
+
+	if done {
+		state := Completed
+		if exitCode != 0 {
+			state = Failed
+		}
+
+		_, err = coll.UpdateOne(db, &bson.M{
+			"_id":   runId,
+			"state": Running,
+			"results": &bson.M{
+				"$elemMatch": &bson.M{
+					"endpoint": endpointId,
+					"state":    Running,
+				},
+			},
+		}, &bson.M{
+			"$set": &bson.M{
+				"results.$.state":     state,
+				"results.$.exit_code": exitCode,
+				"results.$.finished":  time.Now(),
+			},
+		})
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		_, err = coll.UpdateOne(db, &bson.M{
+			"_id":   runId,
+			"state": Running,
+			"results.state": &bson.M{
+				"$ne": Running,
+			},
+		}, &bson.M{
+			"$set": &bson.M{
+				"state": Completed,
+			},
+		})
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	_ = event.PublishDispatch(db, "command_run.change")
+
+	return
+}
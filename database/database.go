@@ -116,6 +116,26 @@ func (d *Database) Alerts() (coll *Collection) {
 	return
 }
 
+func (d *Database) Commands() (coll *Collection) {
+	coll = d.GetCollection("commands")
+	return
+}
+
+func (d *Database) CommandsRun() (coll *Collection) {
+	coll = d.GetCollection("commands_run")
+	return
+}
+
+func (d *Database) CommandsOutput() (coll *Collection) {
+	coll = d.GetCollection("commands_output")
+	return
+}
+
+func (d *Database) Views() (coll *Collection) {
+	coll = d.GetCollection("views")
+	return
+}
+
 func (d *Database) AlertsEvent() (coll *Collection) {
 	coll = d.GetCollection("alerts_event")
 	return
@@ -126,6 +146,16 @@ func (d *Database) AlertsEventLock() (coll *Collection) {
 	return
 }
 
+func (d *Database) AlertsIncident() (coll *Collection) {
+	coll = d.GetCollection("alerts_incident")
+	return
+}
+
+func (d *Database) Silences() (coll *Collection) {
+	coll = d.GetCollection("silences")
+	return
+}
+
 func (d *Database) Checks() (coll *Collection) {
 	coll = d.GetCollection("checks")
 	return
@@ -166,6 +196,66 @@ func (d *Database) EndpointsCheck() (coll *Collection) {
 	return
 }
 
+func (d *Database) EndpointsSystem5m() (coll *Collection) {
+	coll = d.GetCollection("endpoints_system_5m")
+	return
+}
+
+func (d *Database) EndpointsSystem1h() (coll *Collection) {
+	coll = d.GetCollection("endpoints_system_1h")
+	return
+}
+
+func (d *Database) EndpointsLoad5m() (coll *Collection) {
+	coll = d.GetCollection("endpoints_load_5m")
+	return
+}
+
+func (d *Database) EndpointsLoad1h() (coll *Collection) {
+	coll = d.GetCollection("endpoints_load_1h")
+	return
+}
+
+func (d *Database) EndpointsDisk5m() (coll *Collection) {
+	coll = d.GetCollection("endpoints_disk_5m")
+	return
+}
+
+func (d *Database) EndpointsDisk1h() (coll *Collection) {
+	coll = d.GetCollection("endpoints_disk_1h")
+	return
+}
+
+func (d *Database) EndpointsDiskIo5m() (coll *Collection) {
+	coll = d.GetCollection("endpoints_diskio_5m")
+	return
+}
+
+func (d *Database) EndpointsDiskIo1h() (coll *Collection) {
+	coll = d.GetCollection("endpoints_diskio_1h")
+	return
+}
+
+func (d *Database) EndpointsNetwork5m() (coll *Collection) {
+	coll = d.GetCollection("endpoints_network_5m")
+	return
+}
+
+func (d *Database) EndpointsNetwork1h() (coll *Collection) {
+	coll = d.GetCollection("endpoints_network_1h")
+	return
+}
+
+func (d *Database) EndpointsCheck5m() (coll *Collection) {
+	coll = d.GetCollection("endpoints_check_5m")
+	return
+}
+
+func (d *Database) EndpointsCheck1h() (coll *Collection) {
+	coll = d.GetCollection("endpoints_check_1h")
+	return
+}
+
 func (d *Database) EndpointsCheckLog() (coll *Collection) {
 	coll = d.GetCollection("endpoints_check_log")
 	return
@@ -176,6 +266,16 @@ func (d *Database) EndpointsKmsg() (coll *Collection) {
 	return
 }
 
+func (d *Database) EndpointsInventory() (coll *Collection) {
+	coll = d.GetCollection("endpoints_inventory")
+	return
+}
+
+func (d *Database) EndpointsInventoryChange() (coll *Collection) {
+	coll = d.GetCollection("endpoints_inventory_change")
+	return
+}
+
 func (d *Database) Sessions() (coll *Collection) {
 	coll = d.GetCollection("sessions")
 	return
@@ -211,6 +311,16 @@ func (d *Database) Nonces() (coll *Collection) {
 	return
 }
 
+func (d *Database) AuthBackoff() (coll *Collection) {
+	coll = d.GetCollection("auth_backoff")
+	return
+}
+
+func (d *Database) RateLimits() (coll *Collection) {
+	coll = d.GetCollection("rate_limits")
+	return
+}
+
 func (d *Database) Rokeys() (coll *Collection) {
 	coll = d.GetCollection("rokeys")
 	return
@@ -256,6 +366,26 @@ func (d *Database) SshCertificates() (coll *Collection) {
 	return
 }
 
+func (d *Database) HostIdentities() (coll *Collection) {
+	coll = d.GetCollection("host_identities")
+	return
+}
+
+func (d *Database) SshRecordings() (coll *Collection) {
+	coll = d.GetCollection("ssh_recordings")
+	return
+}
+
+func (d *Database) SshRecordingChunks() (coll *Collection) {
+	coll = d.GetCollection("ssh_recording_chunks")
+	return
+}
+
+func (d *Database) ClientCertificates() (coll *Collection) {
+	coll = d.GetCollection("client_certificates")
+	return
+}
+
 func (d *Database) AcmeChallenges() (coll *Collection) {
 	coll = d.GetCollection("acme_challenges")
 	return
@@ -485,6 +615,17 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.Audits(),
+		Keys: &bson.D{
+			{"y", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Policies(),
 		Keys: &bson.D{
@@ -563,6 +704,30 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.AuthBackoff(),
+		Keys: &bson.D{
+			{"t", 1},
+		},
+		Expire: 24 * time.Hour,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.RateLimits(),
+		Keys: &bson.D{
+			{"t", 1},
+		},
+		Expire: 1 * time.Hour,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Rokeys(),
 		Keys: &bson.D{
@@ -631,6 +796,86 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.Alerts(),
+		Keys: &bson.D{
+			{"views", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.Views(),
+		Keys: &bson.D{
+			{"name", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.Commands(),
+		Keys: &bson.D{
+			{"name", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.CommandsRun(),
+		Keys: &bson.D{
+			{"timestamp", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.CommandsRun(),
+		Keys: &bson.D{
+			{"state", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.CommandsOutput(),
+		Keys: &bson.D{
+			{"r", 1},
+			{"e", 1},
+			{"s", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.CommandsOutput(),
+		Keys: &bson.D{
+			{"t", 1},
+		},
+		Expire: 720 * time.Hour,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.AlertsEvent(),
 		Keys: &bson.D{
@@ -668,6 +913,71 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.AlertsIncident(),
+		Keys: &bson.D{
+			{"source", 1},
+			{"alert", 1},
+			{"key", 1},
+		},
+		Unique: true,
+		Partial: &bson.M{
+			"open": true,
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.AlertsIncident(),
+		Keys: &bson.D{
+			{"source", 1},
+			{"start", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.AlertsIncident(),
+		Keys: &bson.D{
+			{"alert", 1},
+			{"start", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.AlertsIncident(),
+		Keys: &bson.D{
+			{"code", 1},
+			{"open", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.Silences(),
+		Keys: &bson.D{
+			{"expires", 1},
+			{"start", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Checks(),
 		Keys: &bson.D{
@@ -690,6 +1000,17 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.Checks(),
+		Keys: &bson.D{
+			{"views", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Endpoints(),
 		Keys: &bson.D{
@@ -746,6 +1067,90 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.SshCertificates(),
+		Keys: &bson.D{
+			{"certificates_info.serial", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.SshRecordings(),
+		Keys: &bson.D{
+			{"user", 1},
+			{"start", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.SshRecordings(),
+		Keys: &bson.D{
+			{"start", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.SshRecordingChunks(),
+		Keys: &bson.D{
+			{"recording", 1},
+			{"index", 1},
+		},
+		Unique: true,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.ClientCertificates(),
+		Keys: &bson.D{
+			{"authority_id", 1},
+			{"serial", 1},
+		},
+		Unique: true,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.ClientCertificates(),
+		Keys: &bson.D{
+			{"user_id", 1},
+			{"timestamp", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.ClientCertificates(),
+		Keys: &bson.D{
+			{"expires", 1},
+		},
+		Expire: 24 * time.Hour,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.Devices(),
 		Keys: &bson.D{
@@ -982,6 +1387,150 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.EndpointsSystem5m(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsSystem1h(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsLoad5m(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsLoad1h(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsDisk5m(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsDisk1h(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsDiskIo5m(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsDiskIo1h(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsNetwork5m(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsNetwork1h(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"e", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsCheck5m(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"c", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsCheck1h(),
+		Keys: &bson.D{
+			{"t", 1},
+			{"c", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	index = &Index{
 		Collection: db.EndpointsCheckLog(),
 		Keys: &bson.D{
@@ -1031,6 +1580,56 @@ func addIndexes() (err error) {
 		return
 	}
 
+	index = &Index{
+		Collection: db.EndpointsInventory(),
+		Keys: &bson.D{
+			{"e", 1},
+			{"k", 1},
+			{"x", 1},
+		},
+		Unique: true,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsInventory(),
+		Keys: &bson.D{
+			{"k", 1},
+			{"n", 1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsInventoryChange(),
+		Keys: &bson.D{
+			{"e", 1},
+			{"t", -1},
+		},
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
+	index = &Index{
+		Collection: db.EndpointsInventoryChange(),
+		Keys: &bson.D{
+			{"t", 1},
+		},
+		Expire: 4320 * time.Hour,
+	}
+	err = index.Create()
+	if err != nil {
+		return
+	}
+
 	return
 }
 
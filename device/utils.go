@@ -150,6 +150,42 @@ func GetAllMode(db *database.Database, userId bson.ObjectID,
 	return
 }
 
+func GetAllNumber(db *database.Database, number string) (
+	devices []*Device, err error) {
+
+	coll := db.Devices()
+	devices = []*Device{}
+
+	cursor, err := coll.Find(db, &bson.M{
+		"number": number,
+		"mode":   Phone,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		devc := &Device{}
+		err = cursor.Decode(devc)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		devices = append(devices, devc)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func CountSecondary(db *database.Database, userId bson.ObjectID) (
 	count int64, err error) {
 
@@ -0,0 +1,326 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/secret"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/sirupsen/logrus"
+)
+
+var powerDnsClient = &http.Client{
+	Timeout: 20 * time.Second,
+}
+
+type PowerDns struct {
+	apiUrl      string
+	apiKey      string
+	server      string
+	cacheZoneId map[string]string
+}
+
+type powerDnsZone struct {
+	Id     string           `json:"id"`
+	Name   string           `json:"name"`
+	RRSets []*powerDnsRRSet `json:"rrsets,omitempty"`
+}
+
+type powerDnsRRSet struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Ttl        int               `json:"ttl,omitempty"`
+	ChangeType string            `json:"changetype,omitempty"`
+	Records    []*powerDnsRecord `json:"records"`
+}
+
+type powerDnsRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+type powerDnsPatch struct {
+	RRSets []*powerDnsRRSet `json:"rrsets"`
+}
+
+func (p *PowerDns) Connect(db *database.Database,
+	secr *secret.Secret) (err error) {
+
+	if secr.Type != secret.PowerDns {
+		err = &errortypes.ApiError{
+			errors.Wrap(err, "acme: Secret type not powerdns"),
+		}
+		return
+	}
+
+	p.apiUrl = strings.TrimRight(secr.Region, "/")
+	p.apiKey = secr.Key
+	p.server = secr.Value
+	if p.server == "" {
+		p.server = "localhost"
+	}
+	p.cacheZoneId = map[string]string{}
+
+	return
+}
+
+func (p *PowerDns) request(method, path string, query url.Values,
+	body interface{}, respData interface{}, codes []int) (err error) {
+
+	reqUrl, err := url.Parse(p.apiUrl + fmt.Sprintf(
+		"/api/v1/servers/%s%s", url.PathEscape(p.server), path))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to parse powerdns url"),
+		}
+		return
+	}
+
+	if query != nil {
+		reqUrl.RawQuery = query.Encode()
+	}
+
+	reqBody := &bytes.Buffer{}
+	if body != nil {
+		err = json.NewEncoder(reqBody).Encode(body)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "dns: Failed to encode powerdns request"),
+			}
+			return
+		}
+	}
+
+	req, err := http.NewRequest(
+		method,
+		reqUrl.String(),
+		reqBody,
+	)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dns: Failed to create powerdns request"),
+		}
+		return
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-API-Key", p.apiKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := powerDnsClient.Do(req)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dns: PowerDNS request failed"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	err = utils.CheckRequestN(resp, "dns: PowerDNS server error", codes)
+	if err != nil {
+		return
+	}
+
+	if respData != nil {
+		err = json.NewDecoder(resp.Body).Decode(respData)
+		if err != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(err, "dns: Failed to parse powerdns response"),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+func (p *PowerDns) DnsZoneFind(db *database.Database, domain string) (
+	zoneId string, err error) {
+
+	domainClean := cleanDomain(domain)
+
+	zoneId = p.cacheZoneId[domainClean]
+	if zoneId != "" {
+		return
+	}
+
+	zones := []*powerDnsZone{}
+	err = p.request("GET", "/zones", nil, nil, &zones, []int{200})
+	if err != nil {
+		return
+	}
+
+	zoneLen := 0
+	for _, zone := range zones {
+		zoneName := cleanDomain(zone.Name)
+		if len(zoneName) <= zoneLen {
+			continue
+		}
+
+		if matchDomains(zoneName, domainClean) ||
+			strings.HasSuffix(domainClean, "."+zoneName) {
+
+			zoneId = zone.Id
+			zoneLen = len(zoneName)
+		}
+	}
+
+	if zoneId == "" {
+		err = &errortypes.ApiError{
+			errors.Wrap(err, "acme: PowerDNS zone not found"),
+		}
+		return
+	}
+
+	p.cacheZoneId[domainClean] = zoneId
+
+	return
+}
+
+func (p *PowerDns) DnsCommit(db *database.Database,
+	domain, recordType string, ops []*Operation) (err error) {
+
+	domain = cleanDomain(domain)
+
+	zoneId, err := p.DnsZoneFind(db, domain)
+	if err != nil {
+		return
+	}
+
+	rrset := &powerDnsRRSet{
+		Name:    domain + ".",
+		Type:    recordType,
+		Ttl:     settings.Acme.DnsPowerDnsTtl,
+		Records: []*powerDnsRecord{},
+	}
+
+	for _, op := range ops {
+		if op.Operation != RETAIN && op.Operation != UPSERT {
+			continue
+		}
+
+		val := op.Value
+		switch recordType {
+		case "TXT":
+			val = "\"" + strings.Trim(val, "\"") + "\""
+			break
+		case "AAAA":
+			val = normalizeIp(val)
+			if val == "" {
+				err = &errortypes.ParseError{
+					errors.Newf("dns: Invalid ipv6 address %s", op.Value),
+				}
+				return
+			}
+			break
+		case "CNAME":
+			val = cleanDomain(val) + "."
+			break
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"operation": "upsert",
+			"zone_id":   zoneId,
+			"domain":    domain,
+			"value":     op.Value,
+		}).Info("domain: PowerDNS dns operation")
+
+		rrset.Records = append(rrset.Records, &powerDnsRecord{
+			Content: val,
+		})
+	}
+
+	if len(rrset.Records) == 0 {
+		logrus.WithFields(logrus.Fields{
+			"operation": "delete",
+			"zone_id":   zoneId,
+			"domain":    domain,
+		}).Info("domain: PowerDNS dns operation")
+
+		rrset.ChangeType = "DELETE"
+		rrset.Ttl = 0
+	} else {
+		rrset.ChangeType = "REPLACE"
+	}
+
+	err = p.request(
+		"PATCH",
+		"/zones/"+url.PathEscape(zoneId),
+		nil,
+		&powerDnsPatch{
+			RRSets: []*powerDnsRRSet{rrset},
+		},
+		nil,
+		[]int{204},
+	)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (p *PowerDns) DnsFind(db *database.Database,
+	domain, recordType string) (vals []string, err error) {
+
+	vals = []string{}
+	domain = cleanDomain(domain)
+
+	zoneId, err := p.DnsZoneFind(db, domain)
+	if err != nil {
+		return
+	}
+
+	query := url.Values{}
+	query.Set("rrset_name", domain+".")
+	query.Set("rrset_type", recordType)
+
+	zone := &powerDnsZone{}
+	err = p.request(
+		"GET",
+		"/zones/"+url.PathEscape(zoneId),
+		query,
+		nil,
+		zone,
+		[]int{200},
+	)
+	if err != nil {
+		return
+	}
+
+	for _, rrset := range zone.RRSets {
+		if rrset.Type != recordType || !matchDomains(rrset.Name, domain) {
+			continue
+		}
+
+		for _, record := range rrset.Records {
+			if record.Disabled {
+				continue
+			}
+
+			val := record.Content
+			if recordType == "AAAA" {
+				val = normalizeIp(val)
+			}
+
+			if val == "" {
+				continue
+			}
+
+			vals = append(vals, val)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,783 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"hash"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/secret"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	rfc2136Timeout = 10 * time.Second
+	tsigFudge      = 300
+	typeTsig       = dnsmessage.Type(250)
+	opCodeUpdate   = dnsmessage.OpCode(5)
+	classNone      = dnsmessage.Class(254)
+)
+
+type Rfc2136 struct {
+	server    string
+	keyName   string
+	keySecret []byte
+	algorithm string
+	ttl       uint32
+	cacheZone map[string]string
+}
+
+func (r *Rfc2136) Connect(db *database.Database,
+	secr *secret.Secret) (err error) {
+
+	if secr.Type != secret.Rfc2136 {
+		err = &errortypes.ApiError{
+			errors.Wrap(err, "acme: Secret type not rfc2136"),
+		}
+		return
+	}
+
+	r.server = secr.Region
+	if _, _, e := net.SplitHostPort(r.server); e != nil {
+		r.server = net.JoinHostPort(r.server, "53")
+	}
+
+	r.keyName = strings.ToLower(fqdn(secr.Key))
+	r.algorithm = secr.Algorithm
+	if r.algorithm == "" {
+		r.algorithm = secret.HmacSha256
+	}
+
+	r.keySecret, err = base64.StdEncoding.DecodeString(secr.Value)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to decode rfc2136 tsig secret"),
+		}
+		return
+	}
+
+	r.ttl = uint32(settings.Acme.DnsRfc2136Ttl)
+	r.cacheZone = map[string]string{}
+
+	return
+}
+
+func (r *Rfc2136) newHash() func() hash.Hash {
+	switch r.algorithm {
+	case secret.HmacSha1:
+		return sha1.New
+	case secret.HmacSha512:
+		return sha512.New
+	default:
+		return sha256.New
+	}
+}
+
+func (r *Rfc2136) exchange(msg []byte) (resp []byte, err error) {
+	conn, err := net.DialTimeout("tcp", r.server, rfc2136Timeout)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dns: Failed to connect to rfc2136 server"),
+		}
+		return
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(rfc2136Timeout))
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dns: Failed to set rfc2136 deadline"),
+		}
+		return
+	}
+
+	data := make([]byte, 2, len(msg)+2)
+	binary.BigEndian.PutUint16(data, uint16(len(msg)))
+	data = append(data, msg...)
+
+	_, err = conn.Write(data)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dns: Failed to write rfc2136 request"),
+		}
+		return
+	}
+
+	respLen := make([]byte, 2)
+	_, err = io.ReadFull(conn, respLen)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dns: Failed to read rfc2136 response"),
+		}
+		return
+	}
+
+	resp = make([]byte, binary.BigEndian.Uint16(respLen))
+	_, err = io.ReadFull(conn, resp)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "dns: Failed to read rfc2136 response"),
+		}
+		return
+	}
+
+	return
+}
+
+// Append a TSIG record signed with the key, see RFC 8945
+func (r *Rfc2136) sign(msg []byte) (signed []byte, sum []byte, err error) {
+	keyName := packName(r.keyName)
+	algorithm := packName(r.algorithm)
+	timeSigned := make([]byte, 6)
+	now := uint64(time.Now().Unix())
+	timeSigned[0] = byte(now >> 40)
+	timeSigned[1] = byte(now >> 32)
+	binary.BigEndian.PutUint32(timeSigned[2:], uint32(now))
+
+	mac := hmac.New(r.newHash(), r.keySecret)
+	mac.Write(msg)
+	mac.Write(keyName)
+	mac.Write([]byte{0, byte(dnsmessage.ClassANY), 0, 0, 0, 0})
+	mac.Write(algorithm)
+	mac.Write(timeSigned)
+	mac.Write([]byte{
+		byte(tsigFudge >> 8), byte(tsigFudge & 0xff), 0, 0, 0, 0})
+	sum = mac.Sum(nil)
+
+	rdata := []byte{}
+	rdata = append(rdata, algorithm...)
+	rdata = append(rdata, timeSigned...)
+	rdata = binary.BigEndian.AppendUint16(rdata, tsigFudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = append(rdata, msg[0], msg[1])
+	rdata = append(rdata, 0, 0, 0, 0)
+
+	signed = append([]byte{}, msg...)
+	signed = append(signed, keyName...)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(typeTsig))
+	signed = binary.BigEndian.AppendUint16(signed,
+		uint16(dnsmessage.ClassANY))
+	signed = binary.BigEndian.AppendUint32(signed, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata)))
+	signed = append(signed, rdata...)
+
+	binary.BigEndian.PutUint16(signed[10:],
+		binary.BigEndian.Uint16(signed[10:])+1)
+
+	return
+}
+
+// Verify the TSIG record of a response to a request signed with the
+// request mac, the response must be signed with the same key
+func (r *Rfc2136) verify(resp []byte, reqMac []byte) (err error) {
+	offset, err := lastRecord(resp)
+	if err != nil {
+		return
+	}
+
+	keyName, pos, err := readName(resp, offset)
+	if err != nil {
+		return
+	}
+
+	if pos+10 > len(resp) || dnsmessage.Type(
+		binary.BigEndian.Uint16(resp[pos:])) != typeTsig {
+
+		err = &errortypes.AuthenticationError{
+			errors.New("dns: Rfc2136 response not signed"),
+		}
+		return
+	}
+	classTtl := resp[pos+2 : pos+8]
+	rdataLen := int(binary.BigEndian.Uint16(resp[pos+8:]))
+	pos += 10
+
+	if pos+rdataLen != len(resp) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid rfc2136 response tsig"),
+		}
+		return
+	}
+
+	algorithm, pos, err := readName(resp, pos)
+	if err != nil {
+		return
+	}
+
+	if pos+10 > len(resp) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid rfc2136 response tsig"),
+		}
+		return
+	}
+	timeSigned := resp[pos : pos+6]
+	fudge := resp[pos+6 : pos+8]
+	macLen := int(binary.BigEndian.Uint16(resp[pos+8:]))
+	pos += 10
+
+	if pos+macLen+6 > len(resp) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid rfc2136 response tsig"),
+		}
+		return
+	}
+	respMac := resp[pos : pos+macLen]
+	origId := resp[pos+macLen : pos+macLen+2]
+	tsigErr := binary.BigEndian.Uint16(resp[pos+macLen+2:])
+	otherLen := int(binary.BigEndian.Uint16(resp[pos+macLen+4:]))
+	other := resp[pos+macLen+4:]
+
+	if len(other) != otherLen+2 {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid rfc2136 response tsig"),
+		}
+		return
+	}
+
+	if !bytes.Equal(bytes.ToLower(keyName), packName(r.keyName)) ||
+		!bytes.Equal(bytes.ToLower(algorithm), packName(r.algorithm)) {
+
+		err = &errortypes.AuthenticationError{
+			errors.New("dns: Rfc2136 response signed with unknown key"),
+		}
+		return
+	}
+
+	if tsigErr != 0 {
+		err = &errortypes.AuthenticationError{
+			errors.Newf("dns: Rfc2136 response tsig error %d", tsigErr),
+		}
+		return
+	}
+
+	signed := int64(binary.BigEndian.Uint16(timeSigned))<<32 |
+		int64(binary.BigEndian.Uint32(timeSigned[2:]))
+	delta := time.Now().Unix() - signed
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta > int64(binary.BigEndian.Uint16(fudge)) {
+		err = &errortypes.AuthenticationError{
+			errors.New("dns: Rfc2136 response tsig time invalid"),
+		}
+		return
+	}
+
+	msg := append([]byte{}, resp[:offset]...)
+	copy(msg, origId)
+	binary.BigEndian.PutUint16(msg[10:],
+		binary.BigEndian.Uint16(msg[10:])-1)
+
+	mac := hmac.New(r.newHash(), r.keySecret)
+	mac.Write(binary.BigEndian.AppendUint16(nil, uint16(len(reqMac))))
+	mac.Write(reqMac)
+	mac.Write(msg)
+	mac.Write(bytes.ToLower(keyName))
+	mac.Write(classTtl)
+	mac.Write(bytes.ToLower(algorithm))
+	mac.Write(timeSigned)
+	mac.Write(fudge)
+	mac.Write(resp[pos+macLen+2:])
+
+	if !hmac.Equal(mac.Sum(nil), respMac) {
+		err = &errortypes.AuthenticationError{
+			errors.New("dns: Rfc2136 response tsig invalid"),
+		}
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) query(domain string, typ dnsmessage.Type) (
+	answers []dnsmessage.Resource, authorities []dnsmessage.Resource,
+	err error) {
+
+	name, err := dnsmessage.NewName(fqdn(domain))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Invalid domain name"),
+		}
+		return
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID: newId(),
+	})
+	err = builder.StartQuestions()
+	if err == nil {
+		err = builder.Question(dnsmessage.Question{
+			Name:  name,
+			Type:  typ,
+			Class: dnsmessage.ClassINET,
+		})
+	}
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build rfc2136 query"),
+		}
+		return
+	}
+
+	msg, err := builder.Finish()
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build rfc2136 query"),
+		}
+		return
+	}
+
+	resp, err := r.exchange(msg)
+	if err != nil {
+		return
+	}
+
+	parser := dnsmessage.Parser{}
+	header, err := parser.Start(resp)
+	if err == nil {
+		err = parser.SkipAllQuestions()
+	}
+	if err == nil {
+		answers, err = parser.AllAnswers()
+	}
+	if err == nil {
+		authorities, err = parser.AllAuthorities()
+	}
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to parse rfc2136 response"),
+		}
+		return
+	}
+
+	if header.RCode != dnsmessage.RCodeSuccess &&
+		header.RCode != dnsmessage.RCodeNameError {
+
+		err = &errortypes.RequestError{
+			errors.Newf("dns: Rfc2136 query failed with %s",
+				header.RCode.String()),
+		}
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) DnsZoneFind(db *database.Database, domain string) (
+	zone string, err error) {
+
+	zone = r.cacheZone[domain]
+	if zone != "" {
+		return
+	}
+
+	answers, authorities, err := r.query(domain, dnsmessage.TypeSOA)
+	if err != nil {
+		return
+	}
+
+	for _, resource := range append(answers, authorities...) {
+		if resource.Header.Type == dnsmessage.TypeSOA {
+			zone = resource.Header.Name.String()
+			break
+		}
+	}
+
+	if zone == "" {
+		err = &errortypes.NotFoundError{
+			errors.Newf("dns: Rfc2136 zone not found for %s", domain),
+		}
+		return
+	}
+
+	r.cacheZone[domain] = zone
+
+	return
+}
+
+func (r *Rfc2136) DnsCommit(db *database.Database,
+	domain, recordType string, ops []*Operation) (err error) {
+
+	domain = cleanDomain(domain)
+
+	zone, err := r.DnsZoneFind(db, domain)
+	if err != nil {
+		return
+	}
+
+	zoneName, err := dnsmessage.NewName(zone)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Invalid zone name"),
+		}
+		return
+	}
+
+	name, err := dnsmessage.NewName(fqdn(domain))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Invalid domain name"),
+		}
+		return
+	}
+
+	typ, err := parseType(recordType)
+	if err != nil {
+		return
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:     newId(),
+		OpCode: opCodeUpdate,
+	})
+
+	err = builder.StartQuestions()
+	if err == nil {
+		err = builder.Question(dnsmessage.Question{
+			Name:  zoneName,
+			Type:  dnsmessage.TypeSOA,
+			Class: dnsmessage.ClassINET,
+		})
+	}
+	if err == nil {
+		err = builder.StartAuthorities()
+	}
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build rfc2136 update"),
+		}
+		return
+	}
+
+	changes := 0
+	for _, op := range ops {
+		hdr := dnsmessage.ResourceHeader{
+			Name: name,
+		}
+
+		switch op.Operation {
+		case UPSERT:
+			hdr.Class = dnsmessage.ClassINET
+			hdr.TTL = r.ttl
+			break
+		case DELETE:
+			hdr.Class = classNone
+			break
+		default:
+			continue
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"operation": op.Operation,
+			"domain":    domain,
+			"value":     op.Value,
+		}).Info("domain: Rfc2136 dns operation")
+
+		err = addResource(&builder, hdr, typ, op.Value)
+		if err != nil {
+			return
+		}
+		changes += 1
+	}
+
+	if changes == 0 {
+		return
+	}
+
+	msg, err := builder.Finish()
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build rfc2136 update"),
+		}
+		return
+	}
+
+	msg, reqMac, err := r.sign(msg)
+	if err != nil {
+		return
+	}
+
+	resp, err := r.exchange(msg)
+	if err != nil {
+		return
+	}
+
+	parser := dnsmessage.Parser{}
+	header, err := parser.Start(resp)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to parse rfc2136 response"),
+		}
+		return
+	}
+
+	if header.RCode != dnsmessage.RCodeSuccess {
+		err = &errortypes.RequestError{
+			errors.Newf("dns: Rfc2136 update failed with %s",
+				header.RCode.String()),
+		}
+		return
+	}
+
+	err = r.verify(resp, reqMac)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Rfc2136) DnsFind(db *database.Database,
+	domain, recordType string) (vals []string, err error) {
+
+	vals = []string{}
+	domain = cleanDomain(domain)
+
+	typ, err := parseType(recordType)
+	if err != nil {
+		return
+	}
+
+	answers, _, err := r.query(domain, typ)
+	if err != nil {
+		return
+	}
+
+	for _, resource := range answers {
+		if resource.Header.Type != typ ||
+			!matchDomains(resource.Header.Name.String(), domain) {
+
+			continue
+		}
+
+		switch body := resource.Body.(type) {
+		case *dnsmessage.TXTResource:
+			vals = append(vals, "\""+strings.Join(body.TXT, "")+"\"")
+			break
+		case *dnsmessage.AResource:
+			vals = append(vals, net.IP(body.A[:]).String())
+			break
+		case *dnsmessage.AAAAResource:
+			vals = append(vals, normalizeIp(net.IP(body.AAAA[:]).String()))
+			break
+		case *dnsmessage.CNAMEResource:
+			vals = append(vals, cleanDomain(body.CNAME.String()))
+			break
+		}
+	}
+
+	return
+}
+
+// Add a record to the update, records with class none delete only the
+// record matching the value, see RFC 2136 section 2.5.4
+func addResource(builder *dnsmessage.Builder, hdr dnsmessage.ResourceHeader,
+	typ dnsmessage.Type, value string) (err error) {
+
+	switch typ {
+	case dnsmessage.TypeTXT:
+		err = builder.TXTResource(hdr, dnsmessage.TXTResource{
+			TXT: []string{strings.Trim(value, "\"")},
+		})
+		break
+	case dnsmessage.TypeA:
+		ip := net.ParseIP(value).To4()
+		if ip == nil {
+			err = &errortypes.ParseError{
+				errors.Newf("dns: Invalid ipv4 address %s", value),
+			}
+			return
+		}
+
+		res := dnsmessage.AResource{}
+		copy(res.A[:], ip)
+		err = builder.AResource(hdr, res)
+		break
+	case dnsmessage.TypeAAAA:
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() != nil {
+			err = &errortypes.ParseError{
+				errors.Newf("dns: Invalid ipv6 address %s", value),
+			}
+			return
+		}
+
+		res := dnsmessage.AAAAResource{}
+		copy(res.AAAA[:], ip.To16())
+		err = builder.AAAAResource(hdr, res)
+		break
+	case dnsmessage.TypeCNAME:
+		target, e := dnsmessage.NewName(fqdn(value))
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "dns: Invalid cname target"),
+			}
+			return
+		}
+
+		err = builder.CNAMEResource(hdr, dnsmessage.CNAMEResource{
+			CNAME: target,
+		})
+		break
+	}
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "dns: Failed to build rfc2136 update"),
+		}
+		return
+	}
+
+	return
+}
+
+func parseType(recordType string) (typ dnsmessage.Type, err error) {
+	switch recordType {
+	case "TXT":
+		typ = dnsmessage.TypeTXT
+		break
+	case "A":
+		typ = dnsmessage.TypeA
+		break
+	case "AAAA":
+		typ = dnsmessage.TypeAAAA
+		break
+	case "CNAME":
+		typ = dnsmessage.TypeCNAME
+		break
+	default:
+		err = &errortypes.ParseError{
+			errors.Newf("dns: Unsupported record type %s", recordType),
+		}
+	}
+
+	return
+}
+
+func packName(name string) (data []byte) {
+	for _, label := range strings.Split(strings.Trim(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		data = append(data, byte(len(label)))
+		data = append(data, strings.ToLower(label)...)
+	}
+	data = append(data, 0)
+	return
+}
+
+// Read an uncompressed name returning the wire bytes and the offset
+// following the name
+func readName(msg []byte, offset int) (name []byte, pos int, err error) {
+	pos = offset
+	for {
+		if pos >= len(msg) || msg[pos]&0xc0 != 0 {
+			err = &errortypes.ParseError{
+				errors.New("dns: Invalid rfc2136 response name"),
+			}
+			return
+		}
+
+		labelLen := int(msg[pos])
+		pos += labelLen + 1
+		if labelLen == 0 {
+			break
+		}
+	}
+
+	if pos > len(msg) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid rfc2136 response name"),
+		}
+		return
+	}
+	name = msg[offset:pos]
+
+	return
+}
+
+// Get the offset of the last record in a message
+func lastRecord(msg []byte) (offset int, err error) {
+	if len(msg) < 12 {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid rfc2136 response"),
+		}
+		return
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	records := int(binary.BigEndian.Uint16(msg[6:])) +
+		int(binary.BigEndian.Uint16(msg[8:])) +
+		int(binary.BigEndian.Uint16(msg[10:]))
+
+	if records == 0 {
+		err = &errortypes.AuthenticationError{
+			errors.New("dns: Rfc2136 response not signed"),
+		}
+		return
+	}
+
+	pos := 12
+	for i := 0; i < questions+records; i++ {
+		offset = pos
+
+		for pos < len(msg) {
+			if msg[pos]&0xc0 == 0xc0 {
+				pos += 2
+				break
+			}
+
+			labelLen := int(msg[pos])
+			pos += labelLen + 1
+			if labelLen == 0 {
+				break
+			}
+		}
+
+		if i < questions {
+			pos += 4
+		} else {
+			if pos+10 > len(msg) {
+				break
+			}
+			pos += 10 + int(binary.BigEndian.Uint16(msg[pos+8:]))
+		}
+
+		if pos > len(msg) {
+			break
+		}
+	}
+
+	if pos != len(msg) {
+		err = &errortypes.ParseError{
+			errors.New("dns: Invalid rfc2136 response"),
+		}
+		return
+	}
+
+	return
+}
+
+func fqdn(name string) string {
+	return strings.Trim(name, ".") + "."
+}
+
+func newId() uint16 {
+	b := make([]byte, 2)
+	_, _ = rand.Read(b)
+	return binary.BigEndian.Uint16(b)
+}
@@ -0,0 +1,468 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	rfc2136TestZone    = "example.com."
+	rfc2136TestKeyName = "zero-key."
+	rfc2136TestDomain  = "_acme-challenge.www.example.com"
+)
+
+var (
+	rfc2136TestKey  = []byte("0123456789abcdef0123456789abcdef")
+	rfc2136TestAlgo = "hmac-sha256."
+)
+
+type rfc2136TestServer struct {
+	t            *testing.T
+	listener     net.Listener
+	lock         sync.Mutex
+	records      map[string][]string
+	signKey      []byte
+	unsigned     bool
+	rrsetDeletes int
+}
+
+func newRfc2136TestServer(t *testing.T) (srv *rfc2136TestServer) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv = &rfc2136TestServer{
+		t:        t,
+		listener: listener,
+		records:  map[string][]string{},
+		signKey:  rfc2136TestKey,
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go srv.serve()
+
+	return
+}
+
+func (s *rfc2136TestServer) client() *Rfc2136 {
+	return &Rfc2136{
+		server:    s.listener.Addr().String(),
+		keyName:   rfc2136TestKeyName,
+		keySecret: rfc2136TestKey,
+		algorithm: "hmac-sha256",
+		ttl:       30,
+		cacheZone: map[string]string{},
+	}
+}
+
+func (s *rfc2136TestServer) get(name string,
+	typ dnsmessage.Type) []string {
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	vals := append([]string{}, s.records[name+typ.String()]...)
+	sort.Strings(vals)
+	return vals
+}
+
+func (s *rfc2136TestServer) set(name string, typ dnsmessage.Type,
+	vals ...string) {
+
+	s.lock.Lock()
+	s.records[name+typ.String()] = vals
+	s.lock.Unlock()
+}
+
+func (s *rfc2136TestServer) sign(key []byte, unsigned bool) {
+	s.lock.Lock()
+	s.signKey = key
+	s.unsigned = unsigned
+	s.lock.Unlock()
+}
+
+func (s *rfc2136TestServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handleConn(conn)
+	}
+}
+
+func (s *rfc2136TestServer) handleConn(conn net.Conn) {
+	defer conn.Close()
+
+	msgLen := make([]byte, 2)
+	_, err := io.ReadFull(conn, msgLen)
+	if err != nil {
+		return
+	}
+
+	msg := make([]byte, binary.BigEndian.Uint16(msgLen))
+	_, err = io.ReadFull(conn, msg)
+	if err != nil {
+		return
+	}
+
+	resp := s.handle(msg)
+
+	data := binary.BigEndian.AppendUint16(nil, uint16(len(resp)))
+	_, _ = conn.Write(append(data, resp...))
+}
+
+func (s *rfc2136TestServer) handle(msg []byte) []byte {
+	parser := dnsmessage.Parser{}
+	header, err := parser.Start(msg)
+	if err != nil {
+		s.t.Error(err)
+		return nil
+	}
+
+	question, err := parser.Question()
+	if err != nil {
+		s.t.Error(err)
+		return nil
+	}
+
+	if header.OpCode == opCodeUpdate {
+		return s.handleUpdate(msg, header, question, &parser)
+	}
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:       header.ID,
+		Response: true,
+	})
+	_ = builder.StartQuestions()
+	_ = builder.Question(question)
+
+	name := strings.ToLower(question.Name.String())
+	if question.Type == dnsmessage.TypeSOA {
+		_ = builder.StartAuthorities()
+		_ = builder.SOAResource(dnsmessage.ResourceHeader{
+			Name:  dnsmessage.MustNewName(rfc2136TestZone),
+			Class: dnsmessage.ClassINET,
+		}, dnsmessage.SOAResource{
+			NS:     dnsmessage.MustNewName("ns." + rfc2136TestZone),
+			MBox:   dnsmessage.MustNewName("admin." + rfc2136TestZone),
+			Serial: 1,
+		})
+	} else {
+		_ = builder.StartAnswers()
+		for _, val := range s.get(name, question.Type) {
+			hdr := dnsmessage.ResourceHeader{
+				Name:  question.Name,
+				Class: dnsmessage.ClassINET,
+				TTL:   30,
+			}
+
+			switch question.Type {
+			case dnsmessage.TypeTXT:
+				_ = builder.TXTResource(hdr, dnsmessage.TXTResource{
+					TXT: []string{val},
+				})
+				break
+			case dnsmessage.TypeAAAA:
+				res := dnsmessage.AAAAResource{}
+				copy(res.AAAA[:], net.ParseIP(val).To16())
+				_ = builder.AAAAResource(hdr, res)
+				break
+			}
+		}
+	}
+
+	resp, err := builder.Finish()
+	if err != nil {
+		s.t.Error(err)
+	}
+	return resp
+}
+
+func (s *rfc2136TestServer) handleUpdate(msg []byte,
+	header dnsmessage.Header, question dnsmessage.Question,
+	parser *dnsmessage.Parser) []byte {
+
+	reqMac, ok := rfc2136TestVerify(msg, rfc2136TestKey)
+	if !ok {
+		return rfc2136TestResponse(header, question,
+			dnsmessage.RCode(9))
+	}
+
+	_ = parser.SkipAllQuestions()
+	_ = parser.SkipAllAnswers()
+	updates, err := parser.AllAuthorities()
+	if err != nil {
+		s.t.Error(err)
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, update := range updates {
+		key := strings.ToLower(update.Header.Name.String()) +
+			update.Header.Type.String()
+
+		val := ""
+		switch body := update.Body.(type) {
+		case *dnsmessage.TXTResource:
+			val = strings.Join(body.TXT, "")
+			break
+		case *dnsmessage.AAAAResource:
+			val = net.IP(body.AAAA[:]).String()
+			break
+		}
+
+		switch update.Header.Class {
+		case dnsmessage.ClassANY:
+			s.rrsetDeletes += 1
+			delete(s.records, key)
+			break
+		case classNone:
+			vals := []string{}
+			for _, v := range s.records[key] {
+				if v != val {
+					vals = append(vals, v)
+				}
+			}
+			s.records[key] = vals
+			break
+		case dnsmessage.ClassINET:
+			found := false
+			for _, v := range s.records[key] {
+				if v == val {
+					found = true
+				}
+			}
+			if !found {
+				s.records[key] = append(s.records[key], val)
+			}
+			break
+		}
+	}
+
+	resp := rfc2136TestResponse(header, question, dnsmessage.RCodeSuccess)
+	if s.unsigned {
+		return resp
+	}
+
+	return rfc2136TestSign(resp, s.signKey, reqMac)
+}
+
+func rfc2136TestResponse(header dnsmessage.Header,
+	question dnsmessage.Question, rcode dnsmessage.RCode) []byte {
+
+	builder := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:       header.ID,
+		Response: true,
+		OpCode:   opCodeUpdate,
+		RCode:    rcode,
+	})
+	_ = builder.StartQuestions()
+	_ = builder.Question(question)
+
+	resp, _ := builder.Finish()
+	return resp
+}
+
+func rfc2136TestVariables(timeSigned []byte) []byte {
+	vars := packName(rfc2136TestKeyName)
+	vars = append(vars, 0, byte(dnsmessage.ClassANY), 0, 0, 0, 0)
+	vars = append(vars, packName(rfc2136TestAlgo)...)
+	vars = append(vars, timeSigned...)
+	vars = binary.BigEndian.AppendUint16(vars, tsigFudge)
+	vars = append(vars, 0, 0, 0, 0)
+	return vars
+}
+
+// Check the request tsig independently of the client, the tsig record
+// is expected to use an uncompressed owner name
+func rfc2136TestVerify(msg []byte, key []byte) (reqMac []byte, ok bool) {
+	owner := append(packName(rfc2136TestKeyName), 0, byte(typeTsig))
+	offset := bytes.LastIndex(msg, owner)
+	if offset < 0 {
+		return
+	}
+
+	algo := packName(rfc2136TestAlgo)
+	pos := offset + len(owner) - 2 + 10
+	if !bytes.HasPrefix(msg[pos:], algo) {
+		return
+	}
+	pos += len(algo)
+
+	timeSigned := msg[pos : pos+6]
+	macLen := int(binary.BigEndian.Uint16(msg[pos+8:]))
+	reqMac = msg[pos+10 : pos+10+macLen]
+
+	unsigned := append([]byte{}, msg[:offset]...)
+	binary.BigEndian.PutUint16(unsigned[10:],
+		binary.BigEndian.Uint16(unsigned[10:])-1)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(unsigned)
+	mac.Write(rfc2136TestVariables(timeSigned))
+
+	ok = hmac.Equal(mac.Sum(nil), reqMac)
+	return
+}
+
+func rfc2136TestSign(msg []byte, key []byte, reqMac []byte) []byte {
+	timeSigned := make([]byte, 6)
+	now := uint64(time.Now().Unix())
+	timeSigned[0] = byte(now >> 40)
+	timeSigned[1] = byte(now >> 32)
+	binary.BigEndian.PutUint32(timeSigned[2:], uint32(now))
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(binary.BigEndian.AppendUint16(nil, uint16(len(reqMac))))
+	mac.Write(reqMac)
+	mac.Write(msg)
+	mac.Write(rfc2136TestVariables(timeSigned))
+	sum := mac.Sum(nil)
+
+	rdata := packName(rfc2136TestAlgo)
+	rdata = append(rdata, timeSigned...)
+	rdata = binary.BigEndian.AppendUint16(rdata, tsigFudge)
+	rdata = binary.BigEndian.AppendUint16(rdata, uint16(len(sum)))
+	rdata = append(rdata, sum...)
+	rdata = append(rdata, msg[0], msg[1], 0, 0, 0, 0)
+
+	signed := append([]byte{}, msg...)
+	signed = append(signed, packName(rfc2136TestKeyName)...)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(typeTsig))
+	signed = binary.BigEndian.AppendUint16(signed,
+		uint16(dnsmessage.ClassANY))
+	signed = binary.BigEndian.AppendUint32(signed, 0)
+	signed = binary.BigEndian.AppendUint16(signed, uint16(len(rdata)))
+	signed = append(signed, rdata...)
+
+	binary.BigEndian.PutUint16(signed[10:],
+		binary.BigEndian.Uint16(signed[10:])+1)
+
+	return signed
+}
+
+func TestRfc2136Commit(t *testing.T) {
+	srv := newRfc2136TestServer(t)
+	client := srv.client()
+	name := rfc2136TestDomain + "."
+
+	srv.set(name, dnsmessage.TypeTXT, "keep", "old")
+
+	err := client.DnsCommit(nil, rfc2136TestDomain, "TXT", []*Operation{
+		{Operation: RETAIN, Value: "\"keep\""},
+		{Operation: DELETE, Value: "\"old\""},
+		{Operation: UPSERT, Value: "\"new\""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.lock.Lock()
+	rrsetDeletes := srv.rrsetDeletes
+	srv.lock.Unlock()
+
+	if rrsetDeletes != 0 {
+		t.Fatal("update deleted the whole rrset")
+	}
+
+	vals := srv.get(name, dnsmessage.TypeTXT)
+	if strings.Join(vals, ",") != "keep,new" {
+		t.Fatalf("unexpected records %v", vals)
+	}
+
+	found, err := client.DnsFind(nil, rfc2136TestDomain, "TXT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(found)
+	if strings.Join(found, ",") != "\"keep\",\"new\"" {
+		t.Fatalf("unexpected find %v", found)
+	}
+
+	if client.cacheZone[rfc2136TestDomain] != rfc2136TestZone {
+		t.Fatalf("unexpected zone %s", client.cacheZone[rfc2136TestDomain])
+	}
+}
+
+func TestRfc2136Aaaa(t *testing.T) {
+	srv := newRfc2136TestServer(t)
+	client := srv.client()
+	name := rfc2136TestDomain + "."
+
+	for _, val := range []string{
+		"192.0.2.1",
+		"::ffff:192.0.2.1",
+		"invalid",
+	} {
+		err := client.DnsCommit(nil, rfc2136TestDomain, "AAAA",
+			[]*Operation{
+				{Operation: UPSERT, Value: val},
+			})
+		if err == nil {
+			t.Fatalf("aaaa record accepted for %s", val)
+		}
+	}
+
+	err := client.DnsCommit(nil, rfc2136TestDomain, "AAAA", []*Operation{
+		{Operation: UPSERT, Value: "2001:db8::1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals := srv.get(name, dnsmessage.TypeAAAA)
+	if strings.Join(vals, ",") != "2001:db8::1" {
+		t.Fatalf("unexpected records %v", vals)
+	}
+}
+
+func TestRfc2136ResponseTsig(t *testing.T) {
+	srv := newRfc2136TestServer(t)
+	client := srv.client()
+
+	ops := []*Operation{
+		{Operation: UPSERT, Value: "\"token\""},
+	}
+
+	srv.sign([]byte("fedcba9876543210fedcba9876543210"), false)
+	err := client.DnsCommit(nil, rfc2136TestDomain, "TXT", ops)
+	if err == nil {
+		t.Fatal("response signed with another key accepted")
+	}
+
+	srv.sign(rfc2136TestKey, true)
+	err = client.DnsCommit(nil, rfc2136TestDomain, "TXT", ops)
+	if err == nil {
+		t.Fatal("unsigned response accepted")
+	}
+
+	srv.sign(rfc2136TestKey, false)
+	err = client.DnsCommit(nil, rfc2136TestDomain, "TXT", ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.keySecret = []byte("fedcba9876543210fedcba9876543210")
+	err = client.DnsCommit(nil, rfc2136TestDomain, "TXT", ops)
+	if err == nil {
+		t.Fatal("update signed with another key accepted")
+	}
+}
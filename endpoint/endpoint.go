@@ -9,6 +9,7 @@ import (
 	"crypto/subtle"
 	"encoding/base64"
 	"encoding/json"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,6 +21,7 @@ import (
 	"github.com/pritunl/pritunl-zero/alert"
 	"github.com/pritunl/pritunl-zero/alertevent"
 	"github.com/pritunl/pritunl-zero/check"
+	"github.com/pritunl/pritunl-zero/command"
 	"github.com/pritunl/pritunl-zero/constants"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/endpoints"
@@ -28,23 +30,27 @@ import (
 	"github.com/pritunl/pritunl-zero/nonce"
 	"github.com/pritunl/pritunl-zero/settings"
 	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/pritunl/pritunl-zero/view"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/nacl/box"
 )
 
+var tagKeyReg = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
 type Endpoint struct {
-	Id            bson.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name          string        `bson:"name" json:"name"`
-	Username      string        `bson:"username" json:"username"`
-	Roles         []string      `bson:"roles" json:"roles"`
-	ClientKey     *ClientKey    `bson:"client_key" json:"client_key"`
-	ServerKey     *ServerKey    `bson:"server_key" json:"-"`
-	Info          *EndpointInfo `bson:"-" json:"info"`
-	HasClientKey  bool          `bson:"-" json:"has_client_key"`
-	Data          *Data         `bson:"data" json:"data"`
-	keyLoaded     bool          `bson:"-" json:"-"`
-	clientPubKey  [32]byte      `bson:"-" json:"-"`
-	serverPrivKey [32]byte      `bson:"-" json:"-"`
+	Id            bson.ObjectID     `bson:"_id,omitempty" json:"id"`
+	Name          string            `bson:"name" json:"name"`
+	Username      string            `bson:"username" json:"username"`
+	Roles         []string          `bson:"roles" json:"roles"`
+	Tags          map[string]string `bson:"tags" json:"tags"`
+	ClientKey     *ClientKey        `bson:"client_key" json:"client_key"`
+	ServerKey     *ServerKey        `bson:"server_key" json:"-"`
+	Info          *EndpointInfo     `bson:"-" json:"info"`
+	HasClientKey  bool              `bson:"-" json:"has_client_key"`
+	Data          *Data             `bson:"data" json:"data"`
+	keyLoaded     bool              `bson:"-" json:"-"`
+	clientPubKey  [32]byte          `bson:"-" json:"-"`
+	serverPrivKey [32]byte          `bson:"-" json:"-"`
 }
 
 type EndpointInfo struct {
@@ -54,7 +60,8 @@ type EndpointInfo struct {
 }
 
 type EndpointConf struct {
-	Checks []*check.Check `json:"checks"`
+	Checks   []*check.Check     `json:"checks"`
+	Commands []*command.Request `json:"commands,omitempty"`
 }
 
 type Data struct {
@@ -174,6 +181,36 @@ func (e *Endpoint) Validate(db *database.Database) (
 		e.Roles = []string{}
 	}
 
+	if e.Tags == nil {
+		e.Tags = map[string]string{}
+	}
+
+	if len(e.Tags) > 64 {
+		errData = &errortypes.ErrorData{
+			Error:   "endpoint_tags_invalid",
+			Message: "Endpoint has too many tags",
+		}
+		return
+	}
+
+	for key, val := range e.Tags {
+		if len(key) > 64 || !tagKeyReg.MatchString(key) {
+			errData = &errortypes.ErrorData{
+				Error:   "endpoint_tag_key_invalid",
+				Message: "Endpoint tag key is invalid",
+			}
+			return
+		}
+
+		if len(val) > 256 {
+			errData = &errortypes.ErrorData{
+				Error:   "endpoint_tag_value_invalid",
+				Message: "Endpoint tag value too long",
+			}
+			return
+		}
+	}
+
 	if e.ClientKey == nil || e.ServerKey == nil {
 		err = e.GenerateKey()
 		if err != nil {
@@ -230,8 +267,8 @@ func (e *Endpoint) Json(alertsMap map[string][]*alert.Alert,
 	}
 }
 
-func (e *Endpoint) GetConf(db *database.Database) (
-	encData []byte, err error) {
+func (e *Endpoint) GetConf(db *database.Database,
+	commands []*command.Request) (encData []byte, err error) {
 
 	clientPubKey, serverPrivKey, err := e.GetKeys()
 	if err != nil {
@@ -242,13 +279,19 @@ func (e *Endpoint) GetConf(db *database.Database) (
 		return
 	}
 
-	checks, err := check.GetRoles(db, e.Roles)
+	views, err := view.GetMatching(db, e.Id)
+	if err != nil {
+		return
+	}
+
+	checks, err := check.GetTargets(db, e.Roles, views)
 	if err != nil {
 		return
 	}
 
 	conf := &EndpointConf{
-		Checks: checks,
+		Checks:   checks,
+		Commands: commands,
 	}
 
 	confData, err := json.Marshal(conf)
@@ -476,7 +519,12 @@ func (e *Endpoint) Register(db *database.Database, reqData *RegisterData) (
 func (e *Endpoint) GetAlerts(db *database.Database) (
 	alerts []*alert.Alert, err error) {
 
-	alerts, err = alert.GetRoles(db, e.Roles)
+	views, err := view.GetMatching(db, e.Id)
+	if err != nil {
+		return
+	}
+
+	alerts, err = alert.GetTargets(db, e.Roles, views)
 	if err != nil {
 		return
 	}
@@ -570,13 +618,31 @@ func (e *Endpoint) InsertDoc(db *database.Database,
 			return
 		}
 
-		actAlrts := doc.CheckAlerts(alerts)
+		key := endpoints.GetAlertKey(doc)
+		active := []bson.ObjectID{}
+
+		firing, er := alertevent.GetOpenAlerts(db, e.Id, key)
+		if er != nil {
+			err = er
+			return
+		}
+
+		actAlrts, er := doc.CheckAlerts(db, alerts, firing)
+		if er != nil {
+			err = er
+			return
+		}
 		if actAlrts != nil && len(actAlrts) > 0 {
 			for _, alrt := range actAlrts {
-				go alertevent.New(e.Roles, e.Id, alrt.Name, e.Name, alrt.Resource,
-					alrt.Message, alrt.Level, alrt.Frequency)
+				active = append(active, alrt.Alert)
+				go alertevent.New(e.Roles, e.Id, alrt.Alert, key, alrt.Name,
+					e.Name, alrt.Resource, alrt.Message, alrt.Level,
+					alrt.Frequency)
 			}
 		}
+
+		go alertevent.Clear(e.Id, key, endpoints.GetAlertResources(doc),
+			active)
 	}
 
 	return
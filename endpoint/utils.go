@@ -221,6 +221,33 @@ func RemoveData(db *database.Database, endpointId bson.ObjectID) (
 		return
 	}
 
+	tiers := []*database.Collection{
+		db.EndpointsSystem5m(),
+		db.EndpointsSystem1h(),
+		db.EndpointsLoad5m(),
+		db.EndpointsLoad1h(),
+		db.EndpointsDisk5m(),
+		db.EndpointsDisk1h(),
+		db.EndpointsDiskIo5m(),
+		db.EndpointsDiskIo1h(),
+		db.EndpointsNetwork5m(),
+		db.EndpointsNetwork1h(),
+		db.EndpointsCheck5m(),
+		db.EndpointsCheck1h(),
+		db.EndpointsInventory(),
+		db.EndpointsInventoryChange(),
+	}
+
+	for _, coll = range tiers {
+		_, err = coll.DeleteMany(db, &bson.M{
+			"e": endpointId,
+		})
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
 	return
 }
 
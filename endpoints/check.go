@@ -6,6 +6,7 @@ import (
 	"math"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/alert"
@@ -29,6 +30,7 @@ type Check struct {
 	ErrorsIn  []string `bson:"-" json:"r"`
 
 	checkName string `bson:"-" json:"-"`
+	checkType string `bson:"-" json:"-"`
 }
 
 type CheckLog struct {
@@ -106,23 +108,32 @@ func (d *Check) StaticData() *bson.M {
 	return nil
 }
 
-func (d *Check) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
+func (d *Check) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
 	alerts = []*Alert{}
 
 	for _, resource := range resources {
 		switch resource.Resource {
 		case alert.CheckHttpFailed:
-			for _, er := range d.ErrorsIn {
-				if er != "" {
-					alerts = []*Alert{
-						NewAlert(resource, fmt.Sprintf(
-							"Check HTTP error: %s %s",
-							d.checkName,
-							er,
-						)),
-					}
-					break
-				}
+			if d.checkType == check.Http || d.checkType == check.Ping {
+				alerts = d.checkFailed(alerts, resource, "HTTP")
+			}
+			break
+		case alert.CheckTcpFailed:
+			if d.checkType == check.Tcp {
+				alerts = d.checkFailed(alerts, resource, "TCP")
+			}
+			break
+		case alert.CheckTlsFailed:
+			if d.checkType == check.Tls {
+				alerts = d.checkFailed(alerts, resource, "TLS")
+			}
+			break
+		case alert.CheckDnsFailed:
+			if d.checkType == check.Dns {
+				alerts = d.checkFailed(alerts, resource, "DNS")
 			}
 			break
 		}
@@ -131,6 +142,26 @@ func (d *Check) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
 	return
 }
 
+func (d *Check) checkFailed(alerts []*Alert, resource *alert.Alert,
+	label string) []*Alert {
+
+	for _, er := range d.ErrorsIn {
+		if er != "" {
+			alerts = append(alerts,
+				NewAlert(resource, fmt.Sprintf(
+					"Check %s error: %s %s",
+					label,
+					d.checkName,
+					er,
+				)),
+			)
+			break
+		}
+	}
+
+	return alerts
+}
+
 func (d *Check) Handle(db *database.Database) (handled, checkAlerts bool,
 	err error) {
 
@@ -138,6 +169,17 @@ func (d *Check) Handle(db *database.Database) (handled, checkAlerts bool,
 		return
 	}
 
+	chck, err := check.Get(db, d.Check)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+		}
+		return
+	}
+
+	d.checkName = chck.Name
+	d.checkType = chck.Type
+
 	log := []string{}
 	for i, e := range d.ErrorsIn {
 		if e != "" && len(d.TargetsIn) > i {
@@ -172,37 +214,6 @@ func (d *Check) Handle(db *database.Database) (handled, checkAlerts bool,
 	return
 }
 
-func (d *Check) HandleOld(db *database.Database) (handled, checkAlerts bool,
-	err error) {
-
-	handled = true
-
-	chck, err := check.Get(db, d.Check)
-	if err != nil {
-		if _, ok := err.(*database.NotFoundError); ok {
-			err = nil
-		}
-		return
-	}
-
-	d.checkName = chck.Name
-
-	state := &check.State{
-		Endpoint:  d.Endpoint,
-		Timestamp: d.Timestamp,
-		Targets:   d.TargetsIn,
-		Latency:   d.LatencyIn,
-		Errors:    d.ErrorsIn,
-	}
-
-	checkAlerts, err = chck.UpdateState(db, state)
-	if err != nil {
-		return
-	}
-
-	return
-}
-
 func (d *CheckLog) FormattedLog(names map[bson.ObjectID]string) (
 	log LogData) {
 
@@ -240,7 +251,7 @@ func GetCheckChartSingle(c context.Context, db *database.Database,
 		return
 	}
 
-	names, err := getRolesNameMapped(db, chck.Roles)
+	names, err := getTargetsNameMapped(db, chck.Roles, chck.Views)
 	if err != nil {
 		return
 	}
@@ -307,7 +318,7 @@ func GetCheckChart(c context.Context, db *database.Database,
 		return
 	}
 
-	coll := db.EndpointsCheck()
+	coll := checkSeries.chartCollection(db, interval)
 	chart := NewChart(start, end, interval)
 
 	chck, err := check.Get(db, checkId)
@@ -315,7 +326,7 @@ func GetCheckChart(c context.Context, db *database.Database,
 		return
 	}
 
-	names, err := getRolesNameMapped(db, chck.Roles)
+	names, err := getTargetsNameMapped(db, chck.Roles, chck.Views)
 	if err != nil {
 		return
 	}
@@ -420,7 +431,7 @@ func GetCheckLog(c context.Context, db *database.Database,
 		return
 	}
 
-	names, err := getRolesNameMapped(db, chck.Roles)
+	names, err := getTargetsNameMapped(db, chck.Roles, chck.Views)
 	if err != nil {
 		return
 	}
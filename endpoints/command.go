@@ -0,0 +1,60 @@
+package endpoints
+
+import (
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/alert"
+	"github.com/pritunl/pritunl-zero/command"
+	"github.com/pritunl/pritunl-zero/database"
+)
+
+type CommandOutput struct {
+	Endpoint  bson.ObjectID `bson:"e" json:"e"`
+	Timestamp time.Time     `bson:"t" json:"t"`
+
+	Run      bson.ObjectID `bson:"-" json:"r"`
+	Sequence int           `bson:"-" json:"s"`
+	Stream   string        `bson:"-" json:"o"`
+	Data     string        `bson:"-" json:"d"`
+	Done     bool          `bson:"-" json:"x"`
+	ExitCode int           `bson:"-" json:"c"`
+}
+
+func (d *CommandOutput) GetCollection(
+	db *database.Database) *database.Collection {
+
+	return db.CommandsOutput()
+}
+
+func (d *CommandOutput) Format(id bson.ObjectID) time.Time {
+	d.Endpoint = id
+	d.Timestamp = d.Timestamp.UTC()
+	return d.Timestamp
+}
+
+func (d *CommandOutput) StaticData() *bson.M {
+	return nil
+}
+
+func (d *CommandOutput) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
+	return
+}
+
+func (d *CommandOutput) Handle(db *database.Database) (handled,
+	checkAlerts bool, err error) {
+
+	handled = true
+
+	err = command.AppendOutput(db, d.Run, d.Endpoint, d.Sequence,
+		d.Stream, d.Data, d.Done, d.ExitCode)
+	if err != nil {
+		return
+	}
+
+	return
+}
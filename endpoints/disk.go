@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/alert"
@@ -76,20 +77,43 @@ func (d *Disk) StaticData() *bson.M {
 	}
 }
 
-func (d *Disk) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
+func (d *Disk) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
 	alerts = []*Alert{}
 
+	docs := []*Disk{d}
+	window := alert.MaxDuration(resources)
+	if window > 0 {
+		docs, err = getDiskSamples(db, d.Endpoint,
+			d.Timestamp.Add(-window), d.Timestamp)
+		if err != nil {
+			return
+		}
+
+		if len(docs) == 0 || !docs[len(docs)-1].Timestamp.Equal(
+			d.Timestamp) {
+
+			docs = append(docs, d)
+		}
+	}
+
 	for _, resource := range resources {
 		if resource.Resource == alert.DiskUsageLevel {
 			for _, mount := range d.Mounts {
-				if mount.Used > float64(resource.ValueInt) {
-					alerts = []*Alert{
+				active, value := resource.Evaluate(
+					diskSamples(docs, mount.Path), d.Timestamp,
+					firing.Contains(resource.Id))
+				if active {
+					alerts = append(alerts,
 						NewAlert(resource, fmt.Sprintf(
 							"Disk low on space %s (%.2f%%)",
 							mount.Path,
-							mount.Used,
+							value,
 						)),
-					}
+					)
+					break
 				}
 			}
 		}
@@ -167,7 +191,7 @@ func GetDiskChart(c context.Context, db *database.Database,
 		return
 	}
 
-	coll := db.EndpointsDisk()
+	coll := diskSeries.chartCollection(db, interval)
 	chart := NewChart(start, end, interval)
 
 	timeQuery := bson.D{
@@ -2,8 +2,10 @@ package endpoints
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/alert"
@@ -80,7 +82,82 @@ func (d *DiskIo) StaticData() *bson.M {
 	}
 }
 
-func (d *DiskIo) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
+// Average time in milliseconds for completed operations in the interval
+func (d *DiskIoDisk) Await() float64 {
+	count := d.CountRead + d.CountWrite
+	if count == 0 {
+		return 0
+	}
+
+	return float64(d.TimeRead+d.TimeWrite) / float64(count)
+}
+
+// Percentage of the interval the disk was busy with operations
+func (d *DiskIoDisk) Utilization() float64 {
+	return min(float64(d.TimeIo)/60000*100, 100)
+}
+
+func (d *DiskIo) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
+	alerts = []*Alert{}
+
+	docs := []*DiskIo{d}
+	window := alert.MaxDuration(resources)
+	if window > 0 {
+		docs, err = getDiskIoSamples(db, d.Endpoint,
+			d.Timestamp.Add(-window), d.Timestamp)
+		if err != nil {
+			return
+		}
+
+		if len(docs) == 0 || !docs[len(docs)-1].Timestamp.Equal(
+			d.Timestamp) {
+
+			docs = append(docs, d)
+		}
+	}
+
+	for _, resource := range resources {
+		switch resource.Resource {
+		case alert.DiskIoAwait:
+			for _, dsk := range d.Disks {
+				active, value := resource.Evaluate(
+					diskIoSamples(docs, dsk.Name, (*DiskIoDisk).Await),
+					d.Timestamp, firing.Contains(resource.Id))
+				if active {
+					alerts = append(alerts,
+						NewAlert(resource, fmt.Sprintf(
+							"Disk I/O high await %s (%.2fms)",
+							dsk.Name,
+							value,
+						)),
+					)
+					break
+				}
+			}
+			break
+		case alert.DiskIoUtilization:
+			for _, dsk := range d.Disks {
+				active, value := resource.Evaluate(
+					diskIoSamples(docs, dsk.Name, (*DiskIoDisk).Utilization),
+					d.Timestamp, firing.Contains(resource.Id))
+				if active {
+					alerts = append(alerts,
+						NewAlert(resource, fmt.Sprintf(
+							"Disk I/O high utilization %s (%.2f%%)",
+							dsk.Name,
+							value,
+						)),
+					)
+					break
+				}
+			}
+			break
+		}
+	}
+
 	return
 }
 
@@ -160,7 +237,7 @@ func GetDiskIoChart(c context.Context, db *database.Database,
 		return
 	}
 
-	coll := db.EndpointsDiskIo()
+	coll := diskIoSeries.chartCollection(db, interval)
 	chart := NewChart(start, end, interval)
 
 	timeQuery := bson.D{
@@ -6,6 +6,7 @@ import (
 	"hash/fnv"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/pritunl-zero/alert"
@@ -21,7 +22,8 @@ type Doc interface {
 	GetCollection(*database.Database) *database.Collection
 	Format(bson.ObjectID) time.Time
 	StaticData() *bson.M
-	CheckAlerts(resources []*alert.Alert) []*Alert
+	CheckAlerts(*database.Database, []*alert.Alert, set.Set) ([]*Alert,
+		error)
 	Handle(*database.Database) (bool, bool, error)
 }
 
@@ -65,11 +67,76 @@ func GetObj(typ string) Doc {
 		return &Kmsg{}
 	case "check":
 		return &Check{}
+	case "packages":
+		return &Packages{}
+	case "ports":
+		return &Ports{}
+	case "users":
+		return &Users{}
+	case "services":
+		return &Services{}
+	case "command":
+		return &CommandOutput{}
 	default:
 		return nil
 	}
 }
 
+// Alert resources evaluated by a doc type, open incidents for these
+// resources are resolved when the doc no longer triggers the alert
+func GetAlertResources(doc Doc) []string {
+	switch doc.(type) {
+	case *System:
+		return []string{
+			alert.SystemCpuLevel,
+			alert.SystemMemoryLevel,
+			alert.SystemSwapLevel,
+			alert.SystemHugePagesLevel,
+			alert.SystemMdFailed,
+			alert.SystemProcesses,
+			alert.SystemPackageUpdates,
+		}
+	case *Disk:
+		return []string{
+			alert.DiskUsageLevel,
+		}
+	case *Load:
+		return []string{
+			alert.LoadPerCore,
+		}
+	case *Network:
+		return []string{
+			alert.NetworkErrorRate,
+			alert.NetworkThroughput,
+		}
+	case *DiskIo:
+		return []string{
+			alert.DiskIoAwait,
+			alert.DiskIoUtilization,
+		}
+	case *Check:
+		return []string{
+			alert.CheckHttpFailed,
+			alert.CheckTcpFailed,
+			alert.CheckTlsFailed,
+			alert.CheckDnsFailed,
+		}
+	default:
+		return []string{}
+	}
+}
+
+// Alert incident key to separate incidents from multiple sources of the
+// same doc type on an endpoint
+func GetAlertKey(doc Doc) string {
+	switch d := doc.(type) {
+	case *Check:
+		return d.Check.Hex()
+	default:
+		return ""
+	}
+}
+
 func GetChart(c context.Context, db *database.Database,
 	endpoint bson.ObjectID, typ string, start, end time.Time,
 	interval time.Duration) (ChartData, error) {
@@ -173,6 +240,7 @@ func NewChart(start, end time.Time, interval time.Duration) (chrt *Chart) {
 }
 
 type Alert struct {
+	Alert     bson.ObjectID
 	Name      string
 	Resource  string
 	Message   string
@@ -182,6 +250,7 @@ type Alert struct {
 
 func NewAlert(resource *alert.Alert, message string) (alrt *Alert) {
 	alrt = &Alert{
+		Alert:     resource.Id,
 		Name:      resource.Name,
 		Resource:  resource.Resource,
 		Message:   message,
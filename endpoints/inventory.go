@@ -0,0 +1,758 @@
+package endpoints
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/alert"
+	"github.com/pritunl/pritunl-zero/database"
+)
+
+const (
+	InventoryPackage = "package"
+	InventoryPort    = "port"
+	InventoryUser    = "user"
+	InventoryService = "service"
+
+	InventoryAdded   = "added"
+	InventoryRemoved = "removed"
+	InventoryChanged = "changed"
+)
+
+type InventoryItem struct {
+	Id        bson.ObjectID `bson:"_id" json:"id"`
+	Endpoint  bson.ObjectID `bson:"e" json:"endpoint"`
+	Kind      string        `bson:"k" json:"kind"`
+	Key       string        `bson:"x" json:"-"`
+	Name      string        `bson:"n" json:"name"`
+	Value     string        `bson:"v" json:"value"`
+	Detail    string        `bson:"d" json:"detail"`
+	FirstSeen time.Time     `bson:"f" json:"first_seen"`
+	Timestamp time.Time     `bson:"t" json:"timestamp"`
+}
+
+type InventoryChange struct {
+	Id        bson.ObjectID `bson:"_id" json:"id"`
+	Endpoint  bson.ObjectID `bson:"e" json:"endpoint"`
+	Kind      string        `bson:"k" json:"kind"`
+	Name      string        `bson:"n" json:"name"`
+	Action    string        `bson:"a" json:"action"`
+	OldValue  string        `bson:"o" json:"old_value"`
+	Value     string        `bson:"v" json:"value"`
+	OldDetail string        `bson:"od" json:"old_detail"`
+	Detail    string        `bson:"d" json:"detail"`
+	Timestamp time.Time     `bson:"t" json:"timestamp"`
+}
+
+type Packages struct {
+	Endpoint  bson.ObjectID `bson:"e" json:"e"`
+	Timestamp time.Time     `bson:"t" json:"t"`
+
+	Packages []*Package `bson:"-" json:"p"`
+}
+
+type Package struct {
+	Name    string `json:"n"`
+	Version string `json:"v"`
+	Arch    string `json:"a"`
+}
+
+type Ports struct {
+	Endpoint  bson.ObjectID `bson:"e" json:"e"`
+	Timestamp time.Time     `bson:"t" json:"t"`
+
+	Ports []*Port `bson:"-" json:"p"`
+}
+
+type Port struct {
+	Protocol string `json:"r"`
+	Address  string `json:"a"`
+	Port     int    `json:"o"`
+	Pid      int    `json:"i"`
+	Process  string `json:"c"`
+}
+
+type Users struct {
+	Endpoint  bson.ObjectID `bson:"e" json:"e"`
+	Timestamp time.Time     `bson:"t" json:"t"`
+
+	Users []*LocalUser `bson:"-" json:"u"`
+}
+
+type LocalUser struct {
+	Name  string `json:"n"`
+	Uid   int    `json:"i"`
+	Gid   int    `json:"g"`
+	Home  string `json:"h"`
+	Shell string `json:"s"`
+}
+
+type Services struct {
+	Endpoint  bson.ObjectID `bson:"e" json:"e"`
+	Timestamp time.Time     `bson:"t" json:"t"`
+
+	Services []*Service `bson:"-" json:"s"`
+}
+
+type Service struct {
+	Name    string `json:"n"`
+	State   string `json:"a"`
+	Enabled bool   `json:"e"`
+}
+
+func (d *Packages) GetCollection(db *database.Database) *database.Collection {
+	return db.EndpointsInventory()
+}
+
+func (d *Packages) Format(id bson.ObjectID) time.Time {
+	d.Endpoint = id
+	d.Timestamp = d.Timestamp.UTC().Truncate(1 * time.Minute)
+	return d.Timestamp
+}
+
+func (d *Packages) StaticData() *bson.M {
+	return nil
+}
+
+func (d *Packages) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
+	return
+}
+
+func (d *Packages) Handle(db *database.Database) (handled,
+	checkAlerts bool, err error) {
+
+	handled = true
+
+	items := []*InventoryItem{}
+	for _, pkg := range d.Packages {
+		key := pkg.Name
+		if pkg.Arch != "" {
+			key += ":" + pkg.Arch
+		}
+
+		items = append(items, &InventoryItem{
+			Key:    key,
+			Name:   pkg.Name,
+			Value:  pkg.Version,
+			Detail: pkg.Arch,
+		})
+	}
+
+	err = syncInventory(db, d.Endpoint, InventoryPackage,
+		d.Timestamp, items)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (d *Ports) GetCollection(db *database.Database) *database.Collection {
+	return db.EndpointsInventory()
+}
+
+func (d *Ports) Format(id bson.ObjectID) time.Time {
+	d.Endpoint = id
+	d.Timestamp = d.Timestamp.UTC().Truncate(1 * time.Minute)
+	return d.Timestamp
+}
+
+func (d *Ports) StaticData() *bson.M {
+	return nil
+}
+
+func (d *Ports) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
+	return
+}
+
+func (d *Ports) Handle(db *database.Database) (handled,
+	checkAlerts bool, err error) {
+
+	handled = true
+
+	items := []*InventoryItem{}
+	for _, port := range d.Ports {
+		items = append(items, &InventoryItem{
+			Key: fmt.Sprintf("%s/%s/%d",
+				port.Protocol, port.Address, port.Port),
+			Name:   fmt.Sprintf("%d/%s", port.Port, port.Protocol),
+			Value:  port.Process,
+			Detail: port.Address,
+		})
+	}
+
+	err = syncInventory(db, d.Endpoint, InventoryPort,
+		d.Timestamp, items)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (d *Users) GetCollection(db *database.Database) *database.Collection {
+	return db.EndpointsInventory()
+}
+
+func (d *Users) Format(id bson.ObjectID) time.Time {
+	d.Endpoint = id
+	d.Timestamp = d.Timestamp.UTC().Truncate(1 * time.Minute)
+	return d.Timestamp
+}
+
+func (d *Users) StaticData() *bson.M {
+	return nil
+}
+
+func (d *Users) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
+	return
+}
+
+func (d *Users) Handle(db *database.Database) (handled,
+	checkAlerts bool, err error) {
+
+	handled = true
+
+	items := []*InventoryItem{}
+	for _, usr := range d.Users {
+		items = append(items, &InventoryItem{
+			Key:   usr.Name,
+			Name:  usr.Name,
+			Value: fmt.Sprintf("%d", usr.Uid),
+			Detail: fmt.Sprintf("gid %d home %s shell %s",
+				usr.Gid, usr.Home, usr.Shell),
+		})
+	}
+
+	err = syncInventory(db, d.Endpoint, InventoryUser,
+		d.Timestamp, items)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (d *Services) GetCollection(db *database.Database) *database.Collection {
+	return db.EndpointsInventory()
+}
+
+func (d *Services) Format(id bson.ObjectID) time.Time {
+	d.Endpoint = id
+	d.Timestamp = d.Timestamp.UTC().Truncate(1 * time.Minute)
+	return d.Timestamp
+}
+
+func (d *Services) StaticData() *bson.M {
+	return nil
+}
+
+func (d *Services) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
+	return
+}
+
+func (d *Services) Handle(db *database.Database) (handled,
+	checkAlerts bool, err error) {
+
+	handled = true
+
+	items := []*InventoryItem{}
+	for _, srvc := range d.Services {
+		detail := "disabled"
+		if srvc.Enabled {
+			detail = "enabled"
+		}
+
+		items = append(items, &InventoryItem{
+			Key:    srvc.Name,
+			Name:   srvc.Name,
+			Value:  srvc.State,
+			Detail: detail,
+		})
+	}
+
+	err = syncInventory(db, d.Endpoint, InventoryService,
+		d.Timestamp, items)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Replace the stored inventory of a kind for an endpoint with a full
+// snapshot, recording each added, removed and changed item
+func syncInventory(db *database.Database, endpoint bson.ObjectID,
+	kind string, timestamp time.Time, items []*InventoryItem) (err error) {
+
+	// Empty reports are ignored to avoid removing the inventory when the
+	// agent fails to collect it
+	if len(items) == 0 {
+		return
+	}
+
+	coll := db.EndpointsInventory()
+	changeColl := db.EndpointsInventoryChange()
+
+	cursor, err := coll.Find(db, &bson.M{
+		"e": endpoint,
+		"k": kind,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	current := map[string]*InventoryItem{}
+	for cursor.Next(db) {
+		item := &InventoryItem{}
+		err = cursor.Decode(item)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		current[item.Key] = item
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	// Items already stored receive no change record on the first snapshot
+	initial := len(current) == 0
+
+	inserts := []interface{}{}
+	changes := []interface{}{}
+	seen := set.NewSet()
+
+	for _, item := range items {
+		if seen.Contains(item.Key) {
+			continue
+		}
+		seen.Add(item.Key)
+
+		cur := current[item.Key]
+		if cur == nil {
+			item.Id = bson.NewObjectID()
+			item.Endpoint = endpoint
+			item.Kind = kind
+			item.FirstSeen = timestamp
+			item.Timestamp = timestamp
+			inserts = append(inserts, item)
+
+			if !initial {
+				changes = append(changes, &InventoryChange{
+					Id:        bson.NewObjectID(),
+					Endpoint:  endpoint,
+					Kind:      kind,
+					Name:      item.Name,
+					Action:    InventoryAdded,
+					Value:     item.Value,
+					Detail:    item.Detail,
+					Timestamp: timestamp,
+				})
+			}
+			continue
+		}
+
+		if cur.Value != item.Value || cur.Detail != item.Detail {
+			_, err = coll.UpdateOne(db, &bson.M{
+				"_id": cur.Id,
+			}, &bson.M{
+				"$set": &bson.M{
+					"n": item.Name,
+					"v": item.Value,
+					"d": item.Detail,
+				},
+			})
+			if err != nil {
+				err = database.ParseError(err)
+				return
+			}
+
+			changes = append(changes, &InventoryChange{
+				Id:        bson.NewObjectID(),
+				Endpoint:  endpoint,
+				Kind:      kind,
+				Name:      item.Name,
+				Action:    InventoryChanged,
+				OldValue:  cur.Value,
+				Value:     item.Value,
+				OldDetail: cur.Detail,
+				Detail:    item.Detail,
+				Timestamp: timestamp,
+			})
+		}
+	}
+
+	removed := []bson.ObjectID{}
+	for key, cur := range current {
+		if seen.Contains(key) {
+			continue
+		}
+
+		removed = append(removed, cur.Id)
+		changes = append(changes, &InventoryChange{
+			Id:        bson.NewObjectID(),
+			Endpoint:  endpoint,
+			Kind:      kind,
+			Name:      cur.Name,
+			Action:    InventoryRemoved,
+			OldValue:  cur.Value,
+			OldDetail: cur.Detail,
+			Timestamp: timestamp,
+		})
+	}
+
+	if len(removed) > 0 {
+		_, err = coll.DeleteMany(db, &bson.M{
+			"_id": &bson.M{
+				"$in": removed,
+			},
+		})
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	if len(inserts) > 0 {
+		_, err = coll.InsertMany(db, inserts)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	_, err = coll.UpdateMany(db, &bson.M{
+		"e": endpoint,
+		"k": kind,
+	}, &bson.M{
+		"$set": &bson.M{
+			"t": timestamp,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if len(changes) > 0 {
+		_, err = changeColl.InsertMany(db, changes)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	return
+}
+
+func GetInventory(c context.Context, db *database.Database,
+	endpoint bson.ObjectID, kind string) (items []*InventoryItem, err error) {
+
+	coll := db.EndpointsInventory()
+	items = []*InventoryItem{}
+
+	cursor, err := coll.Find(
+		c,
+		&bson.M{
+			"e": endpoint,
+			"k": kind,
+		},
+		options.Find().
+			SetSort(bson.D{{"n", 1}}),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(c)
+
+	for cursor.Next(c) {
+		item := &InventoryItem{}
+		err = cursor.Decode(item)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		items = append(items, item)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetInventoryChanges(c context.Context, db *database.Database,
+	endpoint bson.ObjectID, kind string, page, pageCount int64) (
+	changes []*InventoryChange, count int64, err error) {
+
+	coll := db.EndpointsInventoryChange()
+	changes = []*InventoryChange{}
+
+	query := bson.M{
+		"e": endpoint,
+	}
+	if kind != "" {
+		query["k"] = kind
+	}
+
+	count, err = coll.CountDocuments(c, query)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if pageCount == 0 {
+		pageCount = 50
+	}
+	maxPage := count / pageCount
+	if count == pageCount {
+		maxPage = 0
+	}
+	page = min(page, maxPage)
+	skip := min(page*pageCount, count)
+
+	cursor, err := coll.Find(
+		c,
+		query,
+		options.Find().
+			SetSort(bson.D{{"t", -1}}).
+			SetSkip(skip).
+			SetLimit(pageCount),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(c)
+
+	for cursor.Next(c) {
+		change := &InventoryChange{}
+		err = cursor.Decode(change)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		changes = append(changes, change)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// Search inventory items across all endpoints. The value filter is either a
+// case insensitive substring or a version comparison such as "<3.0.7".
+func SearchInventory(c context.Context, db *database.Database,
+	kind, name, value string, page, pageCount int64) (
+	items []*InventoryItem, count int64, err error) {
+
+	coll := db.EndpointsInventory()
+	items = []*InventoryItem{}
+
+	query := bson.M{
+		"k": kind,
+	}
+	if name != "" {
+		query["n"] = &bson.M{
+			"$regex":   fmt.Sprintf("^%s$", regexp.QuoteMeta(name)),
+			"$options": "i",
+		}
+	}
+
+	op, version := parseVersionFilter(value)
+	if op == "" && value != "" {
+		query["v"] = &bson.M{
+			"$regex":   fmt.Sprintf(".*%s.*", regexp.QuoteMeta(value)),
+			"$options": "i",
+		}
+	}
+
+	cursor, err := coll.Find(
+		c,
+		query,
+		options.Find().
+			SetSort(bson.D{{"n", 1}, {"e", 1}}),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(c)
+
+	matched := []*InventoryItem{}
+	for cursor.Next(c) {
+		item := &InventoryItem{}
+		err = cursor.Decode(item)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		if op != "" && !matchVersion(item.Value, op, version) {
+			continue
+		}
+
+		matched = append(matched, item)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	count = int64(len(matched))
+
+	if pageCount <= 0 {
+		pageCount = 50
+	}
+	maxPage := count / pageCount
+	if count == pageCount {
+		maxPage = 0
+	}
+	page = max(min(page, maxPage), 0)
+	skip := min(page*pageCount, count)
+
+	items = matched[skip:min(skip+pageCount, count)]
+
+	return
+}
+
+func parseVersionFilter(filter string) (op, version string) {
+	filter = strings.TrimSpace(filter)
+
+	for _, prefix := range []string{"<=", ">=", "!=", "<", ">", "="} {
+		if strings.HasPrefix(filter, prefix) {
+			op = prefix
+			version = strings.TrimSpace(filter[len(prefix):])
+			return
+		}
+	}
+
+	return
+}
+
+func matchVersion(value, op, version string) bool {
+	cmp := compareVersion(value, version)
+
+	switch op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "!=":
+		return cmp != 0
+	default:
+		return cmp == 0
+	}
+}
+
+func versionParts(version string) (parts []string) {
+	cur := []rune{}
+	digit := false
+
+	for _, r := range version {
+		isDigit := unicode.IsDigit(r)
+		isLetter := unicode.IsLetter(r)
+
+		if !isDigit && !isLetter {
+			if len(cur) > 0 {
+				parts = append(parts, string(cur))
+				cur = []rune{}
+			}
+			continue
+		}
+
+		if len(cur) > 0 && isDigit != digit {
+			parts = append(parts, string(cur))
+			cur = []rune{}
+		}
+
+		digit = isDigit
+		cur = append(cur, r)
+	}
+
+	if len(cur) > 0 {
+		parts = append(parts, string(cur))
+	}
+
+	return
+}
+
+// Compare package versions by numeric and alphabetic segments, numeric
+// segments are compared by value
+func compareVersion(a, b string) int {
+	aParts := versionParts(a)
+	bParts := versionParts(b)
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		x := aParts[i]
+		y := bParts[i]
+
+		if unicode.IsDigit(rune(x[0])) && unicode.IsDigit(rune(y[0])) {
+			x = strings.TrimLeft(x, "0")
+			y = strings.TrimLeft(y, "0")
+
+			if len(x) != len(y) {
+				if len(x) < len(y) {
+					return -1
+				}
+				return 1
+			}
+		}
+
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+	}
+
+	if len(aParts) < len(bParts) {
+		return -1
+	} else if len(aParts) > len(bParts) {
+		return 1
+	}
+
+	return 0
+}
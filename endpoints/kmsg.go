@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/alert"
@@ -54,7 +55,10 @@ func (d *Kmsg) StaticData() *bson.M {
 	return nil
 }
 
-func (d *Kmsg) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
+func (d *Kmsg) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
 	alerts = []*Alert{}
 
 	for _, resource := range resources {
@@ -63,13 +67,13 @@ func (d *Kmsg) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
 			if strings.Contains(strings.ToLower(d.Message),
 				strings.ToLower(resource.ValueStr)) {
 
-				alerts = []*Alert{
+				alerts = append(alerts,
 					NewAlert(resource, fmt.Sprintf(
 						"Kmsg keyword match (%s): %s",
 						resource.ValueStr,
 						strings.Split(d.Message, "\n")[0],
 					)),
-				}
+				)
 			}
 			break
 		}
@@ -0,0 +1,153 @@
+package endpoints
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo"
+	"github.com/pritunl/pritunl-zero/database"
+)
+
+func findLatest(db *database.Database, coll *database.Collection,
+	group interface{}, since time.Time,
+	decode func(*mongo.Cursor) error) (err error) {
+
+	cursor, err := coll.Aggregate(db, []*bson.M{
+		&bson.M{
+			"$match": &bson.M{
+				"t": &bson.M{
+					"$gte": since,
+				},
+			},
+		},
+		&bson.M{
+			"$sort": &bson.M{
+				"t": -1,
+			},
+		},
+		&bson.M{
+			"$group": &bson.M{
+				"_id": group,
+				"doc": &bson.M{
+					"$first": "$$ROOT",
+				},
+			},
+		},
+		&bson.M{
+			"$replaceRoot": &bson.M{
+				"newRoot": "$doc",
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		err = decode(cursor)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetLatestSystem(db *database.Database, since time.Time) (
+	docs []*System, err error) {
+
+	docs = []*System{}
+
+	err = findLatest(db, db.EndpointsSystem(), "$e", since,
+		func(cursor *mongo.Cursor) (err error) {
+			doc := &System{}
+			err = cursor.Decode(doc)
+			if err != nil {
+				return
+			}
+			docs = append(docs, doc)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetLatestDisk(db *database.Database, since time.Time) (
+	docs []*Disk, err error) {
+
+	docs = []*Disk{}
+
+	err = findLatest(db, db.EndpointsDisk(), "$e", since,
+		func(cursor *mongo.Cursor) (err error) {
+			doc := &Disk{}
+			err = cursor.Decode(doc)
+			if err != nil {
+				return
+			}
+			docs = append(docs, doc)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetLatestNetwork(db *database.Database, since time.Time) (
+	docs []*Network, err error) {
+
+	docs = []*Network{}
+
+	err = findLatest(db, db.EndpointsNetwork(), "$e", since,
+		func(cursor *mongo.Cursor) (err error) {
+			doc := &Network{}
+			err = cursor.Decode(doc)
+			if err != nil {
+				return
+			}
+			docs = append(docs, doc)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetLatestCheck(db *database.Database, since time.Time) (
+	docs []*Check, err error) {
+
+	docs = []*Check{}
+
+	err = findLatest(db, db.EndpointsCheck(), &bson.M{
+		"e": "$e",
+		"c": "$c",
+	}, since, func(cursor *mongo.Cursor) (err error) {
+		doc := &Check{}
+		err = cursor.Decode(doc)
+		if err != nil {
+			return
+		}
+		docs = append(docs, doc)
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -2,8 +2,10 @@ package endpoints
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/alert"
@@ -42,7 +44,60 @@ func (d *Load) StaticData() *bson.M {
 	return nil
 }
 
-func (d *Load) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
+func (d *Load) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
+	alerts = []*Alert{}
+	cores := 0
+
+	for _, resource := range resources {
+		if resource.Resource == alert.LoadPerCore {
+			cores, err = getCpuCores(db, d.Endpoint)
+			if err != nil {
+				return
+			}
+			break
+		}
+	}
+
+	if cores == 0 {
+		return
+	}
+
+	docs := []*Load{d}
+	window := alert.MaxDuration(resources)
+	if window > 0 {
+		docs, err = getLoadSamples(db, d.Endpoint,
+			d.Timestamp.Add(-window), d.Timestamp)
+		if err != nil {
+			return
+		}
+
+		if len(docs) == 0 || !docs[len(docs)-1].Timestamp.Equal(
+			d.Timestamp) {
+
+			docs = append(docs, d)
+		}
+	}
+
+	for _, resource := range resources {
+		switch resource.Resource {
+		case alert.LoadPerCore:
+			active, value := resource.Evaluate(loadSamples(docs, cores),
+				d.Timestamp, firing.Contains(resource.Id))
+			if active {
+				alerts = append(alerts,
+					NewAlert(resource, fmt.Sprintf(
+						"System high load per core (%.2f)",
+						value/100,
+					)),
+				)
+			}
+			break
+		}
+	}
+
 	return
 }
 
@@ -116,7 +171,7 @@ func GetLoadChart(c context.Context, db *database.Database,
 		return
 	}
 
-	coll := db.EndpointsLoad()
+	coll := loadSeries.chartCollection(db, interval)
 	chart := NewChart(start, end, interval)
 
 	timeQuery := bson.D{
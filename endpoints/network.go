@@ -2,8 +2,10 @@ package endpoints
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/alert"
@@ -86,7 +88,86 @@ func (d *Network) StaticData() *bson.M {
 	}
 }
 
-func (d *Network) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
+// Percentage of packets with errors or dropped in the interval
+func (i *Interface) ErrorRate() float64 {
+	packets := i.PacketsSent + i.PacketsRecv
+	if packets == 0 {
+		return 0
+	}
+
+	errs := i.ErrorsSent + i.ErrorsRecv + i.DropsSent + i.DropsRecv
+
+	return float64(errs) / float64(packets) * 100
+}
+
+// Highest of transmit and receive rate in megabits per second
+func (i *Interface) Throughput() float64 {
+	bytes := max(i.BytesSent, i.BytesRecv)
+
+	return float64(bytes) * 8 / 60 / 1000000
+}
+
+func (d *Network) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
+	alerts = []*Alert{}
+
+	docs := []*Network{d}
+	window := alert.MaxDuration(resources)
+	if window > 0 {
+		docs, err = getNetworkSamples(db, d.Endpoint,
+			d.Timestamp.Add(-window), d.Timestamp)
+		if err != nil {
+			return
+		}
+
+		if len(docs) == 0 || !docs[len(docs)-1].Timestamp.Equal(
+			d.Timestamp) {
+
+			docs = append(docs, d)
+		}
+	}
+
+	for _, resource := range resources {
+		switch resource.Resource {
+		case alert.NetworkErrorRate:
+			for _, iface := range d.Interfaces {
+				active, value := resource.Evaluate(
+					networkSamples(docs, iface.Name, (*Interface).ErrorRate),
+					d.Timestamp, firing.Contains(resource.Id))
+				if active {
+					alerts = append(alerts,
+						NewAlert(resource, fmt.Sprintf(
+							"Network high error rate %s (%.2f%%)",
+							iface.Name,
+							value,
+						)),
+					)
+					break
+				}
+			}
+			break
+		case alert.NetworkThroughput:
+			for _, iface := range d.Interfaces {
+				active, value := resource.Evaluate(
+					networkSamples(docs, iface.Name, (*Interface).Throughput),
+					d.Timestamp, firing.Contains(resource.Id))
+				if active {
+					alerts = append(alerts,
+						NewAlert(resource, fmt.Sprintf(
+							"Network high throughput %s (%.2f Mbps)",
+							iface.Name,
+							value,
+						)),
+					)
+					break
+				}
+			}
+			break
+		}
+	}
+
 	return
 }
 
@@ -167,7 +248,7 @@ func GetNetworkChart(c context.Context, db *database.Database,
 		return
 	}
 
-	coll := db.EndpointsNetwork()
+	coll := networkSeries.chartCollection(db, interval)
 	chart := NewChart(start, end, interval)
 
 	timeQuery := bson.D{
@@ -0,0 +1,389 @@
+package endpoints
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/settings"
+)
+
+const (
+	Tier5m = 5 * time.Minute
+	Tier1h = 1 * time.Hour
+
+	// Buckets within the lookback are recomputed to include late samples
+	Lookback5m = 1 * time.Hour
+	Lookback1h = 6 * time.Hour
+)
+
+type rollupField struct {
+	Key string
+	Op  string
+}
+
+// Time series stored per minute with 5 minute and 1 hour rollup tiers. Tier
+// documents share the raw document layout so chart queries can run
+// unmodified against any tier.
+type series struct {
+	Raw      func(*database.Database) *database.Collection
+	Tier5m   func(*database.Database) *database.Collection
+	Tier1h   func(*database.Database) *database.Collection
+	RawTtl   int
+	Keys     []string
+	Array    string
+	ArrayKey string
+	Fields   []*rollupField
+}
+
+var systemSeries = &series{
+	Raw:    (*database.Database).EndpointsSystem,
+	Tier5m: (*database.Database).EndpointsSystem5m,
+	Tier1h: (*database.Database).EndpointsSystem1h,
+	RawTtl: 90,
+	Keys:   []string{"e"},
+	Fields: []*rollupField{
+		{"cu", "$avg"},
+		{"mu", "$avg"},
+		{"su", "$avg"},
+		{"hu", "$avg"},
+		{"pc", "$max"},
+	},
+}
+
+var loadSeries = &series{
+	Raw:    (*database.Database).EndpointsLoad,
+	Tier5m: (*database.Database).EndpointsLoad5m,
+	Tier1h: (*database.Database).EndpointsLoad1h,
+	RawTtl: 90,
+	Keys:   []string{"e"},
+	Fields: []*rollupField{
+		{"lx", "$avg"},
+		{"ly", "$avg"},
+		{"lz", "$avg"},
+	},
+}
+
+var diskSeries = &series{
+	Raw:      (*database.Database).EndpointsDisk,
+	Tier5m:   (*database.Database).EndpointsDisk5m,
+	Tier1h:   (*database.Database).EndpointsDisk1h,
+	RawTtl:   90,
+	Keys:     []string{"e"},
+	Array:    "m",
+	ArrayKey: "p",
+	Fields: []*rollupField{
+		{"u", "$avg"},
+	},
+}
+
+var diskIoSeries = &series{
+	Raw:      (*database.Database).EndpointsDiskIo,
+	Tier5m:   (*database.Database).EndpointsDiskIo5m,
+	Tier1h:   (*database.Database).EndpointsDiskIo1h,
+	RawTtl:   90,
+	Keys:     []string{"e"},
+	Array:    "d",
+	ArrayKey: "n",
+	Fields: []*rollupField{
+		{"br", "$sum"},
+		{"bw", "$sum"},
+		{"cr", "$sum"},
+		{"cw", "$sum"},
+		{"tr", "$sum"},
+		{"tw", "$sum"},
+		{"ti", "$sum"},
+	},
+}
+
+var networkSeries = &series{
+	Raw:      (*database.Database).EndpointsNetwork,
+	Tier5m:   (*database.Database).EndpointsNetwork5m,
+	Tier1h:   (*database.Database).EndpointsNetwork1h,
+	RawTtl:   90,
+	Keys:     []string{"e"},
+	Array:    "i",
+	ArrayKey: "n",
+	Fields: []*rollupField{
+		{"bs", "$sum"},
+		{"br", "$sum"},
+		{"ps", "$sum"},
+		{"pr", "$sum"},
+		{"es", "$sum"},
+		{"er", "$sum"},
+		{"ds", "$sum"},
+		{"dr", "$sum"},
+		{"fs", "$sum"},
+		{"fr", "$sum"},
+	},
+}
+
+var checkSeries = &series{
+	Raw:    (*database.Database).EndpointsCheck,
+	Tier5m: (*database.Database).EndpointsCheck5m,
+	Tier1h: (*database.Database).EndpointsCheck1h,
+	RawTtl: 180,
+	Keys:   []string{"e", "c"},
+	Fields: []*rollupField{
+		{"u", "$min"},
+		{"d", "$max"},
+		{"p", "$avg"},
+	},
+}
+
+var allSeries = []*series{
+	systemSeries,
+	loadSeries,
+	diskSeries,
+	diskIoSeries,
+	networkSeries,
+	checkSeries,
+}
+
+func bucketExpr(interval time.Duration) *bson.M {
+	return &bson.M{
+		"$let": &bson.M{
+			"vars": &bson.M{
+				"t": &bson.D{{"$toLong", "$t"}},
+			},
+			"in": &bson.M{
+				"$subtract": &bson.A{
+					"$$t",
+					&bson.M{
+						"$mod": &bson.A{
+							"$$t",
+							interval.Milliseconds(),
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Select the coarsest tier that evenly divides the chart interval
+func (s *series) chartCollection(db *database.Database,
+	interval time.Duration) *database.Collection {
+
+	if interval%Tier1h == 0 {
+		return s.Tier1h(db)
+	}
+	if interval%Tier5m == 0 {
+		return s.Tier5m(db)
+	}
+	return s.Raw(db)
+}
+
+func (s *series) pipeline(interval time.Duration, start, end time.Time,
+	dest string) []*bson.M {
+
+	groupId := bson.D{}
+	for _, key := range s.Keys {
+		groupId = append(groupId, bson.E{key, "$" + key})
+	}
+	groupId = append(groupId, bson.E{"t", bucketExpr(interval)})
+
+	prefix := "$"
+	if s.Array != "" {
+		prefix = "$" + s.Array + "."
+		groupId = append(groupId, bson.E{s.ArrayKey, prefix + s.ArrayKey})
+	}
+
+	group := bson.M{
+		"_id": groupId,
+	}
+	for _, field := range s.Fields {
+		group[field.Key] = &bson.D{
+			{field.Op, prefix + field.Key},
+		}
+	}
+
+	pipeline := []*bson.M{
+		&bson.M{
+			"$match": &bson.M{
+				"t": &bson.M{
+					"$gte": start,
+					"$lt":  end,
+				},
+			},
+		},
+	}
+
+	if s.Array != "" {
+		pipeline = append(pipeline, &bson.M{
+			"$unwind": "$" + s.Array,
+		})
+	}
+
+	pipeline = append(pipeline, &bson.M{
+		"$group": group,
+	})
+
+	if s.Array != "" {
+		outerId := bson.D{}
+		for _, key := range s.Keys {
+			outerId = append(outerId, bson.E{key, "$_id." + key})
+		}
+		outerId = append(outerId, bson.E{"t", "$_id.t"})
+
+		item := bson.D{
+			{s.ArrayKey, "$_id." + s.ArrayKey},
+		}
+		for _, field := range s.Fields {
+			item = append(item, bson.E{field.Key, "$" + field.Key})
+		}
+
+		pipeline = append(pipeline,
+			&bson.M{
+				"$sort": &bson.M{
+					"_id": 1,
+				},
+			},
+			&bson.M{
+				"$group": &bson.M{
+					"_id": outerId,
+					s.Array: &bson.M{
+						"$push": item,
+					},
+				},
+			},
+		)
+	}
+
+	set := bson.M{
+		"t": &bson.M{
+			"$toDate": "$_id.t",
+		},
+	}
+	for _, key := range s.Keys {
+		set[key] = "$_id." + key
+	}
+
+	pipeline = append(pipeline,
+		&bson.M{
+			"$set": set,
+		},
+		&bson.M{
+			"$merge": &bson.M{
+				"into":           dest,
+				"whenMatched":    "replace",
+				"whenNotMatched": "insert",
+			},
+		},
+	)
+
+	return pipeline
+}
+
+func (s *series) rollup(db *database.Database,
+	src, dest *database.Collection, interval, lookback time.Duration,
+	end time.Time) (err error) {
+
+	start := time.Time{}
+
+	err = dest.FindOne(
+		db,
+		&bson.M{},
+		options.FindOne().SetProjection(bson.D{{"_id", 1}}),
+	).Err()
+	if err != nil {
+		err = database.ParseError(err)
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+		} else {
+			return
+		}
+	} else {
+		start = end.Add(-lookback).Truncate(interval)
+	}
+
+	cursor, err := src.Aggregate(
+		db, s.pipeline(interval, start, end, dest.Name()))
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	_ = cursor.Close(db)
+
+	return
+}
+
+// Raw samples also expire through the collection ttl index, retention
+// longer than the index has no effect
+func (s *series) rawRetention() int {
+	retention := settings.Endpoint.RetentionRaw
+	if s == checkSeries {
+		retention = settings.Endpoint.RetentionCheckRaw
+	}
+
+	if retention <= 0 || retention > s.RawTtl {
+		retention = s.RawTtl
+	}
+
+	return retention
+}
+
+func (s *series) prune(db *database.Database, coll *database.Collection,
+	days int) (err error) {
+
+	if days <= 0 {
+		return
+	}
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"t": &bson.M{
+			"$lt": time.Now().Add(-time.Duration(days) * 24 * time.Hour),
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// Roll raw samples into the 5 minute tier and the 5 minute tier into the
+// 1 hour tier. Only complete 5 minute buckets are written, buckets within
+// the lookback are rewritten on each run to include late samples.
+func Rollup(db *database.Database) (err error) {
+	end := time.Now().UTC().Truncate(Tier5m)
+
+	for _, srs := range allSeries {
+		err = srs.rollup(db, srs.Raw(db), srs.Tier5m(db),
+			Tier5m, Lookback5m, end)
+		if err != nil {
+			return
+		}
+
+		err = srs.rollup(db, srs.Tier5m(db), srs.Tier1h(db),
+			Tier1h, Lookback1h, end)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+func Prune(db *database.Database) (err error) {
+	for _, srs := range allSeries {
+		err = srs.prune(db, srs.Raw(db), srs.rawRetention())
+		if err != nil {
+			return
+		}
+
+		err = srs.prune(db, srs.Tier5m(db), settings.Endpoint.Retention5m)
+		if err != nil {
+			return
+		}
+
+		err = srs.prune(db, srs.Tier1h(db), settings.Endpoint.Retention1h)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,274 @@
+package endpoints
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/alert"
+	"github.com/pritunl/pritunl-zero/database"
+)
+
+type endpointCores struct {
+	Data struct {
+		CpuCores int `bson:"cpu_cores"`
+	} `bson:"data"`
+}
+
+func findSamples(db *database.Database, coll *database.Collection,
+	endpoint bson.ObjectID, start, end time.Time,
+	decode func(*mongo.Cursor) error) (err error) {
+
+	cursor, err := coll.Find(
+		db,
+		bson.M{
+			"e": endpoint,
+			"t": bson.D{
+				{"$gt", start},
+				{"$lte", end},
+			},
+		},
+		options.Find().
+			SetSort(bson.D{{"t", 1}}),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		err = decode(cursor)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func getCpuCores(db *database.Database, endpoint bson.ObjectID) (
+	cores int, err error) {
+
+	coll := db.Endpoints()
+	endpt := &endpointCores{}
+
+	err = coll.FindOne(
+		db,
+		&bson.M{
+			"_id": endpoint,
+		},
+		options.FindOne().
+			SetProjection(bson.D{{"data.cpu_cores", 1}}),
+	).Decode(endpt)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	cores = endpt.Data.CpuCores
+	if cores < 1 {
+		cores = 1
+	}
+
+	return
+}
+
+func getSystemSamples(db *database.Database, endpoint bson.ObjectID,
+	start, end time.Time) (docs []*System, err error) {
+
+	docs = []*System{}
+
+	err = findSamples(db, db.EndpointsSystem(), endpoint, start, end,
+		func(cursor *mongo.Cursor) (err error) {
+			doc := &System{}
+			err = cursor.Decode(doc)
+			if err != nil {
+				return
+			}
+			docs = append(docs, doc)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func systemSamples(docs []*System,
+	value func(*System) float64) (samples []*alert.Sample) {
+
+	samples = make([]*alert.Sample, 0, len(docs))
+	for _, doc := range docs {
+		samples = append(samples, &alert.Sample{
+			Timestamp: doc.Timestamp,
+			Value:     value(doc),
+		})
+	}
+
+	return
+}
+
+func getDiskSamples(db *database.Database, endpoint bson.ObjectID,
+	start, end time.Time) (docs []*Disk, err error) {
+
+	docs = []*Disk{}
+
+	err = findSamples(db, db.EndpointsDisk(), endpoint, start, end,
+		func(cursor *mongo.Cursor) (err error) {
+			doc := &Disk{}
+			err = cursor.Decode(doc)
+			if err != nil {
+				return
+			}
+			docs = append(docs, doc)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func diskSamples(docs []*Disk, path string) (samples []*alert.Sample) {
+	samples = []*alert.Sample{}
+	for _, doc := range docs {
+		for _, mount := range doc.Mounts {
+			if mount.Path == path {
+				samples = append(samples, &alert.Sample{
+					Timestamp: doc.Timestamp,
+					Value:     mount.Used,
+				})
+				break
+			}
+		}
+	}
+
+	return
+}
+
+func getLoadSamples(db *database.Database, endpoint bson.ObjectID,
+	start, end time.Time) (docs []*Load, err error) {
+
+	docs = []*Load{}
+
+	err = findSamples(db, db.EndpointsLoad(), endpoint, start, end,
+		func(cursor *mongo.Cursor) (err error) {
+			doc := &Load{}
+			err = cursor.Decode(doc)
+			if err != nil {
+				return
+			}
+			docs = append(docs, doc)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func loadSamples(docs []*Load, cores int) (samples []*alert.Sample) {
+	samples = make([]*alert.Sample, 0, len(docs))
+	for _, doc := range docs {
+		samples = append(samples, &alert.Sample{
+			Timestamp: doc.Timestamp,
+			Value:     doc.Load1 / float64(cores) * 100,
+		})
+	}
+
+	return
+}
+
+func getNetworkSamples(db *database.Database, endpoint bson.ObjectID,
+	start, end time.Time) (docs []*Network, err error) {
+
+	docs = []*Network{}
+
+	err = findSamples(db, db.EndpointsNetwork(), endpoint, start, end,
+		func(cursor *mongo.Cursor) (err error) {
+			doc := &Network{}
+			err = cursor.Decode(doc)
+			if err != nil {
+				return
+			}
+			docs = append(docs, doc)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func networkSamples(docs []*Network, name string,
+	value func(*Interface) float64) (samples []*alert.Sample) {
+
+	samples = []*alert.Sample{}
+	for _, doc := range docs {
+		for _, iface := range doc.Interfaces {
+			if iface.Name == name {
+				samples = append(samples, &alert.Sample{
+					Timestamp: doc.Timestamp,
+					Value:     value(iface),
+				})
+				break
+			}
+		}
+	}
+
+	return
+}
+
+func getDiskIoSamples(db *database.Database, endpoint bson.ObjectID,
+	start, end time.Time) (docs []*DiskIo, err error) {
+
+	docs = []*DiskIo{}
+
+	err = findSamples(db, db.EndpointsDiskIo(), endpoint, start, end,
+		func(cursor *mongo.Cursor) (err error) {
+			doc := &DiskIo{}
+			err = cursor.Decode(doc)
+			if err != nil {
+				return
+			}
+			docs = append(docs, doc)
+			return
+		})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func diskIoSamples(docs []*DiskIo, name string,
+	value func(*DiskIoDisk) float64) (samples []*alert.Sample) {
+
+	samples = []*alert.Sample{}
+	for _, doc := range docs {
+		for _, dsk := range doc.Disks {
+			if dsk.Name == name {
+				samples = append(samples, &alert.Sample{
+					Timestamp: doc.Timestamp,
+					Value:     value(dsk),
+				})
+				break
+			}
+		}
+	}
+
+	return
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/dropbox/godropbox/container/set"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/alert"
@@ -78,62 +79,122 @@ func (d *System) StaticData() *bson.M {
 	}
 }
 
-func (d *System) CheckAlerts(resources []*alert.Alert) (alerts []*Alert) {
+func (d *System) CheckAlerts(db *database.Database,
+	resources []*alert.Alert, firing set.Set) (alerts []*Alert,
+	err error) {
+
 	alerts = []*Alert{}
 
+	docs := []*System{d}
+	window := alert.MaxDuration(resources)
+	if window > 0 {
+		docs, err = getSystemSamples(db, d.Endpoint,
+			d.Timestamp.Add(-window), d.Timestamp)
+		if err != nil {
+			return
+		}
+
+		if len(docs) == 0 || !docs[len(docs)-1].Timestamp.Equal(
+			d.Timestamp) {
+
+			docs = append(docs, d)
+		}
+	}
+
 	for _, resource := range resources {
 		switch resource.Resource {
 		case alert.SystemCpuLevel:
-			if d.CpuUsage > float64(resource.ValueInt) {
-				alerts = []*Alert{
+			active, value := resource.Evaluate(systemSamples(docs,
+				func(doc *System) float64 {
+					return doc.CpuUsage
+				}), d.Timestamp, firing.Contains(resource.Id))
+			if active {
+				alerts = append(alerts,
 					NewAlert(resource, fmt.Sprintf(
 						"System cpu high usage (%.2f%%)",
-						d.MemUsage,
+						value,
 					)),
-				}
+				)
 			}
 			break
 		case alert.SystemMemoryLevel:
-			if d.MemUsage > float64(resource.ValueInt) {
-				alerts = []*Alert{
+			active, value := resource.Evaluate(systemSamples(docs,
+				func(doc *System) float64 {
+					return doc.MemUsage
+				}), d.Timestamp, firing.Contains(resource.Id))
+			if active {
+				alerts = append(alerts,
 					NewAlert(resource, fmt.Sprintf(
 						"System low on memory (%.2f%%)",
-						d.MemUsage,
+						value,
 					)),
-				}
+				)
 			}
 			break
 		case alert.SystemSwapLevel:
-			if d.SwapUsage > float64(resource.ValueInt) {
-				alerts = []*Alert{
+			active, value := resource.Evaluate(systemSamples(docs,
+				func(doc *System) float64 {
+					return doc.SwapUsage
+				}), d.Timestamp, firing.Contains(resource.Id))
+			if active {
+				alerts = append(alerts,
 					NewAlert(resource, fmt.Sprintf(
 						"System low on swap (%.2f%%)",
-						d.SwapUsage,
+						value,
 					)),
-				}
+				)
 			}
 			break
 		case alert.SystemHugePagesLevel:
-			if d.SwapUsage > float64(resource.ValueInt) {
-				alerts = []*Alert{
+			active, value := resource.Evaluate(systemSamples(docs,
+				func(doc *System) float64 {
+					return doc.HugeUsage
+				}), d.Timestamp, firing.Contains(resource.Id))
+			if active {
+				alerts = append(alerts,
 					NewAlert(resource, fmt.Sprintf(
 						"System low on hugepages (%.2f%%)",
-						d.SwapUsage,
+						value,
 					)),
-				}
+				)
+			}
+			break
+		case alert.SystemProcesses:
+			active, value := resource.Evaluate(systemSamples(docs,
+				func(doc *System) float64 {
+					return float64(doc.Processes)
+				}), d.Timestamp, firing.Contains(resource.Id))
+			if active {
+				alerts = append(alerts,
+					NewAlert(resource, fmt.Sprintf(
+						"System high process count (%.0f)",
+						value,
+					)),
+				)
+			}
+			break
+		case alert.SystemPackageUpdates:
+			if d.PackageUpdates >= resource.ValueInt {
+				alerts = append(alerts,
+					NewAlert(resource, fmt.Sprintf(
+						"System package updates pending (%d)",
+						d.PackageUpdates,
+					)),
+				)
 			}
 			break
 		case alert.SystemMdFailed:
 			if d.MdStat != nil {
 				for _, md := range d.MdStat {
 					if md.Failed > 0 {
-						alerts = []*Alert{
+						alerts = append(alerts,
 							NewAlert(resource, fmt.Sprintf(
 								"System MD RAID device failed (%s %s)",
 								md.Name,
 								md.Level,
 							)),
-						}
+						)
+						break
 					}
 				}
 			}
@@ -215,7 +276,7 @@ func GetSystemChart(c context.Context, db *database.Database,
 		return
 	}
 
-	coll := db.EndpointsSystem()
+	coll := systemSeries.chartCollection(db, interval)
 	chart := NewChart(start, end, interval)
 
 	timeQuery := bson.D{
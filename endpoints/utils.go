@@ -4,6 +4,7 @@ import (
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/view"
 )
 
 type endpointName struct {
@@ -11,26 +12,27 @@ type endpointName struct {
 	Name string        `bson:"name" json:"name"`
 }
 
-func getRolesName(db *database.Database, roles []string) (
-	endpts []*endpointName, err error) {
+func getTargetsName(db *database.Database, roles []string,
+	views []bson.ObjectID) (endpts []*endpointName, err error) {
 
 	coll := db.Endpoints()
 	endpts = []*endpointName{}
 
-	if roles == nil {
-		roles = []string{}
+	query, err := view.GetTargetsQuery(db, roles, views)
+	if err != nil {
+		return
 	}
 
 	cursor, err := coll.Find(
 		db,
-		bson.M{
-			"roles": bson.M{
-				"$in": roles,
-			},
-		},
+		query,
 		options.Find().
 			SetProjection(bson.D{{"name", 1}}),
 	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
 	defer cursor.Close(db)
 
 	for cursor.Next(db) {
@@ -53,12 +55,12 @@ func getRolesName(db *database.Database, roles []string) (
 	return
 }
 
-func getRolesNameMapped(db *database.Database, roles []string) (
-	endptsMap map[bson.ObjectID]string, err error) {
+func getTargetsNameMapped(db *database.Database, roles []string,
+	views []bson.ObjectID) (endptsMap map[bson.ObjectID]string, err error) {
 
 	endptsMap = map[bson.ObjectID]string{}
 
-	endpts, err := getRolesName(db, roles)
+	endpts, err := getTargetsName(db, roles, views)
 	if err != nil {
 		return
 	}
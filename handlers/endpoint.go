@@ -8,6 +8,8 @@ import (
 	"github.com/dropbox/godropbox/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/command"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/demo"
 	"github.com/pritunl/pritunl-zero/endpoint"
@@ -153,6 +155,15 @@ func EndpointCommGet(c *gin.Context) {
 		return
 	})
 
+	runs := make(chan bson.ObjectID, 10)
+	listenerId := command.Register(endpointId, func(runId bson.ObjectID) {
+		select {
+		case runs <- runId:
+		case <-ctx.Done():
+		}
+	})
+	defer command.Unregister(endpointId, listenerId)
+
 	ticker := time.NewTicker(endpointPingInterval)
 	socket.Ticker = ticker
 
@@ -192,6 +203,48 @@ func EndpointCommGet(c *gin.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case runId := <-runs:
+			run, e := command.GetRun(db, runId)
+			if e != nil {
+				logrus.WithFields(logrus.Fields{
+					"run_id": runId.Hex(),
+					"error":  e,
+				}).Error("mhandlers: Failed to get endpoint command run")
+				continue
+			}
+
+			if run.State != command.Running || !run.HasEndpoint(endpointId) {
+				continue
+			}
+
+			encConf, e := endpt.GetConf(db, []*command.Request{
+				run.Request(),
+			})
+			if e != nil {
+				logrus.WithFields(logrus.Fields{
+					"error": e,
+				}).Error("mhandlers: Failed to get endpoint command conf")
+
+				_ = conn.Close()
+				return
+			}
+
+			if encConf == nil {
+				continue
+			}
+
+			err = conn.SetWriteDeadline(
+				time.Now().Add(endpointWriteTimeout))
+			if err != nil {
+				_ = conn.Close()
+				return
+			}
+
+			err = conn.WriteMessage(websocket.TextMessage, encConf)
+			if err != nil {
+				_ = conn.Close()
+				return
+			}
 		case <-ticker.C:
 			err = conn.WriteControl(websocket.PingMessage, []byte{},
 				time.Now().Add(endpointWriteTimeout))
@@ -218,7 +271,7 @@ func EndpointCommGet(c *gin.Context) {
 				endpt = newEndpt
 				endptUpdate = time.Now()
 
-				encConf, e := endpt.GetConf(db)
+				encConf, e := endpt.GetConf(db, nil)
 				if e != nil {
 					logrus.WithFields(logrus.Fields{
 						"error": e,
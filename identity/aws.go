@@ -0,0 +1,99 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+type awsDocument struct {
+	AccountId        string `json:"accountId"`
+	Region           string `json:"region"`
+	AvailabilityZone string `json:"availabilityZone"`
+	InstanceId       string `json:"instanceId"`
+	PrivateIp        string `json:"privateIp"`
+}
+
+// Verifies the instance identity document from the instance metadata
+// service using the base64 RSA SHA256 signature and the regional
+// AWS public certificates. The document does not change for the life of
+// the instance and must be bound to the host key with Bind.
+type AwsVerifier struct {
+	Certificates []*x509.Certificate
+	Now          func() time.Time
+}
+
+func (v *AwsVerifier) Verify(doc *Document, hostname, pubKey string) (
+	ident *Identity, err error) {
+
+	sig, err := base64.StdEncoding.DecodeString(
+		strings.Join(strings.Fields(doc.Signature), ""))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to decode aws signature"),
+		}
+		return
+	}
+
+	hash := sha256.Sum256([]byte(doc.Document))
+	curTime := now(v.Now)
+
+	valid := false
+	for _, cert := range v.Certificates {
+		if curTime.After(cert.NotAfter) {
+			continue
+		}
+
+		pubKey, ok := cert.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+
+		e := rsa.VerifyPKCS1v15(pubKey, crypto.SHA256, hash[:], sig)
+		if e == nil {
+			valid = true
+			break
+		}
+	}
+
+	if !valid {
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Invalid aws document signature"),
+		}
+		return
+	}
+
+	data := &awsDocument{}
+	err = json.Unmarshal([]byte(doc.Document), data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to parse aws document"),
+		}
+		return
+	}
+
+	if data.AccountId == "" || data.InstanceId == "" {
+		err = &errortypes.ParseError{
+			errors.New("identity: Incomplete aws document"),
+		}
+		return
+	}
+
+	ident = &Identity{
+		Provider:   Aws,
+		Account:    data.AccountId,
+		Region:     data.Region,
+		Zone:       data.AvailabilityZone,
+		InstanceId: data.InstanceId,
+	}
+
+	return
+}
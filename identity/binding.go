@@ -0,0 +1,77 @@
+package identity
+
+import (
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+type binding struct {
+	Id        string    `bson:"_id"`
+	Hostname  string    `bson:"hostname"`
+	PublicKey string    `bson:"public_key"`
+	Timestamp time.Time `bson:"timestamp"`
+}
+
+// Binds the instance to the hostname and public key of the first verified
+// request, a leaked document without a freshness claim cannot be replayed
+// to obtain a certificate for another key
+func Bind(db *database.Database, ident *Identity, hostname,
+	pubKey string) (err error) {
+
+	coll := db.HostIdentities()
+	pubKey = strings.TrimSpace(pubKey)
+
+	bind := &binding{
+		Id: ident.Provider + ":" + ident.Account + ":" +
+			ident.InstanceId,
+		Hostname:  hostname,
+		PublicKey: pubKey,
+		Timestamp: time.Now(),
+	}
+
+	_, err = coll.InsertOne(db, bind)
+	if err == nil {
+		return
+	}
+
+	err = database.ParseError(err)
+	if _, ok := err.(*database.DuplicateKeyError); !ok {
+		return
+	}
+	err = nil
+
+	existing := &binding{}
+	err = coll.FindOneId(bind.Id, existing)
+	if err != nil {
+		return
+	}
+
+	if existing.Hostname != hostname || subtle.ConstantTimeCompare(
+		[]byte(existing.PublicKey), []byte(pubKey)) != 1 {
+
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Instance bound to another host key"),
+		}
+		return
+	}
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id": bind.Id,
+	}, &bson.M{
+		"$set": &bson.M{
+			"timestamp": bind.Timestamp,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,9 @@
+package identity
+
+const (
+	Aws    = "aws"
+	Google = "google"
+	Oracle = "oracle"
+
+	GoogleCertsUrl = "https://www.googleapis.com/oauth2/v1/certs"
+)
@@ -0,0 +1,294 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	client = &http.Client{
+		Timeout: 10 * time.Second,
+	}
+	googleKeys          = map[string]*rsa.PublicKey{}
+	googleKeysTimestamp time.Time
+	googleKeysLock      = sync.Mutex{}
+)
+
+type googleHeader struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+type googleComputeEngine struct {
+	ProjectId    string `json:"project_id"`
+	Zone         string `json:"zone"`
+	InstanceId   string `json:"instance_id"`
+	InstanceName string `json:"instance_name"`
+}
+
+type googleClaims struct {
+	Issuer   string `json:"iss"`
+	Audience string `json:"aud"`
+	Expires  int64  `json:"exp"`
+	IssuedAt int64  `json:"iat"`
+	Google   struct {
+		ComputeEngine googleComputeEngine `json:"compute_engine"`
+	} `json:"google"`
+}
+
+// Verifies the instance identity token from the metadata server requested
+// with format=full. The token audience must be the configured audience
+// followed by # and the SHA256 fingerprint of the host public key to bind
+// the token to the key. When Keys is nil the Google OAuth2 certificates
+// are fetched and cached.
+type GoogleVerifier struct {
+	Audience string
+	Keys     map[string]*rsa.PublicKey
+	Now      func() time.Time
+}
+
+func (v *GoogleVerifier) getKey(keyId string) (
+	pubKey *rsa.PublicKey, err error) {
+
+	if v.Keys != nil {
+		pubKey = v.Keys[keyId]
+		return
+	}
+
+	googleKeysLock.Lock()
+	defer googleKeysLock.Unlock()
+
+	pubKey = googleKeys[keyId]
+	if pubKey != nil && time.Since(googleKeysTimestamp) < 1*time.Hour {
+		return
+	}
+
+	resp, err := client.Get(GoogleCertsUrl)
+	if err != nil {
+		err = &errortypes.RequestError{
+			errors.Wrap(err, "identity: Failed to request google certs"),
+		}
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		err = &errortypes.RequestError{
+			errors.Newf("identity: Google certs bad status %d",
+				resp.StatusCode),
+		}
+		return
+	}
+
+	certsData := map[string]string{}
+	err = json.NewDecoder(resp.Body).Decode(&certsData)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to parse google certs"),
+		}
+		return
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for kid, certPem := range certsData {
+		certs, e := ParseCertificates(certPem)
+		if e != nil {
+			err = e
+			return
+		}
+
+		key, ok := certs[0].PublicKey.(*rsa.PublicKey)
+		if ok {
+			keys[kid] = key
+		}
+	}
+
+	googleKeys = keys
+	googleKeysTimestamp = time.Now()
+	pubKey = googleKeys[keyId]
+
+	return
+}
+
+func (v *GoogleVerifier) Verify(doc *Document, hostname, pubKey string) (
+	ident *Identity, err error) {
+
+	parts := strings.Split(strings.TrimSpace(doc.Document), ".")
+	if len(parts) != 3 {
+		err = &errortypes.ParseError{
+			errors.New("identity: Invalid google token format"),
+		}
+		return
+	}
+
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to decode google header"),
+		}
+		return
+	}
+
+	claimsData, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to decode google claims"),
+		}
+		return
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to decode google signature"),
+		}
+		return
+	}
+
+	header := &googleHeader{}
+	err = json.Unmarshal(headerData, header)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to parse google header"),
+		}
+		return
+	}
+
+	if header.Algorithm != "RS256" {
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Invalid google token algorithm"),
+		}
+		return
+	}
+
+	key, err := v.getKey(header.KeyId)
+	if err != nil {
+		return
+	}
+
+	if key == nil {
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Unknown google token key"),
+		}
+		return
+	}
+
+	hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	err = rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig)
+	if err != nil {
+		err = &errortypes.AuthenticationError{
+			errors.Wrap(err, "identity: Invalid google token signature"),
+		}
+		return
+	}
+
+	claims := &googleClaims{}
+	err = json.Unmarshal(claimsData, claims)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to parse google claims"),
+		}
+		return
+	}
+
+	if claims.Issuer != "https://accounts.google.com" &&
+		claims.Issuer != "accounts.google.com" {
+
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Invalid google token issuer"),
+		}
+		return
+	}
+
+	if v.Audience == "" {
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Invalid google token audience"),
+		}
+		return
+	}
+
+	audience, err := GoogleAudience(v.Audience, pubKey)
+	if err != nil {
+		return
+	}
+
+	if subtle.ConstantTimeCompare(
+		[]byte(claims.Audience), []byte(audience)) != 1 {
+
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Invalid google token audience"),
+		}
+		return
+	}
+
+	curTime := now(v.Now)
+	if curTime.After(time.Unix(claims.Expires, 0)) ||
+		curTime.Add(1*time.Minute).Before(time.Unix(claims.IssuedAt, 0)) {
+
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Google token expired"),
+		}
+		return
+	}
+
+	engine := claims.Google.ComputeEngine
+	if engine.ProjectId == "" || engine.InstanceId == "" {
+		err = &errortypes.ParseError{
+			errors.New("identity: Google token missing instance info"),
+		}
+		return
+	}
+
+	if engine.InstanceName == "" || (hostname != engine.InstanceName &&
+		!strings.HasPrefix(hostname, engine.InstanceName+".")) {
+
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Google instance name mismatch"),
+		}
+		return
+	}
+
+	zone := engine.Zone
+	region := zone
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		region = zone[:i]
+	}
+
+	ident = &Identity{
+		Provider:     Google,
+		Account:      engine.ProjectId,
+		Region:       region,
+		Zone:         zone,
+		InstanceId:   engine.InstanceId,
+		InstanceName: engine.InstanceName,
+	}
+
+	return
+}
+
+// Audience the host requests the identity token with to bind the token to
+// the host public key
+func GoogleAudience(audience, pubKey string) (aud string, err error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubKey))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to parse host public key"),
+		}
+		return
+	}
+
+	aud = audience + "#" + ssh.FingerprintSHA256(key)
+	return
+}
@@ -0,0 +1,169 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const googleTestAudience = "https://zero.example.com"
+
+func googleTestHostKey(t *testing.T) string {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+}
+
+func googleTestToken(t *testing.T, key *rsa.PrivateKey, keyId string,
+	claims *googleClaims) string {
+
+	headerData, err := json.Marshal(&googleHeader{
+		Algorithm: "RS256",
+		KeyId:     keyId,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claimsData, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(headerData) + "." +
+		base64.RawURLEncoding.EncodeToString(claimsData)
+
+	hash := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestGoogleVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostKey := googleTestHostKey(t)
+	otherKey := googleTestHostKey(t)
+
+	audience, err := GoogleAudience(googleTestAudience, hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issued := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newClaims := func() *googleClaims {
+		claims := &googleClaims{
+			Issuer:   "https://accounts.google.com",
+			Audience: audience,
+			Expires:  issued.Add(1 * time.Hour).Unix(),
+			IssuedAt: issued.Unix(),
+		}
+		claims.Google.ComputeEngine = googleComputeEngine{
+			ProjectId:    "zero-project",
+			Zone:         "us-central1-a",
+			InstanceId:   "1234567890",
+			InstanceName: "web-1",
+		}
+		return claims
+	}
+
+	verifier := &GoogleVerifier{
+		Audience: googleTestAudience,
+		Keys: map[string]*rsa.PublicKey{
+			"test": &key.PublicKey,
+		},
+		Now: func() time.Time {
+			return issued.Add(5 * time.Minute)
+		},
+	}
+
+	doc := &Document{
+		Type:     Google,
+		Document: googleTestToken(t, key, "test", newClaims()),
+	}
+
+	ident, err := verifier.Verify(doc, "web-1.example.com", hostKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ident.Account != "zero-project" || ident.InstanceId != "1234567890" ||
+		ident.Region != "us-central1" || ident.Zone != "us-central1-a" {
+
+		t.Fatalf("unexpected identity %#v", ident)
+	}
+
+	_, err = verifier.Verify(doc, "web-1.example.com", otherKey)
+	if err == nil {
+		t.Fatal("token accepted for another host key")
+	}
+
+	_, err = verifier.Verify(doc, "db-1.example.com", hostKey)
+	if err == nil {
+		t.Fatal("token accepted for another hostname")
+	}
+
+	claims := newClaims()
+	claims.Audience = googleTestAudience
+	unbound := &Document{
+		Type:     Google,
+		Document: googleTestToken(t, key, "test", claims),
+	}
+
+	_, err = verifier.Verify(unbound, "web-1.example.com", hostKey)
+	if err == nil {
+		t.Fatal("token accepted without host key binding")
+	}
+
+	expired := &GoogleVerifier{
+		Audience: verifier.Audience,
+		Keys:     verifier.Keys,
+		Now: func() time.Time {
+			return issued.Add(2 * time.Hour)
+		},
+	}
+
+	_, err = expired.Verify(doc, "web-1.example.com", hostKey)
+	if err == nil {
+		t.Fatal("expired token accepted")
+	}
+
+	otherSigner, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := &Document{
+		Type:     Google,
+		Document: googleTestToken(t, otherSigner, "test", newClaims()),
+	}
+
+	_, err = verifier.Verify(forged, "web-1.example.com", hostKey)
+	if err == nil {
+		t.Fatal("token with invalid signature accepted")
+	}
+}
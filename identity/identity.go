@@ -0,0 +1,113 @@
+package identity
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+type Document struct {
+	Type        string `json:"type"`
+	Document    string `json:"document"`
+	Signature   string `json:"signature"`
+	Certificate string `json:"certificate"`
+}
+
+type Identity struct {
+	Provider     string `json:"provider"`
+	Account      string `json:"account"`
+	Region       string `json:"region"`
+	Zone         string `json:"zone"`
+	InstanceId   string `json:"instance_id"`
+	InstanceName string `json:"instance_name"`
+}
+
+type Verifier interface {
+	Verify(doc *Document, hostname, pubKey string) (
+		ident *Identity, err error)
+}
+
+func (i *Identity) Expand(pattern string) string {
+	return strings.NewReplacer(
+		"{account}", i.Account,
+		"{region}", i.Region,
+		"{zone}", i.Zone,
+		"{instance_id}", i.InstanceId,
+		"{instance_name}", i.InstanceName,
+	).Replace(pattern)
+}
+
+func (i *Identity) Match(hostname string, accounts,
+	patterns []string) bool {
+
+	found := false
+	for _, account := range accounts {
+		if account == i.Account {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return false
+	}
+
+	for _, pattern := range patterns {
+		if utils.Match(i.Expand(pattern), hostname) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func New(provider, certs, audience string) (
+	verifier Verifier, err error) {
+
+	switch provider {
+	case Aws:
+		certificates, e := ParseCertificates(certs)
+		if e != nil {
+			err = e
+			return
+		}
+
+		verifier = &AwsVerifier{
+			Certificates: certificates,
+		}
+		break
+	case Google:
+		verifier = &GoogleVerifier{
+			Audience: audience,
+		}
+		break
+	case Oracle:
+		certificates, e := ParseCertificates(certs)
+		if e != nil {
+			err = e
+			return
+		}
+
+		verifier = &OracleVerifier{
+			Roots: certificates,
+		}
+		break
+	default:
+		err = &errortypes.UnknownError{
+			errors.New("identity: Unknown identity provider"),
+		}
+		return
+	}
+
+	return
+}
+
+func now(nowFunc func() time.Time) time.Time {
+	if nowFunc != nil {
+		return nowFunc()
+	}
+	return time.Now()
+}
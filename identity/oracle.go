@@ -0,0 +1,162 @@
+package identity
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+type oracleDocument struct {
+	Hostname  string `json:"hostname"`
+	PublicKey string `json:"public_key"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Verifies an instance principal signed document. The host signs a JSON
+// document containing the hostname, ssh public key and timestamp with the
+// instance principal key from the metadata service and includes the leaf
+// and intermediate certificates which are verified against the
+// configured Oracle Cloud root certificates.
+type OracleVerifier struct {
+	Roots  []*x509.Certificate
+	Window time.Duration
+	Now    func() time.Time
+}
+
+func (v *OracleVerifier) Verify(doc *Document, hostname, pubKey string) (
+	ident *Identity, err error) {
+
+	chain, err := ParseCertificates(doc.Certificate)
+	if err != nil {
+		return
+	}
+
+	curTime := now(v.Now)
+
+	roots := x509.NewCertPool()
+	for _, cert := range v.Roots {
+		roots.AddCert(cert)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	leaf := chain[0]
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   curTime,
+		KeyUsages: []x509.ExtKeyUsage{
+			x509.ExtKeyUsageAny,
+		},
+	})
+	if err != nil {
+		err = &errortypes.AuthenticationError{
+			errors.Wrap(err, "identity: Invalid oracle certificate chain"),
+		}
+		return
+	}
+
+	leafKey, ok := leaf.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Invalid oracle certificate key type"),
+		}
+		return
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(
+		strings.Join(strings.Fields(doc.Signature), ""))
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to decode oracle signature"),
+		}
+		return
+	}
+
+	hash := sha256.Sum256([]byte(doc.Document))
+	err = rsa.VerifyPKCS1v15(leafKey, crypto.SHA256, hash[:], sig)
+	if err != nil {
+		err = &errortypes.AuthenticationError{
+			errors.Wrap(err, "identity: Invalid oracle document signature"),
+		}
+		return
+	}
+
+	data := &oracleDocument{}
+	err = json.Unmarshal([]byte(doc.Document), data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "identity: Failed to parse oracle document"),
+		}
+		return
+	}
+
+	window := v.Window
+	if window == 0 {
+		window = 5 * time.Minute
+	}
+
+	timestamp := time.Unix(data.Timestamp, 0)
+	if curTime.Sub(timestamp) > window || timestamp.Sub(curTime) > window {
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Oracle document timestamp outside window"),
+		}
+		return
+	}
+
+	if data.Hostname != hostname || subtle.ConstantTimeCompare(
+		[]byte(strings.TrimSpace(data.PublicKey)),
+		[]byte(strings.TrimSpace(pubKey))) != 1 {
+
+		err = &errortypes.AuthenticationError{
+			errors.New("identity: Oracle document does not match request"),
+		}
+		return
+	}
+
+	ident = &Identity{
+		Provider: Oracle,
+	}
+
+	for _, unit := range leaf.Subject.OrganizationalUnit {
+		parts := strings.SplitN(unit, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case "opc-tenant":
+			ident.Account = parts[1]
+			break
+		case "opc-instance":
+			ident.InstanceId = parts[1]
+			break
+		}
+	}
+
+	if ident.Account == "" || ident.InstanceId == "" {
+		err = &errortypes.ParseError{
+			errors.New("identity: Oracle certificate missing instance info"),
+		}
+		ident = nil
+		return
+	}
+
+	if parts := strings.Split(ident.InstanceId, "."); len(parts) > 3 {
+		ident.Region = parts[3]
+	}
+
+	return
+}
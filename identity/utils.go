@@ -0,0 +1,48 @@
+package identity
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+func ParseCertificates(data string) (
+	certs []*x509.Certificate, err error) {
+
+	certs = []*x509.Certificate{}
+	rest := []byte(strings.TrimSpace(data))
+
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, e := x509.ParseCertificate(block.Bytes)
+		if e != nil {
+			err = &errortypes.ParseError{
+				errors.Wrap(e, "identity: Failed to parse certificate"),
+			}
+			return
+		}
+
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		err = &errortypes.ParseError{
+			errors.New("identity: No certificates found"),
+		}
+		return
+	}
+
+	return
+}
@@ -0,0 +1,352 @@
+package lockout
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/pritunl/pritunl-zero/user"
+	"github.com/sirupsen/logrus"
+)
+
+type backoff struct {
+	Id        string    `bson:"_id"`
+	Failures  int       `bson:"n"`
+	Timestamp time.Time `bson:"t"`
+	Until     time.Time `bson:"u"`
+}
+
+// Check the exponential backoff for the source address of the request
+func CheckAddr(db *database.Database, r *http.Request) (
+	errData *errortypes.ErrorData, err error) {
+
+	coll := db.AuthBackoff()
+	bckf := &backoff{}
+
+	err = coll.FindOneId(node.Self.GetRemoteAddr(r), bckf)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+		}
+		return
+	}
+
+	wait := time.Until(bckf.Until)
+	if wait > 0 {
+		errData = &errortypes.ErrorData{
+			Error: "auth_backoff",
+			Message: fmt.Sprintf(
+				"Too many failed attempts, retry in %d seconds",
+				int(math.Ceil(wait.Seconds()))),
+		}
+		return
+	}
+
+	return
+}
+
+// Check the user lock, locked users receive the generic invalid
+// credentials error to prevent disclosing the lock state
+func CheckUser(usr *user.User) (errData *errortypes.ErrorData) {
+	if usr.IsLocked() {
+		logrus.WithFields(logrus.Fields{
+			"user_id":      usr.Id.Hex(),
+			"username":     usr.Username,
+			"locked_until": usr.LockedUntil,
+		}).Warn("lockout: Authentication attempt for locked user")
+
+		errData = &errortypes.ErrorData{
+			Error:   "auth_invalid",
+			Message: "Authentication credentials are invalid",
+		}
+		return
+	}
+
+	return
+}
+
+// Record a failed attempt for the source address and user, the user is
+// locked after the configured failures within the lockout window
+func Failed(db *database.Database, r *http.Request, usr *user.User) (
+	err error) {
+
+	err = failedAddr(db, r)
+	if err != nil {
+		return
+	}
+
+	if usr == nil || settings.Auth.DisableLockout {
+		return
+	}
+
+	err = failedUser(db, r, usr)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func failedAddr(db *database.Database, r *http.Request) (err error) {
+	coll := db.AuthBackoff()
+	now := time.Now()
+	windowStart := now.Add(
+		-time.Duration(settings.Auth.LockoutWindow) * time.Second)
+
+	// Failures outside of the lockout window are reset, the backoff
+	// doubles with each failure up to the maximum
+	failures := &bson.M{
+		"$cond": []interface{}{
+			&bson.M{
+				"$lt": []interface{}{"$t", windowStart},
+			},
+			1,
+			&bson.M{
+				"$add": []interface{}{"$n", 1},
+			},
+		},
+	}
+	delay := &bson.M{
+		"$min": []interface{}{
+			&bson.M{
+				"$multiply": []interface{}{
+					settings.Auth.BackoffBase * 1000,
+					&bson.M{
+						"$pow": []interface{}{
+							2,
+							&bson.M{
+								"$min": []interface{}{
+									&bson.M{
+										"$subtract": []interface{}{"$n", 1},
+									},
+									30,
+								},
+							},
+						},
+					},
+				},
+			},
+			settings.Auth.BackoffMax * 1000,
+		},
+	}
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id": node.Self.GetRemoteAddr(r),
+	}, []*bson.M{
+		&bson.M{
+			"$set": &bson.M{
+				"n": failures,
+				"t": now,
+			},
+		},
+		&bson.M{
+			"$set": &bson.M{
+				"u": &bson.M{
+					"$add": []interface{}{now, delay},
+				},
+			},
+		},
+	}, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func failedUser(db *database.Database, r *http.Request, usr *user.User) (
+	err error) {
+
+	coll := db.Users()
+	now := time.Now()
+	windowStart := now.Add(
+		-time.Duration(settings.Auth.LockoutWindow) * time.Second)
+
+	_, err = coll.UpdateOne(db, &bson.M{
+		"_id": usr.Id,
+		"login_failure_start": &bson.M{
+			"$not": &bson.M{
+				"$gte": windowStart,
+			},
+		},
+	}, &bson.M{
+		"$set": &bson.M{
+			"login_failures":      0,
+			"login_failure_start": now,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	updated := &user.User{}
+	err = coll.FindOneAndUpdate(
+		db,
+		&bson.M{
+			"_id": usr.Id,
+		},
+		&bson.M{
+			"$inc": &bson.M{
+				"login_failures": 1,
+			},
+		},
+		options.FindOneAndUpdate().
+			SetReturnDocument(options.After),
+	).Decode(updated)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	usr.LoginFailures = updated.LoginFailures
+	usr.LoginFailureStart = updated.LoginFailureStart
+
+	if usr.LoginFailures < settings.Auth.LockoutAttempts {
+		return
+	}
+
+	lockedUntil := time.Time{}
+	if !settings.Auth.LockoutPermanent {
+		lockedUntil = now.Add(
+			time.Duration(settings.Auth.LockoutDuration) * time.Second)
+	}
+
+	resp, err := coll.UpdateOne(db, &bson.M{
+		"_id": usr.Id,
+		"$or": []*bson.M{
+			&bson.M{
+				"locked": &bson.M{
+					"$ne": true,
+				},
+			},
+			&bson.M{
+				"locked_until": &bson.M{
+					"$gt": time.Time{},
+					"$lt": now,
+				},
+			},
+		},
+	}, &bson.M{
+		"$set": &bson.M{
+			"locked":         true,
+			"locked_until":   lockedUntil,
+			"login_failures": 0,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	if resp.ModifiedCount == 0 {
+		return
+	}
+
+	usr.Locked = true
+	usr.LockedUntil = lockedUntil
+	usr.LoginFailures = 0
+
+	err = audit.New(
+		db,
+		r,
+		usr.Id,
+		audit.UserLocked,
+		audit.Fields{
+			"method":       "failures",
+			"failures":     settings.Auth.LockoutAttempts,
+			"locked_until": lockedUntil,
+		},
+	)
+	if err != nil {
+		return
+	}
+
+	_ = event.PublishDispatch(db, "user.change")
+
+	return
+}
+
+// Clear the user failures after a successful authentication, the source
+// address backoff is left to expire with the lockout window so a success
+// on one account does not reset failures against another
+func Success(db *database.Database, r *http.Request, usr *user.User) (
+	err error) {
+
+	if usr == nil || (usr.LoginFailures == 0 && !usr.Locked) {
+		return
+	}
+
+	_, err = db.Users().UpdateOne(db, &bson.M{
+		"_id": usr.Id,
+	}, &bson.M{
+		"$set": &bson.M{
+			"locked":         false,
+			"locked_until":   time.Time{},
+			"login_failures": 0,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	usr.Locked = false
+	usr.LockedUntil = time.Time{}
+	usr.LoginFailures = 0
+
+	return
+}
+
+// Lock or unlock a user from the admin console, locks set by an
+// administrator remain until unlocked
+func SetLocked(db *database.Database, r *http.Request, usr *user.User,
+	adminUsr *user.User, locked bool) (err error) {
+
+	usr.Locked = locked
+	usr.LockedUntil = time.Time{}
+	usr.LoginFailures = 0
+
+	_, err = db.Users().UpdateOne(db, &bson.M{
+		"_id": usr.Id,
+	}, &bson.M{
+		"$set": &bson.M{
+			"locked":         usr.Locked,
+			"locked_until":   usr.LockedUntil,
+			"login_failures": usr.LoginFailures,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	typ := audit.UserUnlocked
+	if locked {
+		typ = audit.UserLocked
+	}
+
+	fields := audit.Fields{
+		"method": "admin",
+	}
+	if adminUsr != nil {
+		fields["admin"] = adminUsr.Username
+	}
+
+	err = audit.New(db, r, usr.Id, typ, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
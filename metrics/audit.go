@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/node"
+)
+
+type loginType struct {
+	Type   string
+	Source string
+	Result string
+}
+
+var loginTypes = []loginType{
+	{audit.AdminLogin, "admin", "success"},
+	{audit.AdminLoginFailed, "admin", "failure"},
+	{audit.AdminAuthFailed, "admin", "auth_failure"},
+	{audit.ProxyLogin, "proxy", "success"},
+	{audit.ProxyLoginFailed, "proxy", "failure"},
+	{audit.ProxyAuthFailed, "proxy", "auth_failure"},
+	{audit.UserLogin, "user", "success"},
+	{audit.UserLoginFailed, "user", "failure"},
+	{audit.UserAuthFailed, "user", "auth_failure"},
+}
+
+func exportAudit(db *database.Database) (families []*Family, err error) {
+	nodes, err := node.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	logins := newFamily("audit_logins_total", Counter,
+		"Login attempts handled per node since node start")
+
+	for _, nde := range nodes {
+		for _, login := range loginTypes {
+			count, ok := nde.Logins[login.Type]
+			if !ok {
+				continue
+			}
+
+			logins.Add(float64(count), Labels{
+				"node":   nde.Name,
+				"source": login.Source,
+				"result": login.Result,
+			})
+		}
+	}
+
+	families = []*Family{
+		logins,
+	}
+
+	return
+}
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/check"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/endpoint"
+	"github.com/pritunl/pritunl-zero/endpoints"
+)
+
+// Endpoint samples older than this are considered stale and not exported
+const endpointStale = 5 * time.Minute
+
+func exportEndpoints(db *database.Database) (families []*Family, err error) {
+	since := time.Now().Add(-endpointStale)
+
+	endpts, err := endpoint.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	names := map[bson.ObjectID]string{}
+	for _, endpt := range endpts {
+		names[endpt.Id] = endpt.Name
+	}
+
+	checks, err := check.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	checkNames := map[bson.ObjectID]string{}
+	for _, chck := range checks {
+		checkNames[chck.Id] = chck.Name
+	}
+
+	cpu := newFamily("endpoint_cpu_percent", Gauge,
+		"Endpoint cpu usage percent")
+	mem := newFamily("endpoint_memory_percent", Gauge,
+		"Endpoint memory usage percent")
+	swap := newFamily("endpoint_swap_percent", Gauge,
+		"Endpoint swap usage percent")
+	huge := newFamily("endpoint_hugepages_percent", Gauge,
+		"Endpoint hugepages usage percent")
+	procs := newFamily("endpoint_processes", Gauge,
+		"Endpoint process count")
+
+	systems, err := endpoints.GetLatestSystem(db, since)
+	if err != nil {
+		return
+	}
+
+	for _, doc := range systems {
+		name, ok := names[doc.Endpoint]
+		if !ok {
+			continue
+		}
+
+		labels := Labels{
+			"endpoint": name,
+		}
+
+		cpu.Add(doc.CpuUsage, labels)
+		mem.Add(doc.MemUsage, labels)
+		swap.Add(doc.SwapUsage, labels)
+		huge.Add(doc.HugeUsage, labels)
+		procs.Add(float64(doc.Processes), labels)
+	}
+
+	diskUsed := newFamily("endpoint_disk_used_percent", Gauge,
+		"Endpoint mount usage percent")
+
+	disks, err := endpoints.GetLatestDisk(db, since)
+	if err != nil {
+		return
+	}
+
+	for _, doc := range disks {
+		name, ok := names[doc.Endpoint]
+		if !ok {
+			continue
+		}
+
+		for _, mount := range doc.Mounts {
+			diskUsed.Add(mount.Used, Labels{
+				"endpoint": name,
+				"mount":    mount.Path,
+			})
+		}
+	}
+
+	netSent := newFamily("endpoint_network_sent_bytes_per_second", Gauge,
+		"Endpoint interface transmit rate over the last sample")
+	netRecv := newFamily("endpoint_network_recv_bytes_per_second", Gauge,
+		"Endpoint interface receive rate over the last sample")
+	netErrors := newFamily("endpoint_network_error_percent", Gauge,
+		"Endpoint interface packets with errors or drops percent")
+
+	networks, err := endpoints.GetLatestNetwork(db, since)
+	if err != nil {
+		return
+	}
+
+	for _, doc := range networks {
+		name, ok := names[doc.Endpoint]
+		if !ok {
+			continue
+		}
+
+		for _, iface := range doc.Interfaces {
+			labels := Labels{
+				"endpoint":  name,
+				"interface": iface.Name,
+			}
+
+			netSent.Add(float64(iface.BytesSent)/60, labels)
+			netRecv.Add(float64(iface.BytesRecv)/60, labels)
+			netErrors.Add(iface.ErrorRate(), labels)
+		}
+	}
+
+	checkUp := newFamily("endpoint_check_targets_up", Gauge,
+		"Endpoint check targets passing")
+	checkDown := newFamily("endpoint_check_targets_down", Gauge,
+		"Endpoint check targets failing")
+	checkLatency := newFamily("endpoint_check_latency_ms", Gauge,
+		"Endpoint check average target latency in milliseconds")
+
+	checkDocs, err := endpoints.GetLatestCheck(db, since)
+	if err != nil {
+		return
+	}
+
+	for _, doc := range checkDocs {
+		name, ok := names[doc.Endpoint]
+		if !ok {
+			continue
+		}
+
+		checkName, ok := checkNames[doc.Check]
+		if !ok {
+			continue
+		}
+
+		labels := Labels{
+			"endpoint": name,
+			"check":    checkName,
+		}
+
+		checkUp.Add(float64(doc.TargetsUp), labels)
+		checkDown.Add(float64(doc.TargetsDown), labels)
+		checkLatency.Add(float64(doc.LatencyAvg), labels)
+	}
+
+	families = []*Family{
+		cpu,
+		mem,
+		swap,
+		huge,
+		procs,
+		diskUsed,
+		netSent,
+		netRecv,
+		netErrors,
+		checkUp,
+		checkDown,
+		checkLatency,
+	}
+
+	return
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+const (
+	prefix = "pritunl_zero_"
+
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+type Labels map[string]string
+
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+type Family struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []*Sample
+}
+
+func (f *Family) Add(value float64, labels Labels) {
+	f.Samples = append(f.Samples, &Sample{
+		Labels: labels,
+		Value:  value,
+	})
+}
+
+func newFamily(name, typ, help string) *Family {
+	return &Family{
+		Name:    prefix + name,
+		Help:    help,
+		Type:    typ,
+		Samples: []*Sample{},
+	}
+}
+
+var labelEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	"\n", `\n`,
+)
+
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out strings.Builder
+	out.WriteString("{")
+	for i, key := range keys {
+		if i != 0 {
+			out.WriteString(",")
+		}
+		out.WriteString(key)
+		out.WriteString(`="`)
+		out.WriteString(labelEscaper.Replace(labels[key]))
+		out.WriteString(`"`)
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Write families in the Prometheus text exposition format
+func Write(w io.Writer, families []*Family) (err error) {
+	buf := bufio.NewWriter(w)
+
+	for _, family := range families {
+		buf.WriteString("# HELP " + family.Name + " " + family.Help + "\n")
+		buf.WriteString("# TYPE " + family.Name + " " + family.Type + "\n")
+
+		for _, sample := range family.Samples {
+			buf.WriteString(family.Name)
+			buf.WriteString(formatLabels(sample.Labels))
+			buf.WriteString(" ")
+			buf.WriteString(strconv.FormatFloat(sample.Value, 'g', -1, 64))
+			buf.WriteString("\n")
+		}
+	}
+
+	err = buf.Flush()
+	if err != nil {
+		err = &errortypes.WriteError{
+			errors.Wrap(err, "metrics: Failed to write metrics"),
+		}
+		return
+	}
+
+	return
+}
+
+func Export(db *database.Database) (families []*Family, err error) {
+	families = []*Family{}
+
+	nodeFamilies, err := exportNodes(db)
+	if err != nil {
+		return
+	}
+	families = append(families, nodeFamilies...)
+
+	sshFamilies, err := exportSsh(db)
+	if err != nil {
+		return
+	}
+	families = append(families, sshFamilies...)
+
+	auditFamilies, err := exportAudit(db)
+	if err != nil {
+		return
+	}
+	families = append(families, auditFamilies...)
+
+	endpointFamilies, err := exportEndpoints(db)
+	if err != nil {
+		return
+	}
+	families = append(families, endpointFamilies...)
+
+	return
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/service"
+)
+
+func exportNodes(db *database.Database) (families []*Family, err error) {
+	nodes, err := node.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	services, err := service.GetAllNames(db)
+	if err != nil {
+		return
+	}
+
+	serviceNames := map[string]string{}
+	for _, srvc := range services {
+		serviceNames[srvc.Id.Hex()] = srvc.Name
+	}
+
+	online := newFamily("node_online", Gauge,
+		"Node is online and updating")
+	memory := newFamily("node_memory_percent", Gauge,
+		"Node memory usage percent")
+	load1 := newFamily("node_load1", Gauge,
+		"Node one minute load average")
+	load5 := newFamily("node_load5", Gauge,
+		"Node five minute load average")
+	load15 := newFamily("node_load15", Gauge,
+		"Node fifteen minute load average")
+	requests := newFamily("node_requests_per_minute", Gauge,
+		"Node requests handled in the last minute")
+	srvcRequests := newFamily("service_requests_total", Counter,
+		"Proxy requests handled per service since node start")
+	srvcCacheHits := newFamily("service_cache_hits_total", Counter,
+		"Proxy cache hits per service since node start")
+	srvcCacheMisses := newFamily("service_cache_misses_total", Counter,
+		"Proxy cache misses per service since node start")
+
+	for _, nde := range nodes {
+		labels := Labels{
+			"node": nde.Name,
+			"type": nde.Type,
+		}
+
+		if nde.IsOnline() {
+			online.Add(1, labels)
+		} else {
+			online.Add(0, labels)
+		}
+		memory.Add(nde.Memory, labels)
+		load1.Add(nde.Load1, labels)
+		load5.Add(nde.Load5, labels)
+		load15.Add(nde.Load15, labels)
+		requests.Add(float64(nde.RequestsMin), labels)
+
+		for srvcId, count := range nde.ServiceRequests {
+			name := serviceNames[srvcId]
+			if name == "" {
+				name = "unknown-service-" + srvcId
+			}
+
+			srvcRequests.Add(float64(count), Labels{
+				"node":    nde.Name,
+				"service": name,
+			})
+		}
+
+		for srvcId, count := range nde.ServiceCacheHits {
+			name := serviceNames[srvcId]
+			if name == "" {
+				name = "unknown-service-" + srvcId
+			}
+
+			srvcCacheHits.Add(float64(count), Labels{
+				"node":    nde.Name,
+				"service": name,
+			})
+		}
+
+		for srvcId, count := range nde.ServiceCacheMisses {
+			name := serviceNames[srvcId]
+			if name == "" {
+				name = "unknown-service-" + srvcId
+			}
+
+			srvcCacheMisses.Add(float64(count), Labels{
+				"node":    nde.Name,
+				"service": name,
+			})
+		}
+	}
+
+	families = []*Family{
+		online,
+		memory,
+		load1,
+		load5,
+		load15,
+		requests,
+		srvcRequests,
+		srvcCacheHits,
+		srvcCacheMisses,
+	}
+
+	return
+}
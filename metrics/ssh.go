@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/authority"
+	"github.com/pritunl/pritunl-zero/database"
+)
+
+type authorityCount struct {
+	Id    bson.ObjectID `bson:"_id"`
+	Count int64         `bson:"count"`
+}
+
+const sshTtl = 1 * time.Minute
+
+var (
+	sshCounts    map[bson.ObjectID]int64
+	sshTimestamp time.Time
+	sshLock      = sync.Mutex{}
+)
+
+// Certificate aggregation is cached to avoid scanning the ssh certificates
+// on every scrape
+func getSshCounts(db *database.Database) (
+	counts map[bson.ObjectID]int64, err error) {
+
+	sshLock.Lock()
+	defer sshLock.Unlock()
+
+	if sshCounts != nil && time.Since(sshTimestamp) < sshTtl {
+		counts = sshCounts
+		return
+	}
+
+	coll := db.SshCertificates()
+
+	counts = map[bson.ObjectID]int64{}
+
+	cursor, err := coll.Aggregate(db, []*bson.M{
+		&bson.M{
+			"$unwind": "$authority_ids",
+		},
+		&bson.M{
+			"$group": &bson.M{
+				"_id": "$authority_ids",
+				"count": &bson.M{
+					"$sum": 1,
+				},
+			},
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		doc := &authorityCount{}
+		err = cursor.Decode(doc)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		counts[doc.Id] = doc.Count
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	sshCounts = counts
+	sshTimestamp = time.Now()
+
+	return
+}
+
+func exportSsh(db *database.Database) (families []*Family, err error) {
+	authrs, err := authority.GetAllNames(db, &bson.M{})
+	if err != nil {
+		return
+	}
+
+	counts, err := getSshCounts(db)
+	if err != nil {
+		return
+	}
+
+	issued := newFamily("ssh_certificates_issued", Gauge,
+		"SSH certificates issued per authority in the last seven days")
+
+	for _, authr := range authrs {
+		issued.Add(float64(counts[authr.Id]), Labels{
+			"authority": authr.Name,
+		})
+	}
+
+	families = []*Family{
+		issued,
+	}
+
+	return
+}
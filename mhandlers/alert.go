@@ -19,15 +19,19 @@ import (
 )
 
 type alertData struct {
-	Id        bson.ObjectID `json:"id"`
-	Name      string        `json:"name"`
-	Roles     []string      `json:"roles"`
-	Resource  string        `json:"resource"`
-	Level     int           `json:"level"`
-	Frequency int           `json:"frequency"`
-	Ignores   []string      `json:"ignores"`
-	ValueInt  int           `json:"value_int"`
-	ValueStr  string        `json:"value_str"`
+	Id         bson.ObjectID   `json:"id"`
+	Name       string          `json:"name"`
+	Roles      []string        `json:"roles"`
+	Views      []bson.ObjectID `json:"views"`
+	Resource   string          `json:"resource"`
+	Level      int             `json:"level"`
+	Frequency  int             `json:"frequency"`
+	Ignores    []string        `json:"ignores"`
+	ValueInt   int             `json:"value_int"`
+	ValueStr   string          `json:"value_str"`
+	Duration   int             `json:"duration"`
+	ClearValue int             `json:"clear_value"`
+	Aggregate  string          `json:"aggregate"`
 }
 
 type alertsData struct {
@@ -66,22 +70,30 @@ func alertPut(c *gin.Context) {
 
 	alrt.Name = data.Name
 	alrt.Roles = data.Roles
+	alrt.Views = data.Views
 	alrt.Resource = data.Resource
 	alrt.Level = data.Level
 	alrt.Frequency = data.Frequency
 	alrt.Ignores = data.Ignores
 	alrt.ValueInt = data.ValueInt
 	alrt.ValueStr = data.ValueStr
+	alrt.Duration = data.Duration
+	alrt.ClearValue = data.ClearValue
+	alrt.Aggregate = data.Aggregate
 
 	fields := set.NewSet(
 		"name",
 		"roles",
+		"views",
 		"resource",
 		"level",
 		"frequency",
 		"ignores",
 		"value_int",
 		"value_str",
+		"duration",
+		"clear_value",
+		"aggregate",
 	)
 
 	errData, err := alrt.Validate(db)
@@ -129,14 +141,18 @@ func alertPost(c *gin.Context) {
 	}
 
 	alrt := &alert.Alert{
-		Name:      data.Name,
-		Roles:     data.Roles,
-		Resource:  data.Resource,
-		Level:     data.Level,
-		Frequency: data.Frequency,
-		Ignores:   data.Ignores,
-		ValueInt:  data.ValueInt,
-		ValueStr:  data.ValueStr,
+		Name:       data.Name,
+		Roles:      data.Roles,
+		Views:      data.Views,
+		Resource:   data.Resource,
+		Level:      data.Level,
+		Frequency:  data.Frequency,
+		Ignores:    data.Ignores,
+		ValueInt:   data.ValueInt,
+		ValueStr:   data.ValueStr,
+		Duration:   data.Duration,
+		ClearValue: data.ClearValue,
+		Aggregate:  data.Aggregate,
 	}
 
 	errData, err := alrt.Validate(db)
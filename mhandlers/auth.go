@@ -16,6 +16,7 @@ import (
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/event"
 	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/ratelimit"
 	"github.com/pritunl/pritunl-zero/secondary"
 	"github.com/pritunl/pritunl-zero/session"
 	"github.com/pritunl/pritunl-zero/utils"
@@ -52,7 +53,19 @@ func authSessionPost(c *gin.Context) {
 		return
 	}
 
-	usr, errData, err := auth.Local(db, data.Username, data.Password)
+	allowed, err := ratelimit.CheckUser(c, db, bson.NilObjectID,
+		strings.ToLower(data.Username))
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if !allowed {
+		return
+	}
+
+	usr, errData, err := auth.Local(db, c.Request,
+		data.Username, data.Password)
 	if err != nil {
 		utils.AbortWithError(c, 500, err)
 		return
@@ -223,6 +236,17 @@ func authSecondaryPost(c *gin.Context) {
 		return
 	}
 
+	allowed, err := ratelimit.CheckUser(c, db, usr.Id,
+		strings.ToLower(usr.Username))
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if !allowed {
+		return
+	}
+
 	errData, err := secd.Handle(db, c.Request, data.Factor, data.Passcode)
 	if err != nil {
 		if _, ok := err.(*secondary.IncompleteError); ok {
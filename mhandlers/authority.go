@@ -31,12 +31,20 @@ type authorityData struct {
 	ProxyHosting       bool          `json:"proxy_hosting"`
 	ProxyHostname      string        `json:"proxy_hostname"`
 	ProxyPort          int           `json:"proxy_port"`
+	ProxyRecording     bool          `json:"proxy_recording"`
 	HostDomain         string        `json:"host_domain"`
 	HostMatches        []string      `json:"host_matches"`
 	HostSubnets        []string      `json:"host_subnets"`
 	HostProxy          string        `json:"host_proxy"`
 	HostCertificates   bool          `json:"host_certificates"`
 	StrictHostChecking bool          `json:"strict_host_checking"`
+	HostIdentity       bool          `json:"host_identity"`
+	HostIdentityType   string        `json:"host_identity_type"`
+	HostIdentityCerts  string        `json:"host_identity_certs"`
+	HostIdentityAud    string        `json:"host_identity_aud"`
+	HostIdentityAccts  []string      `json:"host_identity_accts"`
+	HostIdentityMatch  []string      `json:"host_identity_match"`
+	HostPrincipals     []string      `json:"host_principals"`
 	HsmToken           string        `json:"hsm_token"`
 	HsmSecret          string        `json:"hsm_secret"`
 	HsmSerial          string        `json:"hsm_serial"`
@@ -108,12 +116,20 @@ func authorityPut(c *gin.Context) {
 	authr.ProxyHosting = data.ProxyHosting
 	authr.ProxyHostname = data.ProxyHostname
 	authr.ProxyPort = data.ProxyPort
+	authr.ProxyRecording = data.ProxyRecording
 	authr.HostMatches = data.HostMatches
 	authr.HostSubnets = data.HostSubnets
 	authr.HostDomain = data.HostDomain
 	authr.HostProxy = data.HostProxy
 	authr.HostCertificates = data.HostCertificates
 	authr.StrictHostChecking = data.StrictHostChecking
+	authr.HostIdentity = data.HostIdentity
+	authr.HostIdentityType = data.HostIdentityType
+	authr.HostIdentityCerts = data.HostIdentityCerts
+	authr.HostIdentityAud = data.HostIdentityAud
+	authr.HostIdentityAccts = data.HostIdentityAccts
+	authr.HostIdentityMatch = data.HostIdentityMatch
+	authr.HostPrincipals = data.HostPrincipals
 	authr.HsmSerial = data.HsmSerial
 
 	if authr.Type == authority.PritunlHsm && data.HsmGenerateSecret {
@@ -141,6 +157,7 @@ func authorityPut(c *gin.Context) {
 		"proxy_hosting",
 		"proxy_hostname",
 		"proxy_port",
+		"proxy_recording",
 		"host_domain",
 		"host_matches",
 		"host_subnets",
@@ -148,6 +165,13 @@ func authorityPut(c *gin.Context) {
 		"host_proxy",
 		"host_certificates",
 		"strict_host_checking",
+		"host_identity",
+		"host_identity_type",
+		"host_identity_certs",
+		"host_identity_aud",
+		"host_identity_accts",
+		"host_identity_match",
+		"host_principals",
 		"hsm_token",
 		"hsm_secret",
 		"hsm_serial",
@@ -224,10 +248,18 @@ func authorityPost(c *gin.Context) {
 		ProxyHosting:       data.ProxyHosting,
 		ProxyHostname:      data.ProxyHostname,
 		ProxyPort:          data.ProxyPort,
+		ProxyRecording:     data.ProxyRecording,
 		HostDomain:         data.HostDomain,
 		HostMatches:        data.HostMatches,
 		HostSubnets:        data.HostSubnets,
 		StrictHostChecking: data.StrictHostChecking,
+		HostIdentity:       data.HostIdentity,
+		HostIdentityType:   data.HostIdentityType,
+		HostIdentityCerts:  data.HostIdentityCerts,
+		HostIdentityAud:    data.HostIdentityAud,
+		HostIdentityAccts:  data.HostIdentityAccts,
+		HostIdentityMatch:  data.HostIdentityMatch,
+		HostPrincipals:     data.HostPrincipals,
 	}
 
 	err = authr.GeneratePrivateKey()
@@ -20,17 +20,22 @@ import (
 )
 
 type certificateData struct {
-	Id          bson.ObjectID `json:"id"`
-	Name        string        `json:"name"`
-	Comment     string        `json:"comment"`
-	Type        string        `json:"type"`
-	Key         string        `json:"key"`
-	Certificate string        `json:"certificate"`
-	AcmeDomains []string      `json:"acme_domains"`
-	AcmeType    string        `json:"acme_type"`
-	AcmeAuth    string        `json:"acme_auth"`
-	AcmeSecret  bson.ObjectID `json:"acme_secret"`
-	Refresh     bool          `json:"refresh"`
+	Id              bson.ObjectID `json:"id"`
+	Name            string        `json:"name"`
+	Comment         string        `json:"comment"`
+	Type            string        `json:"type"`
+	Key             string        `json:"key"`
+	Certificate     string        `json:"certificate"`
+	AcmeDomains     []string      `json:"acme_domains"`
+	AcmeType        string        `json:"acme_type"`
+	AcmeAuth        string        `json:"acme_auth"`
+	AcmeSecret      bson.ObjectID `json:"acme_secret"`
+	AcmeDirectory   string        `json:"acme_directory"`
+	AcmeDirectoryCa string        `json:"acme_directory_ca"`
+	AcmeEabSecret   bson.ObjectID `json:"acme_eab_secret"`
+	AcmeChain       string        `json:"acme_chain"`
+	AcmeEmail       string        `json:"acme_email"`
+	Refresh         bool          `json:"refresh"`
 }
 
 type certificatesData struct {
@@ -74,6 +79,11 @@ func certificatePut(c *gin.Context) {
 	cert.AcmeType = data.AcmeType
 	cert.AcmeAuth = data.AcmeAuth
 	cert.AcmeSecret = data.AcmeSecret
+	cert.AcmeDirectory = data.AcmeDirectory
+	cert.AcmeDirectoryCa = data.AcmeDirectoryCa
+	cert.AcmeEabSecret = data.AcmeEabSecret
+	cert.AcmeChain = data.AcmeChain
+	cert.AcmeEmail = data.AcmeEmail
 
 	fields := set.NewSet(
 		"name",
@@ -83,6 +93,11 @@ func certificatePut(c *gin.Context) {
 		"acme_type",
 		"acme_auth",
 		"acme_secret",
+		"acme_directory",
+		"acme_directory_ca",
+		"acme_eab_secret",
+		"acme_chain",
+		"acme_email",
 		"info",
 	)
 
@@ -139,13 +154,18 @@ func certificatePost(c *gin.Context) {
 	}
 
 	cert := &certificate.Certificate{
-		Name:        data.Name,
-		Comment:     data.Comment,
-		Type:        data.Type,
-		AcmeDomains: data.AcmeDomains,
-		AcmeType:    data.AcmeType,
-		AcmeAuth:    data.AcmeAuth,
-		AcmeSecret:  data.AcmeSecret,
+		Name:            data.Name,
+		Comment:         data.Comment,
+		Type:            data.Type,
+		AcmeDomains:     data.AcmeDomains,
+		AcmeType:        data.AcmeType,
+		AcmeAuth:        data.AcmeAuth,
+		AcmeSecret:      data.AcmeSecret,
+		AcmeDirectory:   data.AcmeDirectory,
+		AcmeDirectoryCa: data.AcmeDirectoryCa,
+		AcmeEabSecret:   data.AcmeEabSecret,
+		AcmeChain:       data.AcmeChain,
+		AcmeEmail:       data.AcmeEmail,
 	}
 
 	if cert.Type != certificate.LetsEncrypt {
@@ -24,6 +24,7 @@ type checkData struct {
 	Id         bson.ObjectID   `json:"id"`
 	Name       string          `json:"name"`
 	Roles      []string        `json:"roles"`
+	Views      []bson.ObjectID `json:"views"`
 	Frequency  int             `json:"frequency"`
 	Type       string          `json:"type"`
 	Targets    []string        `json:"targets"`
@@ -31,6 +32,14 @@ type checkData struct {
 	Method     string          `json:"method"`
 	StatusCode int             `json:"status_code"`
 	Headers    []*check.Header `json:"headers"`
+	Body       string          `json:"body"`
+	BodyMatch  string          `json:"body_match"`
+	BodyRegex  string          `json:"body_regex"`
+	ServerName string          `json:"server_name"`
+	ExpiryDays int             `json:"expiry_days"`
+	DnsServer  string          `json:"dns_server"`
+	DnsType    string          `json:"dns_type"`
+	DnsAnswer  string          `json:"dns_answer"`
 }
 
 type checksData struct {
@@ -69,6 +78,7 @@ func checkPut(c *gin.Context) {
 
 	chck.Name = data.Name
 	chck.Roles = data.Roles
+	chck.Views = data.Views
 	chck.Frequency = data.Frequency
 	chck.Type = data.Type
 	chck.Targets = data.Targets
@@ -76,10 +86,19 @@ func checkPut(c *gin.Context) {
 	chck.Method = data.Method
 	chck.StatusCode = data.StatusCode
 	chck.Headers = data.Headers
+	chck.Body = data.Body
+	chck.BodyMatch = data.BodyMatch
+	chck.BodyRegex = data.BodyRegex
+	chck.ServerName = data.ServerName
+	chck.ExpiryDays = data.ExpiryDays
+	chck.DnsServer = data.DnsServer
+	chck.DnsType = data.DnsType
+	chck.DnsAnswer = data.DnsAnswer
 
 	fields := set.NewSet(
 		"name",
 		"roles",
+		"views",
 		"frequency",
 		"type",
 		"targets",
@@ -87,6 +106,14 @@ func checkPut(c *gin.Context) {
 		"method",
 		"status_code",
 		"headers",
+		"body",
+		"body_match",
+		"body_regex",
+		"server_name",
+		"expiry_days",
+		"dns_server",
+		"dns_type",
+		"dns_answer",
 	)
 
 	errData, err := chck.Validate(db)
@@ -134,6 +161,7 @@ func checkPost(c *gin.Context) {
 	chck := &check.Check{
 		Name:       data.Name,
 		Roles:      data.Roles,
+		Views:      data.Views,
 		Frequency:  data.Frequency,
 		Type:       data.Type,
 		Targets:    data.Targets,
@@ -141,6 +169,14 @@ func checkPost(c *gin.Context) {
 		Method:     data.Method,
 		StatusCode: data.StatusCode,
 		Headers:    data.Headers,
+		Body:       data.Body,
+		BodyMatch:  data.BodyMatch,
+		BodyRegex:  data.BodyRegex,
+		ServerName: data.ServerName,
+		ExpiryDays: data.ExpiryDays,
+		DnsServer:  data.DnsServer,
+		DnsType:    data.DnsType,
+		DnsAnswer:  data.DnsAnswer,
 	}
 
 	errData, err := chck.Validate(db)
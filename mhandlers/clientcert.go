@@ -0,0 +1,146 @@
+package mhandlers
+
+import (
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/authority"
+	"github.com/pritunl/pritunl-zero/clientcert"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/demo"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/user"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+type clientcertData struct {
+	Authority bson.ObjectID `json:"authority"`
+	Comment   string        `json:"comment"`
+	Expire    int           `json:"expire"`
+	Csr       string        `json:"csr"`
+}
+
+type clientcertIssueData struct {
+	*clientcert.Certificate
+	PrivateKey string `json:"private_key"`
+}
+
+func clientcertsGet(c *gin.Context) {
+	if demo.IsDemo() {
+		c.JSON(200, []*clientcert.Certificate{})
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	userId, ok := utils.ParseObjectId(c.Param("user_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	certs, err := clientcert.GetAll(db, userId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, certs)
+}
+
+func clientcertPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &clientcertData{}
+
+	userId, ok := utils.ParseObjectId(c.Param("user_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	usr, err := user.Get(db, userId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	authr, err := authority.Get(db, data.Authority)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	cert, keyPem, errData, err := clientcert.New(db, authr, usr,
+		data.Csr, utils.FilterStr(data.Comment, 128), data.Expire)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "clientcert.change")
+
+	c.JSON(200, &clientcertIssueData{
+		Certificate: cert,
+		PrivateKey:  keyPem,
+	})
+}
+
+func clientcertDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	userId, ok := utils.ParseObjectId(c.Param("user_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	certId, ok := utils.ParseObjectId(c.Param("cert_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	cert, err := clientcert.Get(db, certId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if cert.UserId != userId {
+		utils.AbortWithStatus(c, 404)
+		return
+	}
+
+	err = cert.Revoke(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "clientcert.change")
+
+	c.JSON(200, nil)
+}
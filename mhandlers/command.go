@@ -0,0 +1,666 @@
+package mhandlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/authorizer"
+	"github.com/pritunl/pritunl-zero/command"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/demo"
+	"github.com/pritunl/pritunl-zero/endpoint"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/secondary"
+	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/pritunl/pritunl-zero/validator"
+)
+
+type commandData struct {
+	Id      bson.ObjectID `json:"id"`
+	Name    string        `json:"name"`
+	Comment string        `json:"comment"`
+	Roles   []string      `json:"roles"`
+	Exec    string        `json:"exec"`
+	Args    []string      `json:"args"`
+	Timeout int           `json:"timeout"`
+}
+
+type commandsData struct {
+	Commands []*command.Command `json:"commands"`
+	Count    int64              `json:"count"`
+}
+
+type commandRunData struct {
+	Endpoints []bson.ObjectID `json:"endpoints"`
+}
+
+type commandRunSecondaryData struct {
+	Token    string `json:"token"`
+	Factor   string `json:"factor"`
+	Passcode string `json:"passcode"`
+}
+
+type commandRunStartData struct {
+	Run       *command.Run             `json:"run"`
+	Secondary *secondary.SecondaryData `json:"secondary"`
+}
+
+type commandRunsData struct {
+	Runs  []*command.Run `json:"runs"`
+	Count int64          `json:"count"`
+}
+
+func commandPut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &commandData{}
+
+	commandId, ok := utils.ParseObjectId(c.Param("command_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	cmd, err := command.Get(db, commandId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	cmd.Name = data.Name
+	cmd.Comment = data.Comment
+	cmd.Roles = data.Roles
+	cmd.Exec = data.Exec
+	cmd.Args = data.Args
+	cmd.Timeout = data.Timeout
+
+	fields := set.NewSet(
+		"name",
+		"comment",
+		"roles",
+		"exec",
+		"args",
+		"timeout",
+	)
+
+	errData, err := cmd.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = cmd.CommitFields(db, fields)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "command.change")
+
+	c.JSON(200, cmd)
+}
+
+func commandPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &commandData{
+		Name: "New Command",
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	cmd := &command.Command{
+		Name:    data.Name,
+		Comment: data.Comment,
+		Roles:   data.Roles,
+		Exec:    data.Exec,
+		Args:    data.Args,
+		Timeout: data.Timeout,
+	}
+
+	errData, err := cmd.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = cmd.Insert(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "command.change")
+
+	c.JSON(200, cmd)
+}
+
+func commandDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	commandId, ok := utils.ParseObjectId(c.Param("command_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := command.Remove(db, commandId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "command.change")
+
+	c.JSON(200, nil)
+}
+
+func commandsDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	dta := []bson.ObjectID{}
+
+	err := c.Bind(&dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = command.RemoveMulti(db, dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "command.change")
+
+	c.JSON(200, nil)
+}
+
+func commandGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	commandId, ok := utils.ParseObjectId(c.Param("command_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	cmd, err := command.Get(db, commandId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, cmd)
+}
+
+func commandsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+
+	query := bson.M{}
+
+	name := strings.TrimSpace(c.Query("name"))
+	if name != "" {
+		query["$or"] = []*bson.M{
+			&bson.M{
+				"name": &bson.M{
+					"$regex":   fmt.Sprintf(".*%s.*", regexp.QuoteMeta(name)),
+					"$options": "i",
+				},
+			},
+		}
+	}
+
+	commands, count, err := command.GetAllPaged(
+		db, &query, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	dta := &commandsData{
+		Commands: commands,
+		Count:    count,
+	}
+
+	c.JSON(200, dta)
+}
+
+func commandRunPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	authr := c.MustGet("authorizer").(*authorizer.Authorizer)
+	data := &commandRunData{}
+
+	commandId, ok := utils.ParseObjectId(c.Param("command_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	usr, err := authr.GetUser(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	cmd, err := command.Get(db, commandId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if !cmd.HasRole(usr.Roles) {
+		c.JSON(403, &errortypes.ErrorData{
+			Error:   "command_unauthorized",
+			Message: "User does not have a role permitted to run command",
+		})
+		return
+	}
+
+	if len(data.Endpoints) == 0 {
+		c.JSON(400, &errortypes.ErrorData{
+			Error:   "command_endpoints_invalid",
+			Message: "No endpoints selected",
+		})
+		return
+	}
+
+	endpts, err := endpoint.GetMulti(db, data.Endpoints)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if len(endpts) == 0 {
+		c.JSON(400, &errortypes.ErrorData{
+			Error:   "command_endpoints_invalid",
+			Message: "No endpoints selected",
+		})
+		return
+	}
+
+	_, secProviderId, _, errData, err := validator.ValidateAdmin(
+		db, usr, false, c.Request)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(401, errData)
+		return
+	}
+
+	if secProviderId.IsZero() {
+		c.JSON(400, &errortypes.ErrorData{
+			Error:   "command_secondary_unavailable",
+			Message: "Admin secondary authentication required for commands",
+		})
+		return
+	}
+
+	run := &command.Run{
+		Id:          bson.NewObjectID(),
+		Command:     cmd.Id,
+		CommandName: cmd.Name,
+		Exec:        cmd.Exec,
+		Args:        cmd.Args,
+		Timeout:     cmd.Timeout,
+		User:        usr.Id,
+		Username:    usr.Username,
+		State:       command.Pending,
+		Timestamp:   time.Now(),
+		Results:     []*command.Result{},
+	}
+
+	for _, endpt := range endpts {
+		run.Results = append(run.Results, &command.Result{
+			Endpoint:     endpt.Id,
+			EndpointName: endpt.Name,
+			State:        command.Pending,
+		})
+	}
+
+	err = run.Insert(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	secd, err := secondary.NewChallenge(db, usr.Id, secondary.Command,
+		run.Id.Hex(), secProviderId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	secData, err := secd.GetData()
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "command_run.change")
+
+	c.JSON(201, &commandRunStartData{
+		Run:       run,
+		Secondary: secData,
+	})
+}
+
+func commandRunSecondaryPut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	authr := c.MustGet("authorizer").(*authorizer.Authorizer)
+	data := &commandRunSecondaryData{}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	usr, err := authr.GetUser(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	secd, err := secondary.Get(db, data.Token, secondary.Command)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			errData := &errortypes.ErrorData{
+				Error:   "secondary_expired",
+				Message: "Secondary authentication has expired",
+			}
+			c.JSON(400, errData)
+		} else {
+			utils.AbortWithError(c, 500, err)
+		}
+		return
+	}
+
+	if secd.UserId != usr.Id {
+		utils.AbortWithStatus(c, 401)
+		return
+	}
+
+	runId, ok := utils.ParseObjectId(secd.ChallengeId)
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	run, err := command.GetRun(db, runId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if run.State != command.Pending || !time.Now().Before(run.Deadline()) {
+		c.JSON(400, &errortypes.ErrorData{
+			Error:   "command_run_expired",
+			Message: "Command run has expired",
+		})
+		return
+	}
+
+	endpointIds := []bson.ObjectID{}
+	for _, result := range run.Results {
+		endpointIds = append(endpointIds, result.Endpoint)
+	}
+
+	errData, err := secd.Handle(db, c.Request, data.Factor, data.Passcode)
+	if err != nil {
+		if _, ok := err.(*secondary.IncompleteError); ok {
+			c.Status(206)
+		} else {
+			utils.AbortWithError(c, 500, err)
+		}
+		return
+	}
+
+	if errData != nil {
+		err = audit.New(
+			db,
+			c.Request,
+			usr.Id,
+			audit.EndpointCommandFailed,
+			audit.Fields{
+				"run_id":      run.Id,
+				"command_id":  run.Command,
+				"command":     run.CommandName,
+				"endpoints":   endpointIds,
+				"provider_id": secd.ProviderId,
+				"error":       errData.Error,
+				"message":     errData.Message,
+			},
+		)
+		if err != nil {
+			utils.AbortWithError(c, 500, err)
+			return
+		}
+
+		c.JSON(400, errData)
+		return
+	}
+
+	// Roles or the command may have changed during secondary authentication
+	cmd, err := command.Get(db, run.Command)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			cmd = nil
+			err = nil
+		} else {
+			utils.AbortWithError(c, 500, err)
+			return
+		}
+	}
+
+	if cmd == nil || !cmd.HasRole(usr.Roles) {
+		errData = &errortypes.ErrorData{
+			Error:   "command_unauthorized",
+			Message: "User does not have a role permitted to run command",
+		}
+
+		err = audit.New(
+			db,
+			c.Request,
+			usr.Id,
+			audit.EndpointCommandFailed,
+			audit.Fields{
+				"run_id":      run.Id,
+				"command_id":  run.Command,
+				"command":     run.CommandName,
+				"endpoints":   endpointIds,
+				"provider_id": secd.ProviderId,
+				"error":       errData.Error,
+				"message":     errData.Message,
+			},
+		)
+		if err != nil {
+			utils.AbortWithError(c, 500, err)
+			return
+		}
+
+		c.JSON(403, errData)
+		return
+	}
+
+	err = audit.New(
+		db,
+		c.Request,
+		usr.Id,
+		audit.EndpointCommand,
+		audit.Fields{
+			"run_id":      run.Id,
+			"command_id":  run.Command,
+			"command":     run.CommandName,
+			"exec":        run.Exec,
+			"args":        run.Args,
+			"endpoints":   endpointIds,
+			"provider_id": secd.ProviderId,
+		},
+	)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = run.Start(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "command_run.change")
+
+	c.JSON(200, run)
+}
+
+func commandRunGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	runId, ok := utils.ParseObjectId(c.Param("run_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	run, err := command.GetRun(db, runId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, run)
+}
+
+func commandRunsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+
+	query := bson.M{}
+
+	commandId, ok := utils.ParseObjectId(c.Query("command"))
+	if ok {
+		query["command"] = commandId
+	}
+
+	endpointId, ok := utils.ParseObjectId(c.Query("endpoint"))
+	if ok {
+		query["results.endpoint"] = endpointId
+	}
+
+	runs, count, err := command.GetRunsPaged(db, &query, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	dta := &commandRunsData{
+		Runs:  runs,
+		Count: count,
+	}
+
+	c.JSON(200, dta)
+}
+
+func commandRunOutputGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	after := -1
+	if c.Query("after") != "" {
+		after, _ = strconv.Atoi(c.Query("after"))
+	}
+
+	runId, ok := utils.ParseObjectId(c.Param("run_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	endpointId, ok := utils.ParseObjectId(c.Query("endpoint"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	output, err := command.GetOutput(db, runId, endpointId, after)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, output)
+}
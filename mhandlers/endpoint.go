@@ -20,13 +20,15 @@ import (
 	"github.com/pritunl/pritunl-zero/event"
 	"github.com/pritunl/pritunl-zero/settings"
 	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/pritunl/pritunl-zero/view"
 )
 
 type endpointData struct {
-	Id             bson.ObjectID `json:"id"`
-	Name           string        `json:"name"`
-	Roles          []string      `json:"roles"`
-	ResetClientKey bool          `json:"reset_client_key"`
+	Id             bson.ObjectID     `json:"id"`
+	Name           string            `json:"name"`
+	Roles          []string          `json:"roles"`
+	Tags           map[string]string `json:"tags"`
+	ResetClientKey bool              `json:"reset_client_key"`
 }
 
 type endpointsData struct {
@@ -70,6 +72,7 @@ func endpointPut(c *gin.Context) {
 
 	endpt.Name = data.Name
 	endpt.Roles = data.Roles
+	endpt.Tags = data.Tags
 
 	if data.ResetClientKey {
 		err = endpt.GenerateKey()
@@ -82,6 +85,7 @@ func endpointPut(c *gin.Context) {
 	fields := set.NewSet(
 		"name",
 		"roles",
+		"tags",
 		"client_key",
 		"server_key",
 	)
@@ -132,6 +136,7 @@ func endpointPost(c *gin.Context) {
 	endpt := &endpoint.Endpoint{
 		Name:  data.Name,
 		Roles: data.Roles,
+		Tags:  data.Tags,
 	}
 
 	errData, err := endpt.Validate(db)
@@ -241,6 +246,45 @@ func endpointsGet(c *gin.Context) {
 		query["organization"] = organization
 	}
 
+	filters := []*bson.M{}
+
+	fleetQuery := strings.TrimSpace(c.Query("query"))
+	if fleetQuery != "" {
+		filter, err := view.CompileQuery(db, fleetQuery)
+		if err != nil {
+			if _, ok := err.(*errortypes.ParseError); ok {
+				c.JSON(400, &errortypes.ErrorData{
+					Error:   "endpoint_query_invalid",
+					Message: errortypes.GetErrorMessage(err),
+				})
+			} else {
+				utils.AbortWithError(c, 500, err)
+			}
+			return
+		}
+		filters = append(filters, filter)
+	}
+
+	viewId, ok := utils.ParseObjectId(c.Query("view"))
+	if ok {
+		vw, err := view.Get(db, viewId)
+		if err != nil {
+			utils.AbortWithError(c, 500, err)
+			return
+		}
+
+		filter, err := vw.Compile(db)
+		if err != nil {
+			utils.AbortWithError(c, 500, err)
+			return
+		}
+		filters = append(filters, filter)
+	}
+
+	if len(filters) > 0 {
+		query["$and"] = filters
+	}
+
 	endpoints, count, err := endpoint.GetAllPaged(
 		db, &query, page, pageCount)
 	if err != nil {
@@ -27,6 +27,9 @@ func Register(engine *gin.Engine) {
 	dbGroup := engine.Group("")
 	dbGroup.Use(middlewear.Database)
 
+	limitGroup := dbGroup.Group("")
+	limitGroup.Use(middlewear.RateLimitAuth)
+
 	sessGroup := dbGroup.Group("")
 	sessGroup.Use(middlewear.SessionAdmin)
 
@@ -46,16 +49,28 @@ func Register(engine *gin.Engine) {
 	csrfGroup.POST("/alert", alertPost)
 	csrfGroup.DELETE("/alert", alertsDelete)
 	csrfGroup.DELETE("/alert/:alert_id", alertDelete)
+	dbGroup.POST("/alert/twilio", incidentTwilioPost)
+
+	csrfGroup.GET("/incident", incidentsGet)
+	csrfGroup.GET("/incident/:incident_id", incidentGet)
+	csrfGroup.PUT("/incident/:incident_id/acknowledge", incidentAcknowledgePut)
+
+	csrfGroup.GET("/silence", silencesGet)
+	csrfGroup.GET("/silence/:silence_id", silenceGet)
+	csrfGroup.PUT("/silence/:silence_id", silencePut)
+	csrfGroup.POST("/silence", silencePost)
+	csrfGroup.DELETE("/silence", silencesDelete)
+	csrfGroup.DELETE("/silence/:silence_id", silenceDelete)
 
 	engine.GET("/auth/state", authStateGet)
-	dbGroup.POST("/auth/session", authSessionPost)
-	dbGroup.POST("/auth/secondary", authSecondaryPost)
-	dbGroup.GET("/auth/request", authRequestGet)
-	dbGroup.GET("/auth/callback", authCallbackGet)
+	limitGroup.POST("/auth/session", authSessionPost)
+	limitGroup.POST("/auth/secondary", authSecondaryPost)
+	limitGroup.GET("/auth/request", authRequestGet)
+	limitGroup.GET("/auth/callback", authCallbackGet)
 	dbGroup.GET("/auth/webauthn/request", authWanRequestGet)
-	dbGroup.POST("/auth/webauthn/respond", authWanRespondPost)
+	limitGroup.POST("/auth/webauthn/respond", authWanRespondPost)
 	dbGroup.GET("/auth/webauthn/register", authWanRegisterGet)
-	dbGroup.POST("/auth/webauthn/register", authWanRegisterPost)
+	limitGroup.POST("/auth/webauthn/register", authWanRegisterPost)
 	sessGroup.GET("/logout", logoutGet)
 
 	csrfGroup.GET("/authority", authoritiesGet)
@@ -76,6 +91,11 @@ func Register(engine *gin.Engine) {
 	csrfGroup.DELETE("/certificate", certificatesDelete)
 	csrfGroup.DELETE("/certificate/:cert_id", certificateDelete)
 
+	csrfGroup.GET("/clientcertificate/:user_id", clientcertsGet)
+	csrfGroup.POST("/clientcertificate/:user_id", clientcertPost)
+	csrfGroup.DELETE("/clientcertificate/:user_id/:cert_id",
+		clientcertDelete)
+
 	engine.GET("/check", checkGet)
 
 	csrfGroup.GET("/checks", checksGet)
@@ -88,6 +108,18 @@ func Register(engine *gin.Engine) {
 
 	authGroup.GET("/csrf", csrfGet)
 
+	csrfGroup.GET("/command", commandsGet)
+	csrfGroup.GET("/command/:command_id", commandGet)
+	csrfGroup.PUT("/command/:command_id", commandPut)
+	csrfGroup.POST("/command", commandPost)
+	csrfGroup.DELETE("/command", commandsDelete)
+	csrfGroup.DELETE("/command/:command_id", commandDelete)
+	csrfGroup.POST("/command/:command_id/run", commandRunPost)
+	csrfGroup.GET("/command_run", commandRunsGet)
+	csrfGroup.GET("/command_run/:run_id", commandRunGet)
+	csrfGroup.GET("/command_run/:run_id/output", commandRunOutputGet)
+	csrfGroup.PUT("/command_run/secondary", commandRunSecondaryPut)
+
 	csrfGroup.GET("/completion", completionGet)
 
 	csrfGroup.GET("/device/:user_id", devicesGet)
@@ -106,6 +138,17 @@ func Register(engine *gin.Engine) {
 	csrfGroup.DELETE("/endpoint/:endpoint_id", endpointDelete)
 	csrfGroup.GET("/endpoint/:endpoint_id/chart", endpointChartGet)
 	csrfGroup.GET("/endpoint/:endpoint_id/log", endpointLogGet)
+	csrfGroup.GET("/endpoint/:endpoint_id/inventory", endpointInventoryGet)
+	csrfGroup.GET("/endpoint/:endpoint_id/inventory/change",
+		endpointInventoryChangeGet)
+	csrfGroup.GET("/inventory", inventoryGet)
+
+	csrfGroup.GET("/view", viewsGet)
+	csrfGroup.GET("/view/:view_id", viewGet)
+	csrfGroup.PUT("/view/:view_id", viewPut)
+	csrfGroup.POST("/view", viewPost)
+	csrfGroup.DELETE("/view", viewsDelete)
+	csrfGroup.DELETE("/view/:view_id", viewDelete)
 
 	dbGroup.PUT("/endpoint/:endpoint_id/register",
 		handlers.EndpointRegisterPut)
@@ -117,6 +160,8 @@ func Register(engine *gin.Engine) {
 	csrfGroup.GET("/log", logsGet)
 	csrfGroup.GET("/log/:log_id", logGet)
 
+	dbGroup.GET("/metrics", metricsGet)
+
 	csrfGroup.GET("/node", nodesGet)
 	csrfGroup.GET("/node/:node_id", nodeGet)
 	csrfGroup.PUT("/node/:node_id", nodePut)
@@ -141,6 +186,7 @@ func Register(engine *gin.Engine) {
 	csrfGroup.POST("/service", servicePost)
 	csrfGroup.DELETE("/service", servicesDelete)
 	csrfGroup.DELETE("/service/:service_id", serviceDelete)
+	csrfGroup.DELETE("/service/:service_id/cache", serviceCacheDelete)
 
 	csrfGroup.GET("/session/:user_id", sessionsGet)
 	csrfGroup.DELETE("/session/:session_id", sessionDelete)
@@ -150,6 +196,9 @@ func Register(engine *gin.Engine) {
 
 	csrfGroup.GET("/sshcertificate/:user_id", sshcertsGet)
 
+	csrfGroup.GET("/sshrecording/:user_id", recordingsGet)
+	csrfGroup.GET("/sshrecording/:user_id/:recording_id", recordingCastGet)
+
 	csrfGroup.GET("/subscription", subscriptionGet)
 	csrfGroup.GET("/subscription/update", subscriptionUpdateGet)
 	csrfGroup.POST("/subscription", subscriptionPost)
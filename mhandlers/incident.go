@@ -0,0 +1,210 @@
+package mhandlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/alertevent"
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/authorizer"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/demo"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/twilio"
+	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/sirupsen/logrus"
+)
+
+type incidentsData struct {
+	Incidents []*alertevent.Incident `json:"incidents"`
+	Count     int64                  `json:"count"`
+}
+
+func incidentsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+
+	query := bson.M{}
+
+	endpointIdStr := c.Query("endpoint")
+	if endpointIdStr != "" {
+		endpointId, ok := utils.ParseObjectId(endpointIdStr)
+		if !ok {
+			utils.AbortWithStatus(c, 400)
+			return
+		}
+		query["source"] = endpointId
+	}
+
+	alertIdStr := c.Query("alert")
+	if alertIdStr != "" {
+		alertId, ok := utils.ParseObjectId(alertIdStr)
+		if !ok {
+			utils.AbortWithStatus(c, 400)
+			return
+		}
+		query["alert"] = alertId
+	}
+
+	state := c.Query("state")
+	if state != "" {
+		query["state"] = state
+	}
+
+	incidents, count, err := alertevent.GetIncidents(
+		db, &query, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	data := &incidentsData{
+		Incidents: incidents,
+		Count:     count,
+	}
+
+	c.JSON(200, data)
+}
+
+func incidentGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	incId, ok := utils.ParseObjectId(c.Param("incident_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	inc, err := alertevent.GetIncident(db, incId)
+	if err != nil {
+		switch err.(type) {
+		case *database.NotFoundError:
+			utils.AbortWithStatus(c, 404)
+			break
+		default:
+			utils.AbortWithError(c, 500, err)
+		}
+		return
+	}
+
+	c.JSON(200, inc)
+}
+
+func incidentAcknowledgePut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	authr := c.MustGet("authorizer").(*authorizer.Authorizer)
+
+	incId, ok := utils.ParseObjectId(c.Param("incident_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	usr, err := authr.GetUser(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	inc, err := alertevent.GetIncident(db, incId)
+	if err != nil {
+		switch err.(type) {
+		case *database.NotFoundError:
+			utils.AbortWithStatus(c, 404)
+			break
+		default:
+			utils.AbortWithError(c, 500, err)
+		}
+		return
+	}
+
+	errData, err := inc.Acknowledge(db, usr)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = audit.New(
+		db,
+		c.Request,
+		usr.Id,
+		audit.AlertAcknowledge,
+		audit.Fields{
+			"incident_id": inc.Id,
+			"endpoint":    inc.SourceName,
+			"alert":       inc.Name,
+		},
+	)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "alert.change")
+
+	c.JSON(200, inc)
+}
+
+// Twilio incoming message webhook for acknowledging alerts by text reply
+func incidentTwilioPost(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	err := c.Request.ParseForm()
+	if err != nil {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	params := map[string]string{}
+	for key, vals := range c.Request.PostForm {
+		if len(vals) > 0 {
+			params[key] = vals[0]
+		}
+	}
+
+	url := "https://" + c.Request.Host + c.Request.URL.RequestURI()
+	if !twilio.ValidateRequest(url, params,
+		c.GetHeader("X-Twilio-Signature")) {
+
+		utils.AbortWithStatus(c, 401)
+		return
+	}
+
+	reply := ""
+	fields := strings.Fields(params["Body"])
+	if len(fields) == 2 && strings.EqualFold(fields[0], "ack") {
+		inc, e := alertevent.AcknowledgeNumber(db, params["From"], fields[1])
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"error": e,
+			}).Error("mhandlers: Failed to acknowledge incident")
+			reply = "Failed to acknowledge alert"
+		} else if inc == nil {
+			reply = "No firing alert found for code " + fields[1]
+		} else {
+			reply = "Acknowledged " + inc.Name + ":" + inc.SourceName
+			_ = event.PublishDispatch(db, "alert.change")
+		}
+	}
+
+	data, err := twilio.MessageResponse(reply)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.Data(200, "application/xml", data)
+}
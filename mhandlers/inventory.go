@@ -0,0 +1,161 @@
+package mhandlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/endpoint"
+	"github.com/pritunl/pritunl-zero/endpoints"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+type inventoryChangesData struct {
+	Changes []*endpoints.InventoryChange `json:"changes"`
+	Count   int64                        `json:"count"`
+}
+
+type inventorySearchItem struct {
+	*endpoints.InventoryItem
+	EndpointName string `json:"endpoint_name"`
+}
+
+type inventorySearchData struct {
+	Items []*inventorySearchItem `json:"items"`
+	Count int64                  `json:"count"`
+}
+
+func validInventoryKind(kind string) bool {
+	switch kind {
+	case endpoints.InventoryPackage, endpoints.InventoryPort,
+		endpoints.InventoryUser, endpoints.InventoryService:
+
+		return true
+	default:
+		return false
+	}
+}
+
+func endpointInventoryGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	endpointId, ok := utils.ParseObjectId(c.Param("endpoint_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	kind := c.Query("kind")
+	if !validInventoryKind(kind) {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	items, err := endpoints.GetInventory(c, db, endpointId, kind)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, items)
+}
+
+func endpointInventoryChangeGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+	page = max(page, 0)
+	pageCount = min(max(pageCount, 0), 500)
+
+	endpointId, ok := utils.ParseObjectId(c.Param("endpoint_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	kind := c.Query("kind")
+	if kind != "" && !validInventoryKind(kind) {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	changes, count, err := endpoints.GetInventoryChanges(
+		c, db, endpointId, kind, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	dta := &inventoryChangesData{
+		Changes: changes,
+		Count:   count,
+	}
+
+	c.JSON(200, dta)
+}
+
+func inventoryGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+	page = max(page, 0)
+	pageCount = min(max(pageCount, 0), 500)
+
+	kind := c.Query("kind")
+	if kind == "" {
+		kind = endpoints.InventoryPackage
+	}
+	if !validInventoryKind(kind) {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	name := strings.TrimSpace(c.Query("name"))
+	value := strings.TrimSpace(c.Query("value"))
+
+	items, count, err := endpoints.SearchInventory(
+		c, db, kind, name, value, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	endpointIdsSet := set.NewSet()
+	for _, item := range items {
+		endpointIdsSet.Add(item.Endpoint)
+	}
+
+	endpointIds := []bson.ObjectID{}
+	for endpointIdInf := range endpointIdsSet.Iter() {
+		endpointIds = append(endpointIds, endpointIdInf.(bson.ObjectID))
+	}
+
+	endpts, err := endpoint.GetMulti(db, endpointIds)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	endptNames := map[bson.ObjectID]string{}
+	for _, endpt := range endpts {
+		endptNames[endpt.Id] = endpt.Name
+	}
+
+	results := []*inventorySearchItem{}
+	for _, item := range items {
+		results = append(results, &inventorySearchItem{
+			InventoryItem: item,
+			EndpointName:  endptNames[item.Endpoint],
+		})
+	}
+
+	dta := &inventorySearchData{
+		Items: results,
+		Count: count,
+	}
+
+	c.JSON(200, dta)
+}
@@ -0,0 +1,47 @@
+package mhandlers
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/metrics"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+func metricsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	token := settings.System.MetricsToken
+	if token == "" {
+		utils.AbortWithStatus(c, 404)
+		return
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") ||
+		subtle.ConstantTimeCompare(
+			[]byte(strings.TrimPrefix(authHeader, "Bearer ")),
+			[]byte(token)) != 1 {
+
+		utils.AbortWithStatus(c, 401)
+		return
+	}
+
+	families, err := metrics.Export(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(200)
+
+	err = metrics.Write(c.Writer, families)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+}
@@ -36,6 +36,8 @@ type nodeData struct {
 	Authorities          []bson.ObjectID `json:"authorities"`
 	ForwardedForHeader   string          `json:"forwarded_for_header"`
 	ForwardedProtoHeader string          `json:"forwarded_proto_header"`
+	ProxyProtocol        bool            `json:"proxy_protocol"`
+	ProxyProtocolSources []string        `json:"proxy_protocol_sources"`
 }
 
 type nodesData struct {
@@ -87,6 +89,8 @@ func nodePut(c *gin.Context) {
 	nde.Authorities = data.Authorities
 	nde.ForwardedForHeader = data.ForwardedForHeader
 	nde.ForwardedProtoHeader = data.ForwardedProtoHeader
+	nde.ProxyProtocol = data.ProxyProtocol
+	nde.ProxyProtocolSources = data.ProxyProtocolSources
 
 	fields := set.NewSet(
 		"name",
@@ -104,6 +108,8 @@ func nodePut(c *gin.Context) {
 		"authorities",
 		"forwarded_for_header",
 		"forwarded_proto_header",
+		"proxy_protocol",
+		"proxy_protocol_sources",
 	)
 
 	errData, err := nde.Validate(db)
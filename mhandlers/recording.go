@@ -0,0 +1,129 @@
+package mhandlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/authorizer"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/demo"
+	"github.com/pritunl/pritunl-zero/recording"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+type recordingsData struct {
+	Recordings []*recording.Recording `json:"recordings"`
+	Count      int64                  `json:"count"`
+}
+
+func recordingsGet(c *gin.Context) {
+	if demo.IsDemo() {
+		data := &recordingsData{
+			Recordings: []*recording.Recording{},
+			Count:      0,
+		}
+
+		c.JSON(200, data)
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+
+	userId, ok := utils.ParseObjectId(c.Param("user_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	recs, count, err := recording.GetAll(db, userId, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	data := &recordingsData{
+		Recordings: recs,
+		Count:      count,
+	}
+
+	c.JSON(200, data)
+}
+
+func recordingCastGet(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	userId, ok := utils.ParseObjectId(c.Param("user_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	recId, ok := utils.ParseObjectId(c.Param("recording_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	rec, err := recording.Get(db, recId)
+	if err != nil {
+		switch err.(type) {
+		case *database.NotFoundError:
+			utils.AbortWithStatus(c, 404)
+			break
+		default:
+			utils.AbortWithError(c, 500, err)
+		}
+		return
+	}
+
+	if rec.User != userId {
+		utils.AbortWithStatus(c, 404)
+		return
+	}
+
+	authr := c.MustGet("authorizer").(*authorizer.Authorizer)
+	usr, err := authr.GetUser(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = audit.New(
+		db,
+		c.Request,
+		rec.User,
+		audit.RecordingDownload,
+		audit.Fields{
+			"admin_id":     usr.Id,
+			"recording_id": rec.Id,
+			"authority_id": rec.Authority,
+			"destination":  rec.Destination,
+		},
+	)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if c.Query("download") != "" {
+		c.Header("Content-Disposition", fmt.Sprintf(
+			"attachment; filename=\"%s.cast\"", rec.Id.Hex()))
+	}
+	c.Header("Content-Type", "application/x-asciicast")
+	c.Status(200)
+
+	err = recording.WriteCast(db, rec.Id, c.Writer)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+}
@@ -19,13 +19,14 @@ import (
 )
 
 type secretData struct {
-	Id      bson.ObjectID `json:"id"`
-	Name    string        `json:"name"`
-	Comment string        `json:"comment"`
-	Type    string        `json:"type"`
-	Key     string        `json:"key"`
-	Value   string        `json:"value"`
-	Region  string        `json:"region"`
+	Id        bson.ObjectID `json:"id"`
+	Name      string        `json:"name"`
+	Comment   string        `json:"comment"`
+	Type      string        `json:"type"`
+	Key       string        `json:"key"`
+	Value     string        `json:"value"`
+	Region    string        `json:"region"`
+	Algorithm string        `json:"algorithm"`
 }
 
 type secretsData struct {
@@ -68,6 +69,7 @@ func secretPut(c *gin.Context) {
 	secr.Key = data.Key
 	secr.Value = data.Value
 	secr.Region = data.Region
+	secr.Algorithm = data.Algorithm
 
 	fields := set.NewSet(
 		"name",
@@ -76,6 +78,7 @@ func secretPut(c *gin.Context) {
 		"key",
 		"value",
 		"region",
+		"algorithm",
 		"public_key",
 		"private_key",
 	)
@@ -122,12 +125,13 @@ func secretPost(c *gin.Context) {
 	}
 
 	secr := &secret.Secret{
-		Name:    data.Name,
-		Comment: data.Comment,
-		Type:    data.Type,
-		Key:     data.Key,
-		Value:   data.Value,
-		Region:  data.Region,
+		Name:      data.Name,
+		Comment:   data.Comment,
+		Type:      data.Type,
+		Key:       data.Key,
+		Value:     data.Value,
+		Region:    data.Region,
+		Algorithm: data.Algorithm,
 	}
 
 	errData, err := secr.Validate(db)
@@ -5,11 +5,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/cache"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/demo"
 	"github.com/pritunl/pritunl-zero/errortypes"
@@ -28,12 +30,29 @@ type serviceData struct {
 	WebSockets        bool                     `json:"websockets"`
 	DisableCsrfCheck  bool                     `json:"disable_csrf_check"`
 	ClientAuthority   bson.ObjectID            `json:"client_authority"`
+	MtlsMode          string                   `json:"mtls_mode"`
+	MtlsAuthority     bson.ObjectID            `json:"mtls_authority"`
 	Domains           []*service.Domain        `json:"domains"`
 	Roles             []string                 `json:"roles"`
 	Servers           []*service.Server        `json:"servers"`
 	WhitelistNetworks []string                 `json:"whitelist_networks"`
 	WhitelistPaths    []*service.WhitelistPath `json:"whitelist_paths"`
 	WhitelistOptions  bool                     `json:"whitelist_options"`
+	RateLimitUser     int                      `json:"rate_limit_user"`
+	RateLimitSession  int                      `json:"rate_limit_session"`
+	RateLimitIp       int                      `json:"rate_limit_ip"`
+	RateLimitBurst    int                      `json:"rate_limit_burst"`
+	Cache             bool                     `json:"cache"`
+	CacheMemorySize   int                      `json:"cache_memory_size"`
+	CacheDiskSize     int                      `json:"cache_disk_size"`
+	Maintenance       bool                     `json:"maintenance"`
+	MaintenanceStart  time.Time                `json:"maintenance_start"`
+	MaintenanceEnd    time.Time                `json:"maintenance_end"`
+	MaintenancePage   string                   `json:"maintenance_page"`
+	ForbiddenPage     string                   `json:"forbidden_page"`
+	NotFoundPage      string                   `json:"not_found_page"`
+	UnavailablePage   string                   `json:"unavailable_page"`
+	ReplaceErrorPages bool                     `json:"replace_error_pages"`
 }
 
 type servicesData struct {
@@ -78,12 +97,29 @@ func servicePut(c *gin.Context) {
 	srvce.WebSockets = data.WebSockets
 	srvce.DisableCsrfCheck = data.DisableCsrfCheck
 	srvce.ClientAuthority = data.ClientAuthority
+	srvce.MtlsMode = data.MtlsMode
+	srvce.MtlsAuthority = data.MtlsAuthority
 	srvce.Domains = data.Domains
 	srvce.Roles = data.Roles
 	srvce.Servers = data.Servers
 	srvce.WhitelistNetworks = data.WhitelistNetworks
 	srvce.WhitelistPaths = data.WhitelistPaths
 	srvce.WhitelistOptions = data.WhitelistOptions
+	srvce.RateLimitUser = data.RateLimitUser
+	srvce.RateLimitSession = data.RateLimitSession
+	srvce.RateLimitIp = data.RateLimitIp
+	srvce.RateLimitBurst = data.RateLimitBurst
+	srvce.Cache = data.Cache
+	srvce.CacheMemorySize = data.CacheMemorySize
+	srvce.CacheDiskSize = data.CacheDiskSize
+	srvce.Maintenance = data.Maintenance
+	srvce.MaintenanceStart = data.MaintenanceStart
+	srvce.MaintenanceEnd = data.MaintenanceEnd
+	srvce.MaintenancePage = data.MaintenancePage
+	srvce.ForbiddenPage = data.ForbiddenPage
+	srvce.NotFoundPage = data.NotFoundPage
+	srvce.UnavailablePage = data.UnavailablePage
+	srvce.ReplaceErrorPages = data.ReplaceErrorPages
 
 	fields := set.NewSet(
 		"name",
@@ -94,12 +130,29 @@ func servicePut(c *gin.Context) {
 		"websockets",
 		"disable_csrf_check",
 		"client_authority",
+		"mtls_mode",
+		"mtls_authority",
 		"domains",
 		"roles",
 		"servers",
 		"whitelist_networks",
 		"whitelist_paths",
 		"whitelist_options",
+		"rate_limit_user",
+		"rate_limit_session",
+		"rate_limit_ip",
+		"rate_limit_burst",
+		"cache",
+		"cache_memory_size",
+		"cache_disk_size",
+		"maintenance",
+		"maintenance_start",
+		"maintenance_end",
+		"maintenance_page",
+		"forbidden_page",
+		"not_found_page",
+		"unavailable_page",
+		"replace_error_pages",
 	)
 
 	errData, err := srvce.Validate(db)
@@ -153,12 +206,29 @@ func servicePost(c *gin.Context) {
 		WebSockets:        data.WebSockets,
 		DisableCsrfCheck:  data.DisableCsrfCheck,
 		ClientAuthority:   data.ClientAuthority,
+		MtlsMode:          data.MtlsMode,
+		MtlsAuthority:     data.MtlsAuthority,
 		Roles:             data.Roles,
 		Domains:           data.Domains,
 		Servers:           data.Servers,
 		WhitelistNetworks: data.WhitelistNetworks,
 		WhitelistPaths:    data.WhitelistPaths,
 		WhitelistOptions:  data.WhitelistOptions,
+		RateLimitUser:     data.RateLimitUser,
+		RateLimitSession:  data.RateLimitSession,
+		RateLimitIp:       data.RateLimitIp,
+		RateLimitBurst:    data.RateLimitBurst,
+		Cache:             data.Cache,
+		CacheMemorySize:   data.CacheMemorySize,
+		CacheDiskSize:     data.CacheDiskSize,
+		Maintenance:       data.Maintenance,
+		MaintenanceStart:  data.MaintenanceStart,
+		MaintenanceEnd:    data.MaintenanceEnd,
+		MaintenancePage:   data.MaintenancePage,
+		ForbiddenPage:     data.ForbiddenPage,
+		NotFoundPage:      data.NotFoundPage,
+		UnavailablePage:   data.UnavailablePage,
+		ReplaceErrorPages: data.ReplaceErrorPages,
 	}
 
 	errData, err := srvce.Validate(db)
@@ -207,6 +277,28 @@ func serviceDelete(c *gin.Context) {
 	c.JSON(200, nil)
 }
 
+func serviceCacheDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	serviceId, ok := utils.ParseObjectId(c.Param("service_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := cache.PublishPurge(db, serviceId, c.Query("path"))
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, nil)
+}
+
 func servicesDelete(c *gin.Context) {
 	if demo.Blocked(c) {
 		return
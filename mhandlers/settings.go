@@ -27,6 +27,7 @@ type settingsData struct {
 	TwilioAccount             string                        `json:"twilio_account"`
 	TwilioSecret              string                        `json:"twilio_secret"`
 	TwilioNumber              string                        `json:"twilio_number"`
+	MetricsToken              string                        `json:"metrics_token"`
 	ElasticAddress            string                        `json:"elastic_address"`
 	ElasticUsername           string                        `json:"elastic_username"`
 	ElasticPassword           string                        `json:"elastic_password"`
@@ -52,6 +53,7 @@ func getSettingsData() *settingsData {
 		TwilioAccount:             settings.System.TwilioAccount,
 		TwilioSecret:              settings.System.TwilioSecret,
 		TwilioNumber:              settings.System.TwilioNumber,
+		MetricsToken:              settings.System.MetricsToken,
 	}
 
 	if len(settings.Elastic.Addresses) != 0 {
@@ -141,6 +143,11 @@ func settingsPut(c *gin.Context) {
 		fields.Add("twilio_number")
 	}
 
+	if settings.System.MetricsToken != data.MetricsToken {
+		settings.System.MetricsToken = data.MetricsToken
+		fields.Add("metrics_token")
+	}
+
 	if fields.Len() != 0 {
 		err = settings.Commit(db, settings.System, fields)
 		if err != nil {
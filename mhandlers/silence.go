@@ -0,0 +1,284 @@
+package mhandlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/authorizer"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/demo"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/silence"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+type silenceData struct {
+	Id         bson.ObjectID   `json:"id"`
+	Name       string          `json:"name"`
+	Comment    string          `json:"comment"`
+	Roles      []string        `json:"roles"`
+	Endpoints  []bson.ObjectID `json:"endpoints"`
+	Names      []string        `json:"names"`
+	Resources  []string        `json:"resources"`
+	Start      time.Time       `json:"start"`
+	End        time.Time       `json:"end"`
+	Recurrence string          `json:"recurrence"`
+	Until      time.Time       `json:"until"`
+}
+
+type silencesData struct {
+	Silences []*silence.Silence `json:"silences"`
+	Count    int64              `json:"count"`
+}
+
+func silencePut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &silenceData{}
+
+	silenceId, ok := utils.ParseObjectId(c.Param("silence_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	silnc, err := silence.Get(db, silenceId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	silnc.Name = data.Name
+	silnc.Comment = data.Comment
+	silnc.Roles = data.Roles
+	silnc.Endpoints = data.Endpoints
+	silnc.Names = data.Names
+	silnc.Resources = data.Resources
+	silnc.Start = data.Start
+	silnc.End = data.End
+	silnc.Recurrence = data.Recurrence
+	silnc.Until = data.Until
+
+	fields := set.NewSet(
+		"name",
+		"comment",
+		"roles",
+		"endpoints",
+		"names",
+		"resources",
+		"start",
+		"end",
+		"recurrence",
+		"until",
+		"expires",
+	)
+
+	errData, err := silnc.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = silnc.CommitFields(db, fields)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	silnc.Format(time.Now())
+
+	_ = event.PublishDispatch(db, "silence.change")
+
+	c.JSON(200, silnc)
+}
+
+func silencePost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	authr := c.MustGet("authorizer").(*authorizer.Authorizer)
+	data := &silenceData{
+		Name: "New Silence",
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	usr, err := authr.GetUser(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	silnc := &silence.Silence{
+		Name:        data.Name,
+		Comment:     data.Comment,
+		Roles:       data.Roles,
+		Endpoints:   data.Endpoints,
+		Names:       data.Names,
+		Resources:   data.Resources,
+		Start:       data.Start,
+		End:         data.End,
+		Recurrence:  data.Recurrence,
+		Until:       data.Until,
+		Creator:     usr.Id,
+		CreatorName: usr.Username,
+		Timestamp:   time.Now(),
+	}
+
+	errData, err := silnc.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = silnc.Insert(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	silnc.Format(time.Now())
+
+	_ = event.PublishDispatch(db, "silence.change")
+
+	c.JSON(200, silnc)
+}
+
+func silenceDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	silenceId, ok := utils.ParseObjectId(c.Param("silence_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := silence.Remove(db, silenceId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "silence.change")
+
+	c.JSON(200, nil)
+}
+
+func silencesDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	dta := []bson.ObjectID{}
+
+	err := c.Bind(&dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = silence.RemoveMulti(db, dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "silence.change")
+
+	c.JSON(200, nil)
+}
+
+func silenceGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	silenceId, ok := utils.ParseObjectId(c.Param("silence_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	silnc, err := silence.Get(db, silenceId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, silnc)
+}
+
+func silencesGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+
+	query := bson.M{}
+
+	switch c.Query("expired") {
+	case "true":
+		query["expires"] = &bson.M{
+			"$lte": time.Now(),
+		}
+		break
+	case "false":
+		query["expires"] = &bson.M{
+			"$gt": time.Now(),
+		}
+		break
+	}
+
+	silences, count, err := silence.GetAllPaged(
+		db, &query, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	dta := &silencesData{
+		Silences: silences,
+		Count:    count,
+	}
+
+	c.JSON(200, dta)
+}
@@ -11,10 +11,12 @@ import (
 	"github.com/dropbox/godropbox/errors"
 	"github.com/gin-gonic/gin"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/authorizer"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/demo"
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/lockout"
 	"github.com/pritunl/pritunl-zero/user"
 	"github.com/pritunl/pritunl-zero/utils"
 )
@@ -30,6 +32,7 @@ type userData struct {
 	GenerateSecret bool          `json:"generate_secret"`
 	Disabled       bool          `json:"disabled"`
 	ActiveUntil    time.Time     `json:"active_until"`
+	Locked         *bool         `json:"locked"`
 }
 
 type usersData struct {
@@ -60,6 +63,7 @@ func userGet(c *gin.Context) {
 	}
 
 	usr.Secret = ""
+	usr.Locked = usr.IsLocked()
 
 	c.JSON(200, usr)
 }
@@ -184,11 +188,28 @@ func userPut(c *gin.Context) {
 		return
 	}
 
+	if data.Locked != nil && *data.Locked != usr.IsLocked() {
+		authr := c.MustGet("authorizer").(*authorizer.Authorizer)
+
+		adminUsr, err := authr.GetUser(db)
+		if err != nil {
+			utils.AbortWithError(c, 500, err)
+			return
+		}
+
+		err = lockout.SetLocked(db, c.Request, usr, adminUsr, *data.Locked)
+		if err != nil {
+			utils.AbortWithError(c, 500, err)
+			return
+		}
+	}
+
 	_ = event.PublishDispatch(db, "user.change")
 
 	if !showSecret {
 		usr.Secret = ""
 	}
+	usr.Locked = usr.IsLocked()
 
 	c.JSON(200, usr)
 }
@@ -360,6 +381,7 @@ func usersGet(c *gin.Context) {
 
 	for _, usr := range users {
 		usr.Secret = ""
+		usr.Locked = usr.IsLocked()
 	}
 
 	data := &usersData{
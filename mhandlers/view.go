@@ -0,0 +1,250 @@
+package mhandlers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/demo"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/utils"
+	"github.com/pritunl/pritunl-zero/view"
+)
+
+type viewData struct {
+	Id      bson.ObjectID `json:"id"`
+	Name    string        `json:"name"`
+	Comment string        `json:"comment"`
+	Query   string        `json:"query"`
+}
+
+type viewsData struct {
+	Views []*view.View `json:"views"`
+	Count int64        `json:"count"`
+}
+
+func viewPut(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &viewData{}
+
+	viewId, ok := utils.ParseObjectId(c.Param("view_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	vw, err := view.Get(db, viewId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	vw.Name = data.Name
+	vw.Comment = data.Comment
+	vw.Query = data.Query
+
+	fields := set.NewSet(
+		"name",
+		"comment",
+		"query",
+	)
+
+	errData, err := vw.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = vw.CommitFields(db, fields)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "view.change")
+	_ = event.PublishDispatch(db, "endpoint.change")
+
+	c.JSON(200, vw)
+}
+
+func viewPost(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	data := &viewData{
+		Name: "New View",
+	}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	vw := &view.View{
+		Name:    data.Name,
+		Comment: data.Comment,
+		Query:   data.Query,
+	}
+
+	errData, err := vw.Validate(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	err = vw.Insert(db)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "view.change")
+
+	c.JSON(200, vw)
+}
+
+func viewDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+
+	viewId, ok := utils.ParseObjectId(c.Param("view_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	err := view.Remove(db, viewId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "view.change")
+	_ = event.PublishDispatch(db, "alert.change")
+	_ = event.PublishDispatch(db, "check.change")
+
+	c.JSON(200, nil)
+}
+
+func viewsDelete(c *gin.Context) {
+	if demo.Blocked(c) {
+		return
+	}
+
+	db := c.MustGet("db").(*database.Database)
+	dta := []bson.ObjectID{}
+
+	err := c.Bind(&dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	err = view.RemoveMulti(db, dta)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	_ = event.PublishDispatch(db, "view.change")
+	_ = event.PublishDispatch(db, "alert.change")
+	_ = event.PublishDispatch(db, "check.change")
+
+	c.JSON(200, nil)
+}
+
+func viewGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	viewId, ok := utils.ParseObjectId(c.Param("view_id"))
+	if !ok {
+		utils.AbortWithStatus(c, 400)
+		return
+	}
+
+	vw, err := view.Get(db, viewId)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	c.JSON(200, vw)
+}
+
+func viewsGet(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	page, _ := strconv.ParseInt(c.Query("page"), 10, 0)
+	pageCount, _ := strconv.ParseInt(c.Query("page_count"), 10, 0)
+
+	query := bson.M{}
+
+	viewId, ok := utils.ParseObjectId(c.Query("id"))
+	if ok {
+		query["_id"] = viewId
+	}
+
+	name := strings.TrimSpace(c.Query("name"))
+	if name != "" {
+		query["$or"] = []*bson.M{
+			&bson.M{
+				"name": &bson.M{
+					"$regex":   fmt.Sprintf(".*%s.*", regexp.QuoteMeta(name)),
+					"$options": "i",
+				},
+			},
+		}
+	}
+
+	views, count, err := view.GetAllPaged(
+		db, &query, page, pageCount)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	dta := &viewsData{
+		Views: views,
+		Count: count,
+	}
+
+	c.JSON(200, dta)
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/pritunl-zero/audit"
 	"github.com/pritunl/pritunl-zero/auth"
 	"github.com/pritunl/pritunl-zero/authority"
@@ -16,8 +17,10 @@ import (
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/ratelimit"
 	"github.com/pritunl/pritunl-zero/service"
 	"github.com/pritunl/pritunl-zero/session"
+	"github.com/pritunl/pritunl-zero/settings"
 	"github.com/pritunl/pritunl-zero/utils"
 	"github.com/pritunl/pritunl-zero/validator"
 	"github.com/sirupsen/logrus"
@@ -42,6 +45,29 @@ func Database(c *gin.Context) {
 	db.Close()
 }
 
+func RateLimitAuth(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+
+	allowed, retry, err := ratelimit.Check(
+		db,
+		c.Request,
+		bson.NilObjectID,
+		ratelimit.Ip,
+		node.Self.GetRemoteAddr(c.Request),
+		settings.RateLimit.AuthIpRequests,
+		settings.RateLimit.AuthIpBurst,
+	)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if !allowed {
+		ratelimit.Abort(c, retry)
+		return
+	}
+}
+
 func Headers(c *gin.Context) {
 	headers := c.Writer.Header()
 
@@ -2,9 +2,11 @@ package node
 
 import (
 	"container/list"
+	"net"
 	"net/http"
 	"os"
 	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -49,8 +51,14 @@ type Node struct {
 	Services             []bson.ObjectID            `bson:"services" json:"services"`
 	Authorities          []bson.ObjectID            `bson:"authorities" json:"authorities"`
 	RequestsMin          int64                      `bson:"requests_min" json:"requests_min"`
+	ServiceRequests      map[string]int64           `bson:"service_requests" json:"-"`
+	ServiceCacheHits     map[string]int64           `bson:"service_cache_hits" json:"-"`
+	ServiceCacheMisses   map[string]int64           `bson:"service_cache_misses" json:"-"`
+	Logins               map[string]int64           `bson:"logins" json:"-"`
 	ForwardedForHeader   string                     `bson:"forwarded_for_header" json:"forwarded_for_header"`
 	ForwardedProtoHeader string                     `bson:"forwarded_proto_header" json:"forwarded_proto_header"`
+	ProxyProtocol        bool                       `bson:"proxy_protocol" json:"proxy_protocol"`
+	ProxyProtocolSources []string                   `bson:"proxy_protocol_sources" json:"proxy_protocol_sources"`
 	Memory               float64                    `bson:"memory" json:"memory"`
 	Load1                float64                    `bson:"load1" json:"load1"`
 	Load5                float64                    `bson:"load5" json:"load5"`
@@ -60,6 +68,10 @@ type Node struct {
 	Version              int                        `bson:"version" json:"-"`
 	CertificateObjs      []*certificate.Certificate `bson:"-" json:"-"`
 	reqCount             *list.List                 `bson:"-" json:"-"`
+	srvcCount            map[bson.ObjectID]int64    `bson:"-" json:"-"`
+	srvcCacheHits        map[bson.ObjectID]int64    `bson:"-" json:"-"`
+	srvcCacheMisses      map[bson.ObjectID]int64    `bson:"-" json:"-"`
+	loginCount           map[string]int64           `bson:"-" json:"-"`
 	lock                 sync.Mutex                 `bson:"-" json:"-"`
 }
 
@@ -86,8 +98,14 @@ func (n *Node) Copy() *Node {
 		Services:             n.Services,
 		Authorities:          n.Authorities,
 		RequestsMin:          n.RequestsMin,
+		ServiceRequests:      n.ServiceRequests,
+		ServiceCacheHits:     n.ServiceCacheHits,
+		ServiceCacheMisses:   n.ServiceCacheMisses,
+		Logins:               n.Logins,
 		ForwardedForHeader:   n.ForwardedForHeader,
 		ForwardedProtoHeader: n.ForwardedProtoHeader,
+		ProxyProtocol:        n.ProxyProtocol,
+		ProxyProtocolSources: n.ProxyProtocolSources,
 		Memory:               n.Memory,
 		Load1:                n.Load1,
 		Load5:                n.Load5,
@@ -171,6 +189,30 @@ func (n *Node) AddRequest() {
 	n.lock.Unlock()
 }
 
+func (n *Node) AddServiceRequest(srvcId bson.ObjectID) {
+	n.lock.Lock()
+	n.srvcCount[srvcId] += 1
+	n.lock.Unlock()
+}
+
+func (n *Node) AddServiceCacheHit(srvcId bson.ObjectID) {
+	n.lock.Lock()
+	n.srvcCacheHits[srvcId] += 1
+	n.lock.Unlock()
+}
+
+func (n *Node) AddServiceCacheMiss(srvcId bson.ObjectID) {
+	n.lock.Lock()
+	n.srvcCacheMisses[srvcId] += 1
+	n.lock.Unlock()
+}
+
+func (n *Node) AddLogin(typ string) {
+	n.lock.Lock()
+	n.loginCount[typ] += 1
+	n.lock.Unlock()
+}
+
 func (n *Node) GetWebauthn(origin string, strict bool) (
 	web *webauthn.WebAuthn, err error) {
 
@@ -332,6 +374,29 @@ func (n *Node) Validate(db *database.Database) (
 		n.Authorities = []bson.ObjectID{}
 	}
 
+	newProxyProtocolSources := []string{}
+	for _, cidr := range n.ProxyProtocolSources {
+		_, ipNet, e := net.ParseCIDR(strings.TrimSpace(cidr))
+		if e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "proxy_protocol_source_invalid",
+				Message: "PROXY protocol source not a valid subnet",
+			}
+			return
+		}
+		newProxyProtocolSources = append(
+			newProxyProtocolSources, ipNet.String())
+	}
+	n.ProxyProtocolSources = newProxyProtocolSources
+
+	if n.ProxyProtocol && len(n.ProxyProtocolSources) == 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "proxy_protocol_sources_required",
+			Message: "PROXY protocol requires trusted source subnets",
+		}
+		return
+	}
+
 	n.Format()
 
 	return
@@ -340,6 +405,7 @@ func (n *Node) Validate(db *database.Database) (
 func (n *Node) Format() {
 	utils.SortObjectIds(n.Services)
 	utils.SortObjectIds(n.Certificates)
+	sort.Strings(n.ProxyProtocolSources)
 }
 
 func (n *Node) SetActive() {
@@ -425,13 +491,17 @@ func (n *Node) update(db *database.Database) (err error) {
 		},
 		&bson.M{
 			"$set": &bson.M{
-				"timestamp":    n.Timestamp,
-				"requests_min": n.RequestsMin,
-				"memory":       n.Memory,
-				"load1":        n.Load1,
-				"load5":        n.Load5,
-				"load15":       n.Load15,
-				"hostname":     n.Hostname,
+				"timestamp":            n.Timestamp,
+				"requests_min":         n.RequestsMin,
+				"service_requests":     n.ServiceRequests,
+				"service_cache_hits":   n.ServiceCacheHits,
+				"service_cache_misses": n.ServiceCacheMisses,
+				"logins":               n.Logins,
+				"memory":               n.Memory,
+				"load1":                n.Load1,
+				"load5":                n.Load5,
+				"load15":               n.Load15,
+				"hostname":             n.Hostname,
 			},
 		},
 		opts,
@@ -459,6 +529,8 @@ func (n *Node) update(db *database.Database) (err error) {
 	n.Authorities = nde.Authorities
 	n.ForwardedForHeader = nde.ForwardedForHeader
 	n.ForwardedProtoHeader = nde.ForwardedProtoHeader
+	n.ProxyProtocol = nde.ProxyProtocol
+	n.ProxyProtocolSources = nde.ProxyProtocolSources
 
 	return
 }
@@ -538,6 +610,44 @@ func (n *Node) sync() (nde *Node) {
 	}
 	n.Hostname = hostname
 
+	srvcCount := map[bson.ObjectID]int64{}
+	srvcRequests := map[string]int64{}
+	srvcCacheHits := map[bson.ObjectID]int64{}
+	srvcCacheHitsHex := map[string]int64{}
+	srvcCacheMisses := map[bson.ObjectID]int64{}
+	srvcCacheMissesHex := map[string]int64{}
+	loginCount := map[string]int64{}
+	logins := map[string]int64{}
+	if Self != nil {
+		Self.lock.Lock()
+		for srvcId, count := range Self.srvcCount {
+			srvcCount[srvcId] = count
+			srvcRequests[srvcId.Hex()] = count
+		}
+		for srvcId, count := range Self.srvcCacheHits {
+			srvcCacheHits[srvcId] = count
+			srvcCacheHitsHex[srvcId.Hex()] = count
+		}
+		for srvcId, count := range Self.srvcCacheMisses {
+			srvcCacheMisses[srvcId] = count
+			srvcCacheMissesHex[srvcId.Hex()] = count
+		}
+		for typ, count := range Self.loginCount {
+			loginCount[typ] = count
+			logins[typ] = count
+		}
+		Self.lock.Unlock()
+	}
+
+	n.srvcCount = srvcCount
+	n.ServiceRequests = srvcRequests
+	n.srvcCacheHits = srvcCacheHits
+	n.ServiceCacheHits = srvcCacheHitsHex
+	n.srvcCacheMisses = srvcCacheMisses
+	n.ServiceCacheMisses = srvcCacheMissesHex
+	n.loginCount = loginCount
+	n.Logins = logins
+
 	err = n.update(db)
 	if err != nil {
 		logrus.WithFields(logrus.Fields{
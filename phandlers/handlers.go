@@ -39,18 +39,21 @@ func Register(prxy *proxy.Proxy, engine *gin.Engine) {
 	dbGroup := engine.Group("")
 	dbGroup.Use(middlewear.Database)
 
+	limitGroup := dbGroup.Group("")
+	limitGroup.Use(middlewear.RateLimitAuth)
+
 	sessGroup := dbGroup.Group("")
 	sessGroup.Use(middlewear.SessionProxy)
 
 	engine.GET("/auth/state", authStateGet)
-	dbGroup.POST("/auth/session", authSessionPost)
-	dbGroup.POST("/auth/secondary", authSecondaryPost)
-	dbGroup.GET("/auth/request", authRequestGet)
-	dbGroup.GET("/auth/callback", authCallbackGet)
+	limitGroup.POST("/auth/session", authSessionPost)
+	limitGroup.POST("/auth/secondary", authSecondaryPost)
+	limitGroup.GET("/auth/request", authRequestGet)
+	limitGroup.GET("/auth/callback", authCallbackGet)
 	dbGroup.GET("/auth/webauthn/request", authWanRequestGet)
-	dbGroup.POST("/auth/webauthn/respond", authWanRespondPost)
+	limitGroup.POST("/auth/webauthn/respond", authWanRespondPost)
 	dbGroup.GET("/auth/webauthn/register", authWanRegisterGet)
-	dbGroup.POST("/auth/webauthn/register", authWanRegisterPost)
+	limitGroup.POST("/auth/webauthn/register", authWanRegisterPost)
 	sessGroup.GET("/logout", logoutGet)
 
 	engine.GET("/check", checkGet)
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/service"
+	"github.com/pritunl/pritunl-zero/user"
+	"github.com/sirupsen/logrus"
+)
+
+type pageData struct {
+	Service        string
+	RequestId      string
+	User           string
+	Status         int
+	StatusText     string
+	MaintenanceEnd time.Time
+}
+
+type pages struct {
+	srvc        *service.Service
+	maintenance *template.Template
+	forbidden   *template.Template
+	notFound    *template.Template
+	unavailable *template.Template
+}
+
+// Write the page template, returns false when the service does not have
+// a custom page and the default response should be written
+func (p *pages) write(w http.ResponseWriter, r *http.Request,
+	tmpl *template.Template, status int, usr *user.User) bool {
+
+	if p == nil || tmpl == nil {
+		return false
+	}
+
+	data := &pageData{
+		Service:        p.srvc.Name,
+		RequestId:      getRequestId(r),
+		Status:         status,
+		StatusText:     http.StatusText(status),
+		MaintenanceEnd: p.srvc.MaintenanceEnd,
+	}
+	if usr != nil {
+		data.User = usr.Username
+	}
+
+	buf := &bytes.Buffer{}
+	err := tmpl.Execute(buf, data)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"service_id": p.srvc.Id.Hex(),
+			"status":     status,
+			"error":      err,
+		}).Error("proxy: Failed to render service page")
+		return false
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Request-Id", data.RequestId)
+	w.WriteHeader(status)
+	_, _ = w.Write(buf.Bytes())
+
+	return true
+}
+
+func (p *pages) Maintenance(w http.ResponseWriter, r *http.Request) bool {
+	if p == nil {
+		return false
+	}
+
+	if !p.srvc.MaintenanceEnd.IsZero() {
+		retry := int(time.Until(p.srvc.MaintenanceEnd).Seconds())
+		if retry > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+		}
+	}
+
+	return p.write(w, r, p.maintenance, 503, nil)
+}
+
+func (p *pages) Forbidden(w http.ResponseWriter, r *http.Request,
+	usr *user.User) bool {
+
+	if p == nil {
+		return false
+	}
+
+	return p.write(w, r, p.forbidden, 403, usr)
+}
+
+func (p *pages) NotFound(w http.ResponseWriter, r *http.Request) bool {
+	if p == nil {
+		return false
+	}
+
+	return p.write(w, r, p.notFound, 404, nil)
+}
+
+func (p *pages) Unavailable(w http.ResponseWriter, r *http.Request,
+	status int, usr *user.User) bool {
+
+	if p == nil {
+		return false
+	}
+
+	return p.write(w, r, p.unavailable, status, usr)
+}
+
+func getRequestId(r *http.Request) string {
+	reqId := r.Header.Get("X-Request-Id")
+	if reqId == "" {
+		reqId = bson.NewObjectID().Hex()
+		r.Header.Set("X-Request-Id", reqId)
+	}
+
+	return reqId
+}
+
+func newPages(srvc *service.Service) (p *pages, err error) {
+	p = &pages{
+		srvc: srvc,
+	}
+
+	p.maintenance, err = service.ParsePage(srvc.MaintenancePage)
+	if err != nil {
+		return
+	}
+
+	p.forbidden, err = service.ParsePage(srvc.ForbiddenPage)
+	if err != nil {
+		return
+	}
+
+	p.notFound, err = service.ParsePage(srvc.NotFoundPage)
+	if err != nil {
+		return
+	}
+
+	p.unavailable, err = service.ParsePage(srvc.UnavailablePage)
+	if err != nil {
+		return
+	}
+
+	return
+}
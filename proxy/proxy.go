@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"math/rand"
 	"net"
@@ -16,9 +17,11 @@ import (
 	"github.com/pritunl/pritunl-zero/auth"
 	"github.com/pritunl/pritunl-zero/authority"
 	"github.com/pritunl/pritunl-zero/authorizer"
+	"github.com/pritunl/pritunl-zero/cache"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/ratelimit"
 	"github.com/pritunl/pritunl-zero/service"
 	"github.com/pritunl/pritunl-zero/session"
 	"github.com/pritunl/pritunl-zero/settings"
@@ -34,6 +37,9 @@ type Host struct {
 	WhitelistNetworks []*net.IPNet
 	ClientAuthority   *authority.Authority
 	ClientCertificate *tls.Certificate
+	MtlsCertPool      *x509.CertPool
+	Cache             *cache.Cache
+	Pages             *pages
 }
 
 type Proxy struct {
@@ -59,6 +65,17 @@ func (p *Proxy) MatchHost(domain string) (hst *Host, wildcard bool) {
 	return
 }
 
+// Get the certificate pool used to verify client certificates for the
+// domain, nil when the service does not use client certificates
+func (p *Proxy) GetClientCertPool(domain string) *x509.CertPool {
+	host, _ := p.MatchHost(domain)
+	if host == nil {
+		return nil
+	}
+
+	return host.MtlsCertPool
+}
+
 func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 	host, wildcard := p.MatchHost(utils.StripPort(r.Host))
 
@@ -94,10 +111,23 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 
+		if host != nil && host.Pages.Unavailable(w, r, 503, nil) {
+			return true
+		}
+
 		utils.WriteStatus(w, 404)
 		return true
 	}
 
+	node.Self.AddServiceRequest(host.Service.Id)
+
+	if host.Service.IsMaintenance() {
+		if !host.Pages.Maintenance(w, r) {
+			utils.WriteStatus(w, 503)
+		}
+		return true
+	}
+
 	if !host.Service.DisableCsrfCheck {
 		valid := auth.CsrfCheck(w, r, host.Domain.Domain, wildcard)
 		if !valid {
@@ -108,6 +138,13 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 	db := database.GetDatabase()
 	defer db.Close()
 
+	if !checkRateLimit(db, w, r, host.Service, bson.NilObjectID,
+		ratelimit.Ip, node.Self.GetRemoteAddr(r),
+		host.Service.RateLimitIp) {
+
+		return true
+	}
+
 	remoteAddr, addrHeader, addrValid := node.Self.SafeGetRemoteAddr(r)
 	if !addrValid && len(host.WhitelistNetworks) > 0 {
 		logrus.WithFields(logrus.Fields{
@@ -146,8 +183,19 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 							return true
 						}
 
-						wProxies[rand.Intn(wLen)].ServeHTTP(
-							w, r, authorizer.NewProxy(nil))
+						prxy := wProxies[rand.Intn(wLen)]
+						if host.Cache != nil {
+							host.Cache.ServeHTTP(w, r, "",
+								func(rw http.ResponseWriter,
+									req *http.Request) {
+
+									prxy.ServeHTTP(rw, req,
+										authorizer.NewProxy(nil))
+								})
+							return true
+						}
+
+						prxy.ServeHTTP(w, r, authorizer.NewProxy(nil))
 						return true
 					}
 				}
@@ -184,7 +232,7 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 
-		return false
+		return mtlsRequired(w, r, host)
 	}
 
 	usr, err := authr.GetUser(db)
@@ -200,7 +248,7 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 
-		return false
+		return mtlsRequired(w, r, host)
 	}
 
 	active, err := auth.SyncUser(db, usr)
@@ -222,7 +270,7 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 
-		return false
+		return mtlsRequired(w, r, host)
 	}
 
 	_, _, errAudit, errData, err := validator.ValidateProxy(
@@ -259,7 +307,24 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 
-		return false
+		if host.Pages.Forbidden(w, r, usr) {
+			return true
+		}
+
+		return mtlsRequired(w, r, host)
+	}
+
+	if !checkRateLimit(db, w, r, host.Service, usr.Id,
+		ratelimit.User, usr.Id.Hex(), host.Service.RateLimitUser) {
+
+		return true
+	}
+
+	sessionId := authr.SessionId()
+	if sessionId != "" && !checkRateLimit(db, w, r, host.Service, usr.Id,
+		ratelimit.Session, sessionId, host.Service.RateLimitSession) {
+
+		return true
 	}
 
 	if wsLen != 0 && strings.ToLower(r.Header.Get("Upgrade")) == "websocket" {
@@ -288,7 +353,16 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	}
 
-	wProxies[rand.Intn(wLen)].ServeHTTP(w, r, authr)
+	prxy := wProxies[rand.Intn(wLen)]
+	if host.Cache != nil {
+		host.Cache.ServeHTTP(w, r, usr.Id.Hex(),
+			func(rw http.ResponseWriter, req *http.Request) {
+				prxy.ServeHTTP(rw, req, authr)
+			})
+		return true
+	}
+
+	prxy.ServeHTTP(w, r, authr)
 	return true
 }
 
@@ -336,9 +410,29 @@ func (p *Proxy) reloadHosts(db *database.Database,
 		return
 	}
 
+	cacheServices := set.NewSet()
 	for _, srvc := range srvcs {
 		nodeService := nodeServices.Contains(srvc.Id)
 
+		var srvcCache *cache.Cache
+		var srvcPages *pages
+		if nodeService {
+			srvcCache = cache.Get(srvc)
+			if srvcCache != nil {
+				cacheServices.Add(srvc.Id)
+			}
+
+			srvcPages, err = newPages(srvc)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"service_id": srvc.Id.Hex(),
+					"error":      err,
+				}).Error("proxy: Failed to load service pages")
+				err = nil
+				srvcPages = nil
+			}
+		}
+
 		for _, domain := range srvc.Domains {
 			facets = append(facets, fmt.Sprintf("https://%s", domain.Domain))
 
@@ -391,6 +485,14 @@ func (p *Proxy) reloadHosts(db *database.Database,
 				}
 			}
 
+			var mtlsPool *x509.CertPool
+			if srvc.MtlsMode != "" {
+				mtlsPool, err = loadMtlsPool(db, srvc)
+				if err != nil {
+					return
+				}
+			}
+
 			srvcDomain := &Host{
 				Id:                bson.NewObjectID(),
 				Service:           srvc,
@@ -398,6 +500,9 @@ func (p *Proxy) reloadHosts(db *database.Database,
 				WhitelistNetworks: whitelistNets,
 				ClientAuthority:   clientAuthr,
 				ClientCertificate: cert,
+				MtlsCertPool:      mtlsPool,
+				Cache:             srvcCache,
+				Pages:             srvcPages,
 			}
 
 			if strings.Contains(domain.Domain, "*") {
@@ -408,6 +513,8 @@ func (p *Proxy) reloadHosts(db *database.Database,
 		}
 	}
 
+	cache.Prune(cacheServices)
+
 	settings.Local.AppId = appId
 	settings.Local.Facets = facets
 
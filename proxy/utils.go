@@ -1,13 +1,19 @@
 package proxy
 
 import (
+	"crypto/x509"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/authority"
+	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/ratelimit"
+	"github.com/pritunl/pritunl-zero/service"
 	"github.com/pritunl/pritunl-zero/utils"
 	"github.com/sirupsen/logrus"
 )
@@ -45,6 +51,25 @@ func WriteErrorLog(w http.ResponseWriter, r *http.Request, code int,
 	}).Error("proxy: Serve error")
 }
 
+func checkRateLimit(db *database.Database, w http.ResponseWriter,
+	r *http.Request, srvc *service.Service, userId bson.ObjectID,
+	scope, key string, requests int) (allowed bool) {
+
+	allowed, retry, err := ratelimit.Check(db, r, userId, scope,
+		srvc.Id.Hex()+":"+key, requests, srvc.RateLimitBurst)
+	if err != nil {
+		WriteErrorLog(w, r, 500, err)
+		allowed = false
+		return
+	}
+
+	if !allowed {
+		ratelimit.Write(w, retry)
+	}
+
+	return
+}
+
 func stripCookieHeaders(r *http.Request) {
 	r.Header.Del("Pritunl-Zero-Token")
 	r.Header.Del("Pritunl-Zero-Signature")
@@ -74,3 +99,58 @@ func stripCookieHeaders(r *http.Request) {
 		r.Header.Del("Cookie")
 	}
 }
+
+func loadMtlsPool(db *database.Database, srvc *service.Service) (
+	pool *x509.CertPool, err error) {
+
+	authr, err := authority.Get(db, srvc.MtlsAuthority)
+	if err != nil {
+		if _, ok := err.(*database.NotFoundError); ok {
+			err = nil
+
+			logrus.WithFields(logrus.Fields{
+				"service_id":        srvc.Id.Hex(),
+				"mtls_authority_id": srvc.MtlsAuthority.Hex(),
+			}).Warn("proxy: Service mtls authority not found")
+		}
+		return
+	}
+
+	rootCert, err := authr.GetRootCertificate()
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"service_id":        srvc.Id.Hex(),
+			"mtls_authority_id": srvc.MtlsAuthority.Hex(),
+			"error":             err,
+		}).Error("proxy: Failed to load service mtls authority")
+		err = nil
+		return
+	}
+
+	pool = x509.NewCertPool()
+	pool.AddCert(rootCert)
+
+	return
+}
+
+// Reject requests without a valid client certificate on services that
+// require client certificate authentication instead of redirecting to the
+// login page, connections reused from another domain are sent back to
+// the client to reconnect with a certificate
+func mtlsRequired(w http.ResponseWriter, r *http.Request,
+	host *Host) bool {
+
+	if host.Service.MtlsMode != service.MtlsRequired {
+		return false
+	}
+
+	if r.TLS != nil && r.TLS.ServerName != "" && !strings.EqualFold(
+		r.TLS.ServerName, utils.StripPort(r.Host)) {
+
+		utils.WriteStatus(w, 421)
+		return true
+	}
+
+	utils.WriteStatus(w, 401)
+	return true
+}
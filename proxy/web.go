@@ -22,6 +22,7 @@ import (
 	"github.com/pritunl/pritunl-zero/searches"
 	"github.com/pritunl/pritunl-zero/service"
 	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/pritunl/pritunl-zero/user"
 	"github.com/pritunl/pritunl-zero/utils"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/http2"
@@ -33,13 +34,79 @@ type web struct {
 	serverProto string
 	proxyProto  string
 	proxyPort   int
+	pages       *pages
 	Transport   http.RoundTripper
 	ErrorLog    *log.Logger
 }
 
+// Upstream error response replaced by a service page
+type pageError struct {
+	status int
+}
+
+func (e *pageError) Error() string {
+	return fmt.Sprintf("proxy: Upstream error status %d", e.status)
+}
+
+// Replace upstream error responses to html requests with the service
+// pages, upstream responses are passed through unless the service enables
+// replacing error pages
+func (w *web) modifyResponse(resp *http.Response) error {
+	if w.pages == nil || !w.pages.srvc.ReplaceErrorPages ||
+		!strings.Contains(resp.Request.Header.Get("Accept"), "text/html") {
+
+		return nil
+	}
+
+	switch resp.StatusCode {
+	case 404:
+		if w.pages.notFound != nil {
+			return &pageError{status: resp.StatusCode}
+		}
+		break
+	case 502, 503:
+		if w.pages.unavailable != nil {
+			return &pageError{status: resp.StatusCode}
+		}
+		break
+	}
+
+	return nil
+}
+
+func (w *web) errorHandler(rw http.ResponseWriter, r *http.Request,
+	usr *user.User, err error) {
+
+	if pageErr, ok := err.(*pageError); ok {
+		written := false
+		if pageErr.status == 404 {
+			written = w.pages.NotFound(rw, r)
+		} else {
+			written = w.pages.Unavailable(rw, r, pageErr.status, usr)
+		}
+
+		if !written {
+			rw.WriteHeader(pageErr.status)
+		}
+		return
+	}
+
+	w.ErrorLog.Printf("http: proxy error: %v (request %s)",
+		err, getRequestId(r))
+
+	if !w.pages.Unavailable(rw, r, http.StatusBadGateway, usr) {
+		rw.WriteHeader(http.StatusBadGateway)
+	}
+}
+
 func (w *web) ServeHTTP(rw http.ResponseWriter, r *http.Request,
 	authr *authorizer.Authorizer) {
 
+	var usr *user.User
+	if authr != nil {
+		usr, _ = authr.GetUser(nil)
+	}
+
 	prxy := &httputil.ReverseProxy{
 		Director: func(req *http.Request) {
 			req.Header.Set("X-Forwarded-For",
@@ -48,11 +115,8 @@ func (w *web) ServeHTTP(rw http.ResponseWriter, r *http.Request,
 			req.Header.Set("X-Forwarded-Proto", w.proxyProto)
 			req.Header.Set("X-Forwarded-Port", strconv.Itoa(w.proxyPort))
 
-			if authr != nil {
-				usr, _ := authr.GetUser(nil)
-				if usr != nil {
-					req.Header.Set("X-Forwarded-User", usr.Username)
-				}
+			if usr != nil {
+				req.Header.Set("X-Forwarded-User", usr.Username)
 			}
 
 			if w.reqHost != "" {
@@ -101,6 +165,12 @@ func (w *web) ServeHTTP(rw http.ResponseWriter, r *http.Request,
 				index.Index()
 			}
 		},
+		ModifyResponse: w.modifyResponse,
+		ErrorHandler: func(rw http.ResponseWriter, r *http.Request,
+			err error) {
+
+			w.errorHandler(rw, r, usr, err)
+		},
 		Transport: w.Transport,
 		ErrorLog:  w.ErrorLog,
 	}
@@ -228,6 +298,7 @@ func newWeb(proxyProto string, proxyPort int, host *Host,
 		serverHost:  utils.FormatHostPort(server.Hostname, server.Port),
 		proxyProto:  proxyProto,
 		proxyPort:   proxyPort,
+		pages:       host.Pages,
 		Transport:   transportFix,
 		ErrorLog:    log.New(writer, "", 0),
 	}
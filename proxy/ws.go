@@ -14,6 +14,7 @@ import (
 	"github.com/dropbox/godropbox/errors"
 	"github.com/gorilla/websocket"
 	"github.com/pritunl/pritunl-zero/authorizer"
+	"github.com/pritunl/pritunl-zero/clientcert"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/node"
@@ -116,6 +117,28 @@ func (w *webSocketConn) Run(db *database.Database) {
 						}
 					}
 
+					cert := w.authr.GetCertificate()
+					if cert != nil {
+						cert, err = clientcert.Get(db, cert.Id)
+						if err != nil {
+							switch err.(type) {
+							case *database.NotFoundError:
+								break
+							default:
+								logrus.WithFields(logrus.Fields{
+									"error": err,
+								}).Error("proxy: WebSocket certificate error")
+							}
+							w.Close()
+							return
+						}
+
+						if !cert.IsActive() {
+							w.Close()
+							return
+						}
+					}
+
 					srvcId := w.authr.ServiceId()
 					if !srvcId.IsZero() {
 						srvc, err := service.Get(db, srvcId)
@@ -0,0 +1,10 @@
+package ratelimit
+
+const (
+	Mongo  = "mongo"
+	Memory = "memory"
+
+	Ip      = "ip"
+	User    = "user"
+	Session = "session"
+)
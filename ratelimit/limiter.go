@@ -0,0 +1,208 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	buckets      = map[string]*bucket{}
+	bucketsLock  = sync.Mutex{}
+	bucketsSwept = time.Now()
+)
+
+type bucket struct {
+	Tokens    float64   `bson:"k"`
+	Timestamp time.Time `bson:"t"`
+	Denied    bool      `bson:"d"`
+	Allowed   bool      `bson:"a"`
+	Previous  bool      `bson:"p"`
+}
+
+func (b *bucket) retry(requests int) time.Duration {
+	if b.Allowed || requests <= 0 {
+		return 0
+	}
+
+	return time.Duration(math.Ceil(
+		(1-b.Tokens)*60/float64(requests))) * time.Second
+}
+
+// Take a token from the bucket, tokens refill at requests per minute up
+// to burst. Notify is set on the first rejection after a bucket was
+// allowed to avoid repeated audit entries while a client is limited.
+func Allow(db *database.Database, key string, requests, burst int) (
+	allowed bool, retry time.Duration, notify bool) {
+
+	if requests <= 0 {
+		allowed = true
+		return
+	}
+
+	if burst <= 0 {
+		burst = requests
+	}
+
+	var bckt *bucket
+	if settings.RateLimit.Backend != Memory {
+		b, err := allowMongo(db, key, requests, burst)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"key":   key,
+				"error": err,
+			}).Error("ratelimit: Failed to update rate limit, " +
+				"using memory fallback")
+		} else {
+			bckt = b
+		}
+	}
+
+	if bckt == nil {
+		bckt = allowMemory(key, requests, burst)
+	}
+
+	allowed = bckt.Allowed
+	retry = bckt.retry(requests)
+	notify = !bckt.Allowed && !bckt.Previous
+
+	return
+}
+
+func allowMongo(db *database.Database, key string, requests, burst int) (
+	bckt *bucket, err error) {
+
+	coll := db.RateLimits()
+	now := time.Now()
+	bckt = &bucket{}
+
+	elapsed := &bson.M{
+		"$max": bson.A{
+			0,
+			&bson.M{
+				"$subtract": bson.A{
+					now,
+					&bson.M{
+						"$ifNull": bson.A{"$t", now},
+					},
+				},
+			},
+		},
+	}
+	refill := &bson.M{
+		"$add": bson.A{
+			&bson.M{
+				"$ifNull": bson.A{"$k", float64(burst)},
+			},
+			&bson.M{
+				"$multiply": bson.A{
+					elapsed,
+					float64(requests) / 60000,
+				},
+			},
+		},
+	}
+
+	err = coll.FindOneAndUpdate(
+		db,
+		&bson.M{
+			"_id": key,
+		},
+		bson.A{
+			&bson.M{
+				"$set": &bson.M{
+					"k": &bson.M{
+						"$min": bson.A{float64(burst), refill},
+					},
+					"t": now,
+				},
+			},
+			&bson.M{
+				"$set": &bson.M{
+					"a": &bson.M{
+						"$gte": bson.A{"$k", 1},
+					},
+					"p": &bson.M{
+						"$ifNull": bson.A{"$d", false},
+					},
+				},
+			},
+			&bson.M{
+				"$set": &bson.M{
+					"k": &bson.M{
+						"$cond": bson.A{
+							"$a",
+							&bson.M{
+								"$subtract": bson.A{"$k", 1},
+							},
+							"$k",
+						},
+					},
+					"d": &bson.M{
+						"$not": bson.A{"$a"},
+					},
+				},
+			},
+		},
+		options.FindOneAndUpdate().
+			SetUpsert(true).
+			SetReturnDocument(options.After),
+	).Decode(bckt)
+	if err != nil {
+		err = database.ParseError(err)
+		bckt = nil
+		return
+	}
+
+	return
+}
+
+func allowMemory(key string, requests, burst int) (bckt *bucket) {
+	now := time.Now()
+
+	bucketsLock.Lock()
+	defer bucketsLock.Unlock()
+
+	if now.Sub(bucketsSwept) > time.Minute {
+		for k, b := range buckets {
+			if now.Sub(b.Timestamp) > time.Hour {
+				delete(buckets, k)
+			}
+		}
+		bucketsSwept = now
+	}
+
+	bckt = buckets[key]
+	if bckt == nil {
+		bckt = &bucket{
+			Tokens:    float64(burst),
+			Timestamp: now,
+		}
+		buckets[key] = bckt
+	}
+
+	elapsed := max(now.Sub(bckt.Timestamp), 0)
+	bckt.Tokens = min(float64(burst), bckt.Tokens+
+		elapsed.Minutes()*float64(requests))
+	bckt.Timestamp = now
+	bckt.Previous = bckt.Denied
+	bckt.Allowed = bckt.Tokens >= 1
+	if bckt.Allowed {
+		bckt.Tokens -= 1
+	}
+	bckt.Denied = !bckt.Allowed
+
+	bckt = &bucket{
+		Tokens:   bckt.Tokens,
+		Allowed:  bckt.Allowed,
+		Previous: bckt.Previous,
+	}
+
+	return
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/audit"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+// Check a limit for the scope and key, the first rejection of each
+// limited period is recorded in the audit log
+func Check(db *database.Database, r *http.Request, userId bson.ObjectID,
+	scope, key string, requests, burst int) (allowed bool,
+	retry time.Duration, err error) {
+
+	allowed, retry, notify := Allow(db, scope+":"+key, requests, burst)
+	if allowed || !notify {
+		return
+	}
+
+	err = audit.New(
+		db,
+		r,
+		userId,
+		audit.RateLimited,
+		audit.Fields{
+			"scope":       scope,
+			"key":         key,
+			"path":        r.URL.Path,
+			"retry_after": int(retry.Seconds()),
+		},
+	)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Check the authentication limit for a user, aborts the request with a
+// 429 if the limit has been reached. The limit is keyed on the username
+// and source address so one client cannot throttle another client's
+// logins for the same user.
+func CheckUser(c *gin.Context, db *database.Database,
+	userId bson.ObjectID, username string) (allowed bool, err error) {
+
+	key := username + ":" + node.Self.GetRemoteAddr(c.Request)
+
+	allowed, retry, err := Check(db, c.Request, userId, User, key,
+		settings.RateLimit.AuthUserRequests, settings.RateLimit.AuthUserBurst)
+	if err != nil {
+		return
+	}
+
+	if !allowed {
+		Abort(c, retry)
+	}
+
+	return
+}
+
+func RetryAfter(retry time.Duration) string {
+	return strconv.Itoa(max(int(retry.Seconds()), 1))
+}
+
+func Abort(c *gin.Context, retry time.Duration) {
+	c.Header("Retry-After", RetryAfter(retry))
+	utils.AbortWithStatus(c, 429)
+}
+
+func Write(w http.ResponseWriter, retry time.Duration) {
+	w.Header().Set("Retry-After", RetryAfter(retry))
+	utils.WriteStatus(w, 429)
+}
@@ -0,0 +1,83 @@
+package recording
+
+import (
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+type Recording struct {
+	Id          bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Authority   bson.ObjectID `bson:"authority" json:"authority"`
+	User        bson.ObjectID `bson:"user,omitempty" json:"user"`
+	Node        bson.ObjectID `bson:"node" json:"node"`
+	Serial      string        `bson:"serial" json:"serial"`
+	KeyId       string        `bson:"key_id" json:"key_id"`
+	RemoteAddr  string        `bson:"remote_addr" json:"remote_addr"`
+	Destination string        `bson:"destination" json:"destination"`
+	Username    string        `bson:"username" json:"username"`
+	Width       int           `bson:"width" json:"width"`
+	Height      int           `bson:"height" json:"height"`
+	Start       time.Time     `bson:"start" json:"start"`
+	End         time.Time     `bson:"end" json:"end"`
+	Size        int64         `bson:"size" json:"size"`
+	Chunks      int           `bson:"chunks" json:"chunks"`
+}
+
+type Chunk struct {
+	Id        bson.ObjectID `bson:"_id,omitempty"`
+	Recording bson.ObjectID `bson:"recording"`
+	Index     int           `bson:"index"`
+	Timestamp time.Time     `bson:"timestamp"`
+	Data      []byte        `bson:"data"`
+}
+
+func (r *Recording) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.SshRecordings()
+
+	err = coll.CommitFields(r.Id, r, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (r *Recording) Insert(db *database.Database) (err error) {
+	coll := db.SshRecordings()
+
+	if !r.Id.IsZero() {
+		err = &errortypes.DatabaseError{
+			errors.New("recording: Recording already exists"),
+		}
+		return
+	}
+
+	resp, err := coll.InsertOne(db, r)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	r.Id = resp.InsertedID.(bson.ObjectID)
+
+	return
+}
+
+func (c *Chunk) Insert(db *database.Database) (err error) {
+	coll := db.SshRecordingChunks()
+
+	_, err = coll.InsertOne(db, c)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
@@ -0,0 +1,207 @@
+package recording
+
+import (
+	"io"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+func Get(db *database.Database, recId bson.ObjectID) (
+	rec *Recording, err error) {
+
+	coll := db.SshRecordings()
+	rec = &Recording{}
+
+	err = coll.FindOneId(recId, rec)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetAll(db *database.Database, userId bson.ObjectID,
+	page, pageCount int64) (recs []*Recording, count int64, err error) {
+
+	coll := db.SshRecordings()
+	recs = []*Recording{}
+
+	// Recordings without a known user are listed under the nil user id
+	query := &bson.M{
+		"user": userId,
+	}
+	if userId.IsZero() {
+		query = &bson.M{
+			"user": &bson.M{
+				"$exists": false,
+			},
+		}
+	}
+
+	count, err = coll.CountDocuments(db, query)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"start", -1}})
+
+	if pageCount != 0 {
+		maxPage := count / pageCount
+		if count == pageCount {
+			maxPage = 0
+		}
+		page = min(page, maxPage)
+		skip := min(page*pageCount, count)
+		opts.SetSkip(skip).SetLimit(pageCount)
+	}
+
+	cursor, err := coll.Find(db, query, opts)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		rec := &Recording{}
+		err = cursor.Decode(rec)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		recs = append(recs, rec)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func WriteCast(db *database.Database, recId bson.ObjectID,
+	w io.Writer) (err error) {
+
+	coll := db.SshRecordingChunks()
+
+	cursor, err := coll.Find(db, &bson.M{
+		"recording": recId,
+	}, options.Find().
+		SetSort(bson.D{{"index", 1}}))
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		chunk := &Chunk{}
+		err = cursor.Decode(chunk)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		_, err = w.Write(chunk.Data)
+		if err != nil {
+			err = &errortypes.WriteError{
+				errors.Wrap(err, "recording: Failed to write cast"),
+			}
+			return
+		}
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func Remove(db *database.Database, recId bson.ObjectID) (err error) {
+	coll := db.SshRecordingChunks()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"recording": recId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	coll = db.SshRecordings()
+
+	_, err = coll.DeleteOne(db, &bson.M{
+		"_id": recId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		switch err.(type) {
+		case *database.NotFoundError:
+			err = nil
+		default:
+			return
+		}
+	}
+
+	return
+}
+
+func RemoveExpired(db *database.Database, ttl time.Duration) (
+	count int, err error) {
+
+	coll := db.SshRecordings()
+
+	cursor, err := coll.Find(db, &bson.M{
+		"start": &bson.M{
+			"$lt": time.Now().Add(-ttl),
+		},
+	}, options.Find().
+		SetProjection(bson.D{{"_id", 1}}))
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	recIds := []bson.ObjectID{}
+	for cursor.Next(db) {
+		rec := &Recording{}
+		err = cursor.Decode(rec)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		recIds = append(recIds, rec.Id)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	for _, recId := range recIds {
+		err = Remove(db, recId)
+		if err != nil {
+			return
+		}
+
+		count += 1
+	}
+
+	return
+}
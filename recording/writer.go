@@ -0,0 +1,263 @@
+package recording
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	chunkSize     = 256 * 1024
+	chunkQueue    = 64
+	flushInterval = 10 * time.Second
+)
+
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Writes an asciinema v2 cast file to the database in chunks, chunks are
+// inserted in the background to keep database writes off the session
+type Writer struct {
+	rec       *Recording
+	buf       bytes.Buffer
+	pending   []byte
+	index     int
+	lastFlush time.Time
+	closed    bool
+	lock      sync.Mutex
+	chunks    chan *Chunk
+	waiter    sync.WaitGroup
+}
+
+func (w *Writer) Recording() *Recording {
+	return w.rec
+}
+
+func (w *Writer) writeEvent(typ, data string) (err error) {
+	elapsed := time.Since(w.rec.Start).Seconds()
+
+	line, err := json.Marshal([]interface{}{
+		json.Number(fmt.Sprintf("%.6f", elapsed)),
+		typ,
+		data,
+	})
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "recording: Failed to marshal event"),
+		}
+		return
+	}
+
+	w.buf.Write(line)
+	w.buf.WriteByte('\n')
+
+	if w.buf.Len() >= chunkSize || time.Since(w.lastFlush) > flushInterval {
+		w.flush()
+	}
+
+	return
+}
+
+func (w *Writer) flush() {
+	w.lastFlush = time.Now()
+
+	if w.buf.Len() == 0 {
+		return
+	}
+
+	w.chunks <- &Chunk{
+		Recording: w.rec.Id,
+		Index:     w.index,
+		Timestamp: time.Now(),
+		Data:      append([]byte{}, w.buf.Bytes()...),
+	}
+
+	w.index += 1
+	w.buf.Reset()
+}
+
+func (w *Writer) insertChunk(chunk *Chunk) (err error) {
+	db := database.GetDatabase()
+	defer db.Close()
+
+	err = chunk.Insert(db)
+	if err != nil {
+		return
+	}
+
+	w.rec.Size += int64(len(chunk.Data))
+	w.rec.Chunks = chunk.Index + 1
+
+	err = w.rec.CommitFields(db, set.NewSet("size", "chunks"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (w *Writer) run() {
+	defer w.waiter.Done()
+
+	for chunk := range w.chunks {
+		err := w.insertChunk(chunk)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"recording_id": w.rec.Id.Hex(),
+				"chunk":        chunk.Index,
+				"error":        err,
+			}).Error("recording: Failed to write recording chunk")
+		}
+	}
+}
+
+// Record terminal output, incomplete UTF-8 sequences are held until the
+// next write to produce a valid cast file
+func (w *Writer) Write(data []byte) (n int, err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	n = len(data)
+	if w.closed {
+		return
+	}
+
+	data = append(w.pending, data...)
+	cut := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				cut = i
+			}
+			break
+		}
+	}
+
+	w.pending = append([]byte{}, data[cut:]...)
+
+	if cut == 0 {
+		return
+	}
+
+	e := w.writeEvent("o", string(data[:cut]))
+	if e != nil {
+		logrus.WithFields(logrus.Fields{
+			"recording_id": w.rec.Id.Hex(),
+			"error":        e,
+		}).Error("recording: Failed to write recording")
+	}
+
+	return
+}
+
+func (w *Writer) Resize(width, height int) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	e := w.writeEvent("r", fmt.Sprintf("%dx%d", width, height))
+	if e != nil {
+		logrus.WithFields(logrus.Fields{
+			"recording_id": w.rec.Id.Hex(),
+			"error":        e,
+		}).Error("recording: Failed to write recording")
+	}
+}
+
+func (w *Writer) Close() (err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return
+	}
+	w.closed = true
+
+	if len(w.pending) > 0 {
+		e := w.writeEvent("o", string(w.pending))
+		w.pending = nil
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"recording_id": w.rec.Id.Hex(),
+				"error":        e,
+			}).Error("recording: Failed to write recording")
+		}
+	}
+
+	w.flush()
+	close(w.chunks)
+	w.waiter.Wait()
+
+	db := database.GetDatabase()
+	defer db.Close()
+
+	w.rec.End = time.Now()
+	err = w.rec.CommitFields(db, set.NewSet("end"))
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func NewWriter(db *database.Database, rec *Recording, term string) (
+	w *Writer, err error) {
+
+	rec.Start = time.Now()
+
+	err = rec.Insert(db)
+	if err != nil {
+		return
+	}
+
+	w = &Writer{
+		rec:       rec,
+		lastFlush: time.Now(),
+		chunks:    make(chan *Chunk, chunkQueue),
+	}
+
+	header := &castHeader{
+		Version:   2,
+		Width:     rec.Width,
+		Height:    rec.Height,
+		Timestamp: rec.Start.Unix(),
+	}
+	if term != "" {
+		header.Env = map[string]string{
+			"TERM": term,
+		}
+	}
+
+	headerData, err := json.Marshal(header)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "recording: Failed to marshal header"),
+		}
+		return
+	}
+
+	w.buf.Write(headerData)
+	w.buf.WriteByte('\n')
+
+	w.waiter.Add(1)
+	go w.run()
+
+	return
+}
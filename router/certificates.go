@@ -7,6 +7,7 @@ import (
 
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/acme"
 	"github.com/pritunl/pritunl-zero/certificate"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
@@ -74,6 +75,25 @@ func (c *Certificates) GetCertificate(info *tls.ClientHelloInfo) (
 		name = name[:len(name)-1]
 	}
 
+	if len(info.SupportedProtos) == 1 &&
+		info.SupportedProtos[0] == acme.AlpnProto {
+
+		cert, err = acme.GetAlpnCertificate(name)
+		if err != nil {
+			logrus.WithFields(logrus.Fields{
+				"domain": name,
+				"error":  err,
+			}).Error("router: Acme tls-alpn challenge not found")
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"domain": name,
+		}).Info("router: Acme tls-alpn challenge requested")
+
+		return
+	}
+
 	cert = c.domainMap[name]
 	if cert == nil {
 		index := strings.Index(name, ".")
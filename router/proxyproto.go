@@ -0,0 +1,311 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+var (
+	proxyV1Prefix  = []byte("PROXY ")
+	proxyV2Sig     = []byte("\r\n\r\n\x00\r\nQUIT\n")
+	proxyV1MaxLen  = 107
+	proxyV2HdrLen  = 16
+	proxyV2MaxBody = 512
+)
+
+// Listener that reads PROXY protocol v1 and v2 headers from trusted
+// sources, the source address of the header replaces the remote address
+// of the connection. Connections from trusted sources without a header
+// are rejected.
+type proxyListener struct {
+	net.Listener
+	sources []*net.IPNet
+	timeout time.Duration
+}
+
+func (l *proxyListener) Accept() (conn net.Conn, err error) {
+	conn, err = l.Listener.Accept()
+	if err != nil {
+		return
+	}
+
+	if !l.trusted(conn.RemoteAddr()) {
+		return
+	}
+
+	conn = &proxyConn{
+		Conn:    conn,
+		reader:  bufio.NewReaderSize(conn, 1024),
+		timeout: l.timeout,
+	}
+
+	return
+}
+
+func (l *proxyListener) trusted(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	for _, source := range l.sources {
+		if source.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type proxyConn struct {
+	net.Conn
+	reader       *bufio.Reader
+	timeout      time.Duration
+	once         sync.Once
+	remoteAddr   net.Addr
+	err          error
+	deadline     time.Time
+	deadlineLock sync.Mutex
+}
+
+func (c *proxyConn) SetDeadline(t time.Time) error {
+	c.deadlineLock.Lock()
+	c.deadline = t
+	c.deadlineLock.Unlock()
+
+	return c.Conn.SetDeadline(t)
+}
+
+func (c *proxyConn) SetReadDeadline(t time.Time) error {
+	c.deadlineLock.Lock()
+	c.deadline = t
+	c.deadlineLock.Unlock()
+
+	return c.Conn.SetReadDeadline(t)
+}
+
+func (c *proxyConn) Read(b []byte) (n int, err error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		err = c.err
+		return
+	}
+
+	n, err = c.reader.Read(b)
+	return
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// Read the header from a trusted source, the read deadline set by the
+// server is restored after the header is read
+func (c *proxyConn) readHeader() {
+	c.deadlineLock.Lock()
+	deadline := c.deadline
+	c.deadlineLock.Unlock()
+
+	if c.timeout != 0 {
+		headerDeadline := time.Now().Add(c.timeout)
+		if deadline.IsZero() || headerDeadline.Before(deadline) {
+			_ = c.Conn.SetReadDeadline(headerDeadline)
+			defer c.Conn.SetReadDeadline(deadline)
+		}
+	}
+
+	start, err := c.reader.Peek(1)
+	if err != nil {
+		c.err = err
+		return
+	}
+
+	switch start[0] {
+	case proxyV1Prefix[0]:
+		c.remoteAddr, c.err = c.readV1()
+		break
+	case proxyV2Sig[0]:
+		c.remoteAddr, c.err = c.readV2()
+		break
+	default:
+		c.err = &errortypes.ParseError{
+			errors.New("router: Missing PROXY protocol header"),
+		}
+	}
+}
+
+func (c *proxyConn) readV1() (addr net.Addr, err error) {
+	prefix, err := c.reader.Peek(len(proxyV1Prefix))
+	if err != nil {
+		return
+	}
+
+	if !bytes.Equal(prefix, proxyV1Prefix) {
+		err = &errortypes.ParseError{
+			errors.New("router: Missing PROXY protocol header"),
+		}
+		return
+	}
+
+	line := []byte{}
+	for {
+		b, e := c.reader.ReadByte()
+		if e != nil {
+			err = e
+			return
+		}
+
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+
+		if len(line) >= proxyV1MaxLen {
+			err = &errortypes.ParseError{
+				errors.New("router: PROXY protocol header too long"),
+			}
+			return
+		}
+	}
+
+	fields := strings.Fields(strings.TrimSuffix(string(line), "\r\n"))
+	if len(fields) < 2 {
+		err = &errortypes.ParseError{
+			errors.New("router: Invalid PROXY protocol header"),
+		}
+		return
+	}
+
+	switch fields[1] {
+	case "TCP4", "TCP6":
+		break
+	case "UNKNOWN":
+		return
+	default:
+		err = &errortypes.ParseError{
+			errors.Newf("router: Unknown PROXY protocol %s", fields[1]),
+		}
+		return
+	}
+
+	if len(fields) != 6 {
+		err = &errortypes.ParseError{
+			errors.New("router: Invalid PROXY protocol header"),
+		}
+		return
+	}
+
+	ip := net.ParseIP(fields[2])
+	port, e := strconv.Atoi(fields[4])
+	if ip == nil || e != nil || port < 0 || port > 65535 {
+		err = &errortypes.ParseError{
+			errors.New("router: Invalid PROXY protocol source address"),
+		}
+		return
+	}
+
+	addr = &net.TCPAddr{
+		IP:   ip,
+		Port: port,
+	}
+
+	return
+}
+
+func (c *proxyConn) readV2() (addr net.Addr, err error) {
+	hdr, err := c.reader.Peek(proxyV2HdrLen)
+	if err != nil {
+		return
+	}
+
+	if !bytes.Equal(hdr[:len(proxyV2Sig)], proxyV2Sig) {
+		err = &errortypes.ParseError{
+			errors.New("router: Missing PROXY protocol header"),
+		}
+		return
+	}
+
+	verCmd := hdr[12]
+	family := hdr[13]
+	bodyLen := int(binary.BigEndian.Uint16(hdr[14:16]))
+
+	if verCmd>>4 != 2 {
+		err = &errortypes.ParseError{
+			errors.New("router: Invalid PROXY protocol version"),
+		}
+		return
+	}
+
+	if bodyLen > proxyV2MaxBody {
+		err = &errortypes.ParseError{
+			errors.New("router: PROXY protocol header too long"),
+		}
+		return
+	}
+
+	_, err = c.reader.Discard(proxyV2HdrLen)
+	if err != nil {
+		return
+	}
+
+	body := make([]byte, bodyLen)
+	_, err = io.ReadFull(c.reader, body)
+	if err != nil {
+		return
+	}
+
+	// Local connections such as health checks keep the peer address
+	if verCmd&0x0f == 0x00 {
+		return
+	} else if verCmd&0x0f != 0x01 {
+		err = &errortypes.ParseError{
+			errors.New("router: Invalid PROXY protocol command"),
+		}
+		return
+	}
+
+	switch family {
+	case 0x11:
+		if len(body) < 12 {
+			err = &errortypes.ParseError{
+				errors.New("router: Invalid PROXY protocol address"),
+			}
+			return
+		}
+
+		addr = &net.TCPAddr{
+			IP:   net.IP(body[0:4]),
+			Port: int(binary.BigEndian.Uint16(body[8:10])),
+		}
+		break
+	case 0x21:
+		if len(body) < 36 {
+			err = &errortypes.ParseError{
+				errors.New("router: Invalid PROXY protocol address"),
+			}
+			return
+		}
+
+		addr = &net.TCPAddr{
+			IP:   net.IP(body[0:16]),
+			Port: int(binary.BigEndian.Uint16(body[32:34])),
+		}
+		break
+	}
+
+	return
+}
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"math/rand"
+	"net"
 	"net/http"
 	"path"
 	"strconv"
@@ -56,6 +57,8 @@ type Router struct {
 	redirectSystemd      bool
 	forceRedirectSystemd bool
 	protocol             string
+	proxyProtocol        bool
+	proxyProtocolSources []*net.IPNet
 	certificates         *Certificates
 	box                  *crypto.AsymNaclHmac
 	managementDomain     string
@@ -380,7 +383,15 @@ func (r *Router) startRedirect() {
 		"port":       80,
 	}).Error("router: Starting fallback main process redirect server")
 
-	err := r.redirectServer.ListenAndServe()
+	listener, err := r.listen(r.redirectServer.Addr)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("router: Redirect server listen error")
+		return
+	}
+
+	err = r.redirectServer.Serve(listener)
 	if err != nil {
 		if err == http.ErrServerClosed {
 			err = nil
@@ -465,6 +476,25 @@ func (r *Router) initWeb() (err error) {
 		r.protocol = "https"
 	}
 
+	r.proxyProtocol = node.Self.ProxyProtocol
+	r.proxyProtocolSources = []*net.IPNet{}
+	for _, cidr := range node.Self.ProxyProtocolSources {
+		_, source, e := net.ParseCIDR(cidr)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"source": cidr,
+				"error":  e,
+			}).Error("router: Invalid PROXY protocol source")
+			continue
+		}
+		r.proxyProtocolSources = append(r.proxyProtocolSources, source)
+	}
+
+	// The systemd redirect process does not read PROXY protocol headers
+	if r.proxyProtocol && !r.forceRedirectSystemd {
+		r.redirectSystemd = false
+	}
+
 	if r.managementType {
 		r.mRouter = gin.New()
 
@@ -528,6 +558,60 @@ func (r *Router) initWeb() (err error) {
 	return
 }
 
+func (r *Router) listen(addr string) (listener net.Listener, err error) {
+	listener, err = net.Listen("tcp", addr)
+	if err != nil {
+		err = &errortypes.UnknownError{
+			errors.Wrap(err, "router: Server listen failed"),
+		}
+		return
+	}
+
+	if r.proxyProtocol {
+		listener = &proxyListener{
+			Listener: listener,
+			sources:  r.proxyProtocolSources,
+			timeout: time.Duration(
+				settings.Router.HeaderTimeout) * time.Second,
+		}
+	}
+
+	return
+}
+
+// Request client certificates only on proxy domains with client
+// certificate authentication, the certificate is optional at the TLS layer
+// and enforced by the proxy to allow the login page and error responses
+func (r *Router) clientCertConfig(baseConfig *tls.Config) func(
+	*tls.ClientHelloInfo) (*tls.Config, error) {
+
+	return func(info *tls.ClientHelloInfo) (config *tls.Config, err error) {
+		if len(info.SupportedProtos) == 1 &&
+			info.SupportedProtos[0] == acme.AlpnProto {
+
+			return
+		}
+
+		name := strings.TrimRight(strings.ToLower(info.ServerName), ".")
+		if name == "" {
+			return
+		}
+
+		pool := r.proxy.GetClientCertPool(name)
+		if pool == nil {
+			return
+		}
+
+		config = baseConfig.Clone()
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		// Prevent resuming sessions established without a certificate
+		config.SessionTicketsDisabled = true
+
+		return
+	}
+}
+
 func (r *Router) startWeb() {
 	defer r.waiter.Done()
 
@@ -542,8 +626,16 @@ func (r *Router) startWeb() {
 		"read_header_timeout": settings.Router.HeaderTimeout,
 	}).Info("router: Starting web server")
 
+	listener, err := r.listen(r.webServer.Addr)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("router: Web server listen error")
+		return
+	}
+
 	if r.protocol == "http" {
-		err := r.webServer.ListenAndServe()
+		err = r.webServer.Serve(listener)
 		if err != nil {
 			if err == http.ErrServerClosed {
 				err = nil
@@ -575,22 +667,16 @@ func (r *Router) startWeb() {
 			GetCertificate: r.certificates.GetCertificate,
 		}
 		if r.http2 {
-			tlsConfig.NextProtos = []string{"h2"}
+			tlsConfig.NextProtos = []string{"h2", "http/1.1", acme.AlpnProto}
+		} else {
+			tlsConfig.NextProtos = []string{"http/1.1", acme.AlpnProto}
 		}
 		tlsConfig.Certificates = []tls.Certificate{}
-
-		listener, err := tls.Listen("tcp", r.webServer.Addr, tlsConfig)
-		if err != nil {
-			err = &errortypes.UnknownError{
-				errors.Wrap(err, "router: TLS listen failed"),
-			}
-			logrus.WithFields(logrus.Fields{
-				"error": err,
-			}).Error("router: Web server TLS error")
-			return
+		if r.proxyType {
+			tlsConfig.GetConfigForClient = r.clientCertConfig(tlsConfig)
 		}
 
-		err = r.webServer.Serve(listener)
+		err = r.webServer.Serve(tls.NewListener(listener, tlsConfig))
 		if err != nil {
 			if err == http.ErrServerClosed {
 				err = nil
@@ -722,6 +808,9 @@ func (r *Router) hashNode() []byte {
 	_, _ = io.WriteString(hash, fmt.Sprintf("%t", node.Self.NoRedirectServer))
 	_, _ = io.WriteString(hash, fmt.Sprintf("%t", node.Self.Http2))
 	_, _ = io.WriteString(hash, node.Self.Protocol)
+	_, _ = io.WriteString(hash, strconv.FormatBool(node.Self.ProxyProtocol))
+	_, _ = io.WriteString(hash, strings.Join(
+		node.Self.ProxyProtocolSources, ","))
 
 	_, _ = io.WriteString(hash, strconv.Itoa(settings.Router.ReadTimeout))
 	_, _ = io.WriteString(hash, strconv.Itoa(settings.Router.HeaderTimeout))
@@ -26,6 +26,7 @@ const (
 	ProxyDeviceRegister      = "proxy_device_register"
 	Authority                = "authority"
 	AuthorityDevice          = "authority_device"
+	Command                  = "command"
 )
 
 var (
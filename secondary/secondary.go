@@ -17,6 +17,7 @@ import (
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/device"
 	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/lockout"
 	"github.com/pritunl/pritunl-zero/node"
 	"github.com/pritunl/pritunl-zero/settings"
 	"github.com/pritunl/pritunl-zero/user"
@@ -808,6 +809,13 @@ func (s *Secondary) Complete(db *database.Database) (
 func (s *Secondary) Handle(db *database.Database, r *http.Request,
 	factor, passcode string) (errData *errortypes.ErrorData, err error) {
 
+	if factor == Passcode {
+		errData, err = s.checkLockout(db, r)
+		if err != nil || errData != nil {
+			return
+		}
+	}
+
 	switch factor {
 	case Push:
 		errData, err = s.Push(db, r)
@@ -817,6 +825,9 @@ func (s *Secondary) Handle(db *database.Database, r *http.Request,
 		break
 	case Passcode:
 		errData, err = s.Passcode(db, r, passcode)
+		if err == nil {
+			err = s.recordLockout(db, r, errData == nil)
+		}
 		break
 	case Sms:
 		errData, err = s.Sms(db, r)
@@ -837,6 +848,44 @@ func (s *Secondary) Handle(db *database.Database, r *http.Request,
 	return
 }
 
+func (s *Secondary) checkLockout(db *database.Database, r *http.Request) (
+	errData *errortypes.ErrorData, err error) {
+
+	errData, err = lockout.CheckAddr(db, r)
+	if err != nil || errData != nil {
+		return
+	}
+
+	usr, err := s.GetUser(db)
+	if err != nil {
+		return
+	}
+
+	errData = lockout.CheckUser(usr)
+
+	return
+}
+
+func (s *Secondary) recordLockout(db *database.Database, r *http.Request,
+	success bool) (err error) {
+
+	usr, err := s.GetUser(db)
+	if err != nil {
+		return
+	}
+
+	if success {
+		err = lockout.Success(db, r, usr)
+	} else {
+		err = lockout.Failed(db, r, usr)
+	}
+	if err != nil {
+		return
+	}
+
+	return
+}
+
 func (s *Secondary) GetUser(db *database.Database) (
 	usr *user.User, err error) {
 
@@ -5,4 +5,13 @@ const (
 	Cloudflare  = "cloudflare"
 	OracleCloud = "oracle_cloud"
 	GoogleCloud = "google_cloud"
+	AcmeEab     = "acme_eab"
+	Rfc2136     = "rfc2136"
+	PowerDns    = "powerdns"
+)
+
+const (
+	HmacSha1   = "hmac-sha1"
+	HmacSha256 = "hmac-sha256"
+	HmacSha512 = "hmac-sha512"
 )
@@ -1,6 +1,9 @@
 package secret
 
 import (
+	"encoding/base64"
+	"net"
+	"net/url"
 	"strings"
 
 	"github.com/dropbox/godropbox/container/set"
@@ -19,6 +22,7 @@ type Secret struct {
 	Key        string        `bson:"key" json:"key"`
 	Value      string        `bson:"value" json:"value"`
 	Region     string        `bson:"region" json:"region"`
+	Algorithm  string        `bson:"algorithm" json:"algorithm"`
 	PublicKey  string        `bson:"public_key" json:"public_key"`
 	PrivateKey string        `bson:"private_key" json:"-"`
 }
@@ -48,6 +52,98 @@ func (c *Secret) Validate(db *database.Database) (
 		c.Value = ""
 		c.Region = ""
 
+		break
+	case AcmeEab:
+		c.Key = strings.TrimSpace(c.Key)
+		c.Value = strings.TrimSpace(c.Value)
+		c.Region = ""
+
+		if c.Key == "" || c.Value == "" {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_secret_eab",
+				Message: "External account binding key ID and HMAC required",
+			}
+			return
+		}
+
+		break
+	case Rfc2136:
+		c.Key = strings.Trim(strings.TrimSpace(c.Key), ".")
+		c.Value = strings.TrimSpace(c.Value)
+		c.Region = strings.TrimSpace(c.Region)
+
+		if c.Key == "" || c.Value == "" {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_secret_tsig",
+				Message: "TSIG key name and secret required",
+			}
+			return
+		}
+
+		_, e := base64.StdEncoding.DecodeString(c.Value)
+		if e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_secret_tsig",
+				Message: "TSIG secret must be base64 encoded",
+			}
+			return
+		}
+
+		if c.Region == "" {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_secret_server",
+				Message: "DNS server address required",
+			}
+			return
+		}
+
+		if _, _, e := net.SplitHostPort(c.Region); e != nil {
+			c.Region = net.JoinHostPort(strings.Trim(c.Region, "[]"), "53")
+		}
+
+		switch c.Algorithm {
+		case HmacSha256, "":
+			c.Algorithm = HmacSha256
+			break
+		case HmacSha512, HmacSha1:
+			break
+		default:
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_secret_algorithm",
+				Message: "TSIG algorithm invalid",
+			}
+			return
+		}
+
+		break
+	case PowerDns:
+		c.Key = strings.TrimSpace(c.Key)
+		c.Value = strings.TrimSpace(c.Value)
+		c.Region = strings.TrimRight(strings.TrimSpace(c.Region), "/")
+
+		if c.Value == "" {
+			c.Value = "localhost"
+		}
+
+		if c.Key == "" {
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_secret_key",
+				Message: "PowerDNS API key required",
+			}
+			return
+		}
+
+		apiUrl, e := url.Parse(c.Region)
+		if e != nil || apiUrl.Host == "" ||
+			(apiUrl.Scheme != "http" && apiUrl.Scheme != "https") {
+
+			errData = &errortypes.ErrorData{
+				Error:   "invalid_secret_url",
+				Message: "PowerDNS API URL invalid",
+			}
+			return
+		}
+
 		break
 	default:
 		errData = &errortypes.ErrorData{
@@ -57,6 +153,10 @@ func (c *Secret) Validate(db *database.Database) (
 		return
 	}
 
+	if c.Type != Rfc2136 {
+		c.Algorithm = ""
+	}
+
 	if c.PrivateKey == "" {
 		privKey, pubKey, e := utils.GenerateRsaKey()
 		if e != nil {
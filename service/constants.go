@@ -3,4 +3,9 @@ package service
 const (
 	Http  = "http"
 	Https = "https"
+
+	MtlsOptional = "optional"
+	MtlsRequired = "required"
+
+	DefaultCacheMemorySize = 64
 )
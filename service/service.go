@@ -4,6 +4,7 @@ import (
 	"net"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/dropbox/godropbox/container/set"
 	"github.com/dropbox/godropbox/errors"
@@ -40,12 +41,29 @@ type Service struct {
 	WebSockets         bool             `bson:"websockets" json:"websockets"`
 	DisableCsrfCheck   bool             `bson:"disable_csrf_check" json:"disable_csrf_check"`
 	ClientAuthority    bson.ObjectID    `bson:"client_authority,omitempty" json:"client_authority"`
+	MtlsMode           string           `bson:"mtls_mode" json:"mtls_mode"`
+	MtlsAuthority      bson.ObjectID    `bson:"mtls_authority,omitempty" json:"mtls_authority"`
 	Domains            []*Domain        `bson:"domains" json:"domains"`
 	Roles              []string         `bson:"roles" json:"roles"`
 	Servers            []*Server        `bson:"servers" json:"servers"`
 	WhitelistNetworks  []string         `bson:"whitelist_networks" json:"whitelist_networks"`
 	WhitelistPaths     []*WhitelistPath `bson:"whitelist_paths" json:"whitelist_paths"`
 	WhitelistOptions   bool             `bson:"whitelist_options" json:"whitelist_options"`
+	RateLimitUser      int              `bson:"rate_limit_user" json:"rate_limit_user"`
+	RateLimitSession   int              `bson:"rate_limit_session" json:"rate_limit_session"`
+	RateLimitIp        int              `bson:"rate_limit_ip" json:"rate_limit_ip"`
+	RateLimitBurst     int              `bson:"rate_limit_burst" json:"rate_limit_burst"`
+	Cache              bool             `bson:"cache" json:"cache"`
+	CacheMemorySize    int              `bson:"cache_memory_size" json:"cache_memory_size"`
+	CacheDiskSize      int              `bson:"cache_disk_size" json:"cache_disk_size"`
+	Maintenance        bool             `bson:"maintenance" json:"maintenance"`
+	MaintenanceStart   time.Time        `bson:"maintenance_start" json:"maintenance_start"`
+	MaintenanceEnd     time.Time        `bson:"maintenance_end" json:"maintenance_end"`
+	MaintenancePage    string           `bson:"maintenance_page" json:"maintenance_page"`
+	ForbiddenPage      string           `bson:"forbidden_page" json:"forbidden_page"`
+	NotFoundPage       string           `bson:"not_found_page" json:"not_found_page"`
+	UnavailablePage    string           `bson:"unavailable_page" json:"unavailable_page"`
+	ReplaceErrorPages  bool             `bson:"replace_error_pages" json:"replace_error_pages"`
 	logoutPathExtMatch int
 }
 
@@ -105,6 +123,24 @@ func (s *Service) MatchWhitelistPath(matchPth string) bool {
 	return false
 }
 
+// Maintenance toggle is limited to the scheduled window when a start or
+// end time is set
+func (s *Service) IsMaintenance() bool {
+	if !s.Maintenance {
+		return false
+	}
+
+	now := time.Now()
+	if !s.MaintenanceStart.IsZero() && now.Before(s.MaintenanceStart) {
+		return false
+	}
+	if !s.MaintenanceEnd.IsZero() && !now.Before(s.MaintenanceEnd) {
+		return false
+	}
+
+	return true
+}
+
 func (s *Service) RemoveWhitelistNetworks() (err error) {
 	db := database.GetDatabase()
 	defer db.Close()
@@ -192,6 +228,75 @@ func (s *Service) Validate(db *database.Database) (
 		}
 	}
 
+	switch s.MtlsMode {
+	case "":
+		s.MtlsAuthority = bson.NilObjectID
+		break
+	case MtlsOptional, MtlsRequired:
+		if s.MtlsAuthority.IsZero() {
+			errData = &errortypes.ErrorData{
+				Error:   "service_mtls_authority_invalid",
+				Message: "Client certificate authentication requires authority",
+			}
+			return
+		}
+		break
+	default:
+		errData = &errortypes.ErrorData{
+			Error:   "service_mtls_mode_invalid",
+			Message: "Invalid client certificate authentication mode",
+		}
+		return
+	}
+
+	if s.RateLimitUser < 0 || s.RateLimitSession < 0 ||
+		s.RateLimitIp < 0 || s.RateLimitBurst < 0 {
+
+		errData = &errortypes.ErrorData{
+			Error:   "service_rate_limit_invalid",
+			Message: "Service rate limit cannot be negative",
+		}
+		return
+	}
+
+	if s.CacheMemorySize < 0 || s.CacheDiskSize < 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "service_cache_size_invalid",
+			Message: "Service cache size cannot be negative",
+		}
+		return
+	}
+
+	if s.Cache && s.CacheMemorySize == 0 && s.CacheDiskSize == 0 {
+		s.CacheMemorySize = DefaultCacheMemorySize
+	}
+
+	if !s.MaintenanceStart.IsZero() && !s.MaintenanceEnd.IsZero() &&
+		!s.MaintenanceEnd.After(s.MaintenanceStart) {
+
+		errData = &errortypes.ErrorData{
+			Error:   "service_maintenance_invalid",
+			Message: "Maintenance end must be after maintenance start",
+		}
+		return
+	}
+
+	for _, page := range []string{
+		s.MaintenancePage,
+		s.ForbiddenPage,
+		s.NotFoundPage,
+		s.UnavailablePage,
+	} {
+		_, e := ParsePage(page)
+		if e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "service_page_invalid",
+				Message: "Service page template is invalid",
+			}
+			return
+		}
+	}
+
 	newWhitelistNetworks := []string{}
 	for _, cidr := range s.WhitelistNetworks {
 		_, ipNet, e := net.ParseCIDR(cidr)
@@ -1,9 +1,13 @@
 package service
 
 import (
+	"html/template"
+
+	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
 	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
 )
 
 func Get(db *database.Database, serviceId bson.ObjectID) (
@@ -240,3 +244,20 @@ func RemoveMulti(db *database.Database, serviceIds []bson.ObjectID) (
 
 	return
 }
+
+// Parse a service page template, nil when the page is not set
+func ParsePage(text string) (tmpl *template.Template, err error) {
+	if text == "" {
+		return
+	}
+
+	tmpl, err = template.New("page").Parse(text)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "service: Failed to parse page template"),
+		}
+		return
+	}
+
+	return
+}
@@ -12,6 +12,8 @@ type acme struct {
 	DnsCloudflareTtl  int    `bson:"dns_cloudflare_ttl" default:"60"`
 	DnsOracleCloudTtl int    `bson:"dns_oracle_cloud_ttl" default:"30"`
 	DnsGoogleCloudTtl int    `bson:"dns_google_cloud_ttl" default:"30"`
+	DnsRfc2136Ttl     int    `bson:"dns_rfc2136_ttl" default:"30"`
+	DnsPowerDnsTtl    int    `bson:"dns_powerdns_ttl" default:"30"`
 }
 
 func newAcme() interface{} {
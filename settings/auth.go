@@ -239,6 +239,13 @@ type auth struct {
 	UserMaxDuration       int                  `bson:"user_max_duration" json:"user_max_duration" default:"4320"`
 	DisaleGeo             bool                 `bson:"disable_geo" json:"disable_geo"`
 	LimiterExpire         int                  `bson:"limiter_expire" json:"limiter_expire" default:"600"`
+	DisableLockout        bool                 `bson:"disable_lockout" json:"disable_lockout"`
+	LockoutAttempts       int                  `bson:"lockout_attempts" json:"lockout_attempts" default:"10"`
+	LockoutWindow         int                  `bson:"lockout_window" json:"lockout_window" default:"900"`
+	LockoutDuration       int                  `bson:"lockout_duration" json:"lockout_duration" default:"1800"`
+	LockoutPermanent      bool                 `bson:"lockout_permanent" json:"lockout_permanent"`
+	BackoffBase           int                  `bson:"backoff_base" json:"backoff_base" default:"1"`
+	BackoffMax            int                  `bson:"backoff_max" json:"backoff_max" default:"300"`
 }
 
 func (a *auth) GetProvider(id bson.ObjectID) *Provider {
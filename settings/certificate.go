@@ -0,0 +1,28 @@
+package settings
+
+var Certificate *certificate
+
+type certificate struct {
+	Id                    string   `bson:"_id"`
+	DisableExpireAlerts   bool     `bson:"disable_expire_alerts"`
+	AlertRoles            []string `bson:"alert_roles"`
+	AlertFrequency        int      `bson:"alert_frequency" default:"86400"`
+	ExpireAlertLowDays    int      `bson:"expire_alert_low_days" default:"30"`
+	ExpireAlertMediumDays int      `bson:"expire_alert_medium_days" default:"14"`
+	ExpireAlertHighDays   int      `bson:"expire_alert_high_days" default:"3"`
+	AcmeFailureAlerts     int      `bson:"acme_failure_alerts" default:"2"`
+}
+
+func newCertificate() interface{} {
+	return &certificate{
+		Id: "certificate",
+	}
+}
+
+func updateCertificate(data interface{}) {
+	Certificate = data.(*certificate)
+}
+
+func init() {
+	register("certificate", newCertificate, updateCertificate)
+}
@@ -7,6 +7,10 @@ type endpoint struct {
 	Name              string `bson:"name"`
 	KmsgDisplayLimit  int64  `bson:"kmsg_display_limit" default:"5000"`
 	CheckDisplayLimit int64  `bson:"check_display_limit" default:"5000"`
+	RetentionRaw      int    `bson:"retention_raw" default:"90"`
+	RetentionCheckRaw int    `bson:"retention_check_raw" default:"180"`
+	Retention5m       int    `bson:"retention_5m" default:"180"`
+	Retention1h       int    `bson:"retention_1h" default:"730"`
 }
 
 func newEndpoint() interface{} {
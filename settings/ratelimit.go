@@ -0,0 +1,26 @@
+package settings
+
+var RateLimit *rateLimit
+
+type rateLimit struct {
+	Id               string `bson:"_id"`
+	Backend          string `bson:"backend" default:"mongo"`
+	AuthIpRequests   int    `bson:"auth_ip_requests" default:"60"`
+	AuthIpBurst      int    `bson:"auth_ip_burst" default:"20"`
+	AuthUserRequests int    `bson:"auth_user_requests" default:"10"`
+	AuthUserBurst    int    `bson:"auth_user_burst" default:"10"`
+}
+
+func newRateLimit() interface{} {
+	return &rateLimit{
+		Id: "rate_limit",
+	}
+}
+
+func updateRateLimit(data interface{}) {
+	RateLimit = data.(*rateLimit)
+}
+
+func init() {
+	register("rate_limit", newRateLimit, updateRateLimit)
+}
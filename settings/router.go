@@ -26,6 +26,7 @@ type router struct {
 	UnsafeRemoteHeader     bool   `bson:"unsafe_remote_header"`
 	DebugWebSocket         bool   `bson:"debug_websocket"`
 	SkipVerify             bool   `bson:"skip_verify"`
+	CacheMaxObjectSize     int    `bson:"cache_max_object_size" default:"10485760"`
 }
 
 func newRouter() interface{} {
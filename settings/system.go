@@ -21,12 +21,15 @@ type system struct {
 	SshHostTokenLen                int    `bson:"ssh_host_token_len" default:"10"`
 	HsmResponseTimeout             int    `bson:"hsm_response_timeout" default:"10"`
 	DisableBastionHostCertificates bool   `bson:"disable_bastion_host_certificates"`
+	BastionNative                  bool   `bson:"bastion_native"`
 	BastionDockerImage             string `bson:"bastion_docker_image" default:"docker.io/pritunl/pritunl-bastion"`
 	BastionPermitOpen              string `bson:"bastion_permit_open" default:"*:22"`
+	BastionRecordingTtl            int    `bson:"bastion_recording_ttl" default:"90"`
 	ClientCertCacheTtl             int    `bson:"client_cert_cache_ttl" default:"60"`
 	TwilioAccount                  string `bson:"twilio_account"`
 	TwilioSecret                   string `bson:"twilio_secret"`
 	TwilioNumber                   string `bson:"twilio_number"`
+	MetricsToken                   string `bson:"metrics_token"`
 }
 
 func newSystem() interface{} {
@@ -0,0 +1,15 @@
+package silence
+
+import (
+	"time"
+)
+
+const (
+	Daily  = "daily"
+	Weekly = "weekly"
+)
+
+var (
+	// Expiration used for recurring silences without an end
+	NoExpire = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+)
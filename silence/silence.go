@@ -0,0 +1,249 @@
+package silence
+
+import (
+	"path"
+	"slices"
+	"time"
+
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/alert"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+type Silence struct {
+	Id          bson.ObjectID   `bson:"_id,omitempty" json:"id"`
+	Name        string          `bson:"name" json:"name"`
+	Comment     string          `bson:"comment" json:"comment"`
+	Roles       []string        `bson:"roles" json:"roles"`
+	Endpoints   []bson.ObjectID `bson:"endpoints" json:"endpoints"`
+	Names       []string        `bson:"names" json:"names"`
+	Resources   []string        `bson:"resources" json:"resources"`
+	Start       time.Time       `bson:"start" json:"start"`
+	End         time.Time       `bson:"end" json:"end"`
+	Recurrence  string          `bson:"recurrence" json:"recurrence"`
+	Until       time.Time       `bson:"until" json:"until"`
+	Expires     time.Time       `bson:"expires" json:"expires"`
+	Creator     bson.ObjectID   `bson:"creator" json:"creator"`
+	CreatorName string          `bson:"creator_name" json:"creator_name"`
+	Timestamp   time.Time       `bson:"timestamp" json:"timestamp"`
+	Active      bool            `bson:"-" json:"active"`
+	Expired     bool            `bson:"-" json:"expired"`
+}
+
+func (s *Silence) GetPeriod() time.Duration {
+	switch s.Recurrence {
+	case Daily:
+		return 24 * time.Hour
+	case Weekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// Check if the silence or a recurrence of the window covers the time
+func (s *Silence) IsActive(now time.Time) bool {
+	if now.Before(s.Start) || !now.Before(s.Expires) {
+		return false
+	}
+
+	period := s.GetPeriod()
+	if period == 0 {
+		return now.Before(s.End)
+	}
+
+	offset := now.Sub(s.Start) % period
+	return offset < s.End.Sub(s.Start)
+}
+
+func (s *Silence) Match(source bson.ObjectID, sourceName string,
+	roles []string, resource string) bool {
+
+	if len(s.Resources) != 0 && !slices.Contains(s.Resources, resource) {
+		return false
+	}
+
+	if len(s.Roles) == 0 && len(s.Endpoints) == 0 && len(s.Names) == 0 {
+		return true
+	}
+
+	if slices.Contains(s.Endpoints, source) {
+		return true
+	}
+
+	for _, role := range roles {
+		if slices.Contains(s.Roles, role) {
+			return true
+		}
+	}
+
+	for _, name := range s.Names {
+		if name == sourceName {
+			return true
+		}
+
+		match, _ := path.Match(name, sourceName)
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Silence) Format(now time.Time) {
+	s.Active = s.IsActive(now)
+	s.Expired = !now.Before(s.Expires)
+}
+
+func (s *Silence) Validate(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	s.Name = utils.FilterName(s.Name)
+
+	if s.Roles == nil {
+		s.Roles = []string{}
+	}
+
+	if s.Endpoints == nil {
+		s.Endpoints = []bson.ObjectID{}
+	}
+
+	if s.Names == nil {
+		s.Names = []string{}
+	}
+
+	if s.Resources == nil {
+		s.Resources = []string{}
+	}
+
+	for _, name := range s.Names {
+		_, e := path.Match(name, "")
+		if e != nil {
+			errData = &errortypes.ErrorData{
+				Error:   "silence_name_invalid",
+				Message: "Silence endpoint name pattern is invalid",
+			}
+			return
+		}
+	}
+
+	for _, resource := range s.Resources {
+		switch resource {
+		case alert.SystemOffline, alert.SystemCpuLevel,
+			alert.SystemMemoryLevel, alert.SystemSwapLevel,
+			alert.SystemHugePagesLevel, alert.SystemMdFailed,
+			alert.DiskUsageLevel, alert.KmsgKeyword,
+			alert.CheckHttpFailed, alert.SystemProcesses,
+			alert.SystemPackageUpdates, alert.LoadPerCore,
+			alert.NetworkErrorRate, alert.NetworkThroughput,
+			alert.DiskIoAwait, alert.DiskIoUtilization,
+			alert.CheckTcpFailed, alert.CheckTlsFailed,
+			alert.CheckDnsFailed, alert.CertificateExpire,
+			alert.CertificateRenewFailed:
+
+			break
+		default:
+			errData = &errortypes.ErrorData{
+				Error:   "silence_resource_invalid",
+				Message: "Silence alert resource is invalid",
+			}
+			return
+		}
+	}
+
+	if s.Start.IsZero() {
+		s.Start = time.Now()
+	}
+
+	if !s.End.After(s.Start) {
+		errData = &errortypes.ErrorData{
+			Error:   "silence_end_invalid",
+			Message: "Silence end must be after start",
+		}
+		return
+	}
+
+	switch s.Recurrence {
+	case Daily, Weekly:
+		if s.End.Sub(s.Start) >= s.GetPeriod() {
+			errData = &errortypes.ErrorData{
+				Error:   "silence_duration_invalid",
+				Message: "Silence duration must be less than recurrence",
+			}
+			return
+		}
+
+		if s.Until.IsZero() {
+			s.Expires = NoExpire
+		} else if !s.Until.After(s.Start) {
+			errData = &errortypes.ErrorData{
+				Error:   "silence_until_invalid",
+				Message: "Silence recurrence end must be after start",
+			}
+			return
+		} else {
+			s.Expires = s.Until
+		}
+		break
+	case "":
+		s.Until = time.Time{}
+		s.Expires = s.End
+		break
+	default:
+		errData = &errortypes.ErrorData{
+			Error:   "silence_recurrence_invalid",
+			Message: "Silence recurrence is invalid",
+		}
+		return
+	}
+
+	return
+}
+
+func (s *Silence) Commit(db *database.Database) (err error) {
+	coll := db.Silences()
+
+	err = coll.Commit(s.Id, s)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *Silence) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.Silences()
+
+	err = coll.CommitFields(s.Id, s, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (s *Silence) Insert(db *database.Database) (err error) {
+	coll := db.Silences()
+
+	if !s.Id.IsZero() {
+		err = &errortypes.DatabaseError{
+			errors.New("silence: Silence already exists"),
+		}
+		return
+	}
+
+	_, err = coll.InsertOne(db, s)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
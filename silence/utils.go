@@ -0,0 +1,158 @@
+package silence
+
+import (
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/database"
+)
+
+func Get(db *database.Database, silenceId bson.ObjectID) (
+	silnc *Silence, err error) {
+
+	coll := db.Silences()
+	silnc = &Silence{}
+
+	err = coll.FindOneId(silenceId, silnc)
+	if err != nil {
+		return
+	}
+
+	silnc.Format(time.Now())
+
+	return
+}
+
+func GetAllPaged(db *database.Database, query *bson.M,
+	page, pageCount int64) (silences []*Silence, count int64, err error) {
+
+	coll := db.Silences()
+	silences = []*Silence{}
+	now := time.Now()
+
+	count, err = coll.CountDocuments(db, query)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"expires", -1}})
+
+	if pageCount != 0 {
+		maxPage := count / pageCount
+		if count == pageCount {
+			maxPage = 0
+		}
+		page = min(page, maxPage)
+		skip := min(page*pageCount, count)
+		opts.SetSkip(skip).SetLimit(pageCount)
+	}
+
+	cursor, err := coll.Find(db, query, opts)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		silnc := &Silence{}
+		err = cursor.Decode(silnc)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		silnc.Format(now)
+		silences = append(silences, silnc)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// Check if an alert for a source is suppressed by an active silence
+func Silenced(db *database.Database, source bson.ObjectID,
+	sourceName string, roles []string, resource string) (
+	silenced bool, err error) {
+
+	coll := db.Silences()
+	now := time.Now()
+
+	cursor, err := coll.Find(db, &bson.M{
+		"start": &bson.M{
+			"$lte": now,
+		},
+		"expires": &bson.M{
+			"$gt": now,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		silnc := &Silence{}
+		err = cursor.Decode(silnc)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		if silnc.IsActive(now) &&
+			silnc.Match(source, sourceName, roles, resource) {
+
+			silenced = true
+			return
+		}
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func Remove(db *database.Database, silenceId bson.ObjectID) (err error) {
+	coll := db.Silences()
+
+	_, err = coll.DeleteOne(db, &bson.M{
+		"_id": silenceId,
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func RemoveMulti(db *database.Database, silenceIds []bson.ObjectID) (
+	err error) {
+
+	coll := db.Silences()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"_id": &bson.M{
+			"$in": silenceIds,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
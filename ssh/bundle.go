@@ -0,0 +1,138 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/pritunl-zero/authority"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/settings"
+)
+
+const (
+	TrustedUserCaKeysPath = "/etc/ssh/trusted_user_ca_keys"
+	PrincipalsPath        = "/etc/ssh/principals"
+)
+
+type HostBundle struct {
+	Hostname          string              `json:"hostname"`
+	TrustedUserCaKeys []string            `json:"trusted_user_ca_keys"`
+	Principals        map[string][]string `json:"principals"`
+	SshdConfig        string              `json:"sshd_config"`
+	Hash              string              `json:"hash"`
+}
+
+func (b *HostBundle) hash() (err error) {
+	b.Hash = ""
+
+	data, err := json.Marshal(b)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "ssh: Failed to marshal host bundle"),
+		}
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	b.Hash = hex.EncodeToString(hash[:])
+
+	return
+}
+
+// Builds the sshd trust configuration for a host from the authorities
+// matching the host tokens and hostname, the hash changes only when the
+// content changes
+func GetHostBundle(db *database.Database, hostname string,
+	tokens []string) (bundle *HostBundle, errData *errortypes.ErrorData,
+	err error) {
+
+	if len(tokens) > settings.System.SshHostTokenLen {
+		err = errortypes.ParseError{
+			errors.New("ssh: Too many tokens"),
+		}
+		return
+	}
+
+	tokenAuthrs, err := authority.GetTokens(db, tokens)
+	if err != nil {
+		return
+	}
+
+	if len(tokenAuthrs) == 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "invalid_tokens",
+			Message: "All tokens are invalid",
+		}
+		return
+	}
+
+	authrs := []*authority.Authority{}
+	for _, authr := range tokenAuthrs {
+		if authr.MatchHostname(hostname) {
+			authrs = append(authrs, authr)
+		}
+	}
+
+	if len(authrs) == 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "invalid_hostname",
+			Message: "No authorities match hostname",
+		}
+		return
+	}
+
+	sort.Slice(authrs, func(i, j int) bool {
+		return authrs[i].Id.Hex() < authrs[j].Id.Hex()
+	})
+
+	bundle = &HostBundle{
+		Hostname:          hostname,
+		TrustedUserCaKeys: []string{},
+		Principals:        map[string][]string{},
+	}
+
+	for _, authr := range authrs {
+		pubKey := strings.TrimSpace(authr.PublicKey)
+		if pubKey != "" && !slices.Contains(
+			bundle.TrustedUserCaKeys, pubKey) {
+
+			bundle.TrustedUserCaKeys = append(
+				bundle.TrustedUserCaKeys, pubKey)
+		}
+
+		for account, principals := range authr.GetHostPrincipals() {
+			for _, principal := range principals {
+				if !slices.Contains(
+					bundle.Principals[account], principal) {
+
+					bundle.Principals[account] = append(
+						bundle.Principals[account], principal)
+				}
+			}
+		}
+	}
+
+	for _, principals := range bundle.Principals {
+		sort.Strings(principals)
+	}
+
+	bundle.SshdConfig = fmt.Sprintf(
+		"TrustedUserCAKeys %s\nAuthorizedPrincipalsFile %s/%%u\n",
+		TrustedUserCaKeysPath,
+		PrincipalsPath,
+	)
+
+	err = bundle.hash()
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -92,6 +92,23 @@ func GetCertificate(db *database.Database, certId bson.ObjectID) (
 	return
 }
 
+func GetCertificateSerial(db *database.Database, serial string) (
+	cert *Certificate, err error) {
+
+	coll := db.SshCertificates()
+	cert = &Certificate{}
+
+	err = coll.FindOne(db, &bson.M{
+		"certificates_info.serial": serial,
+	}).Decode(cert)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func GetCertificates(db *database.Database, userId bson.ObjectID,
 	page, pageCount int64) (certs []*Certificate, count int64, err error) {
 
@@ -11,8 +11,10 @@ import (
 	"github.com/pritunl/pritunl-zero/authority"
 	"github.com/pritunl/pritunl-zero/database"
 	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/identity"
 	"github.com/pritunl/pritunl-zero/settings"
 	"github.com/pritunl/pritunl-zero/useragent"
+	"github.com/sirupsen/logrus"
 )
 
 func NewHostCertificate(db *database.Database, hostname string, port int,
@@ -107,6 +109,110 @@ func NewHostCertificate(db *database.Database, hostname string, port int,
 	return
 }
 
+func NewHostIdentityCertificate(db *database.Database, hostname string,
+	port int, doc *identity.Document, r *http.Request, pubKey string) (
+	cert *Certificate, errData *errortypes.ErrorData, err error) {
+
+	pubKey = strings.TrimSpace(pubKey)
+
+	if doc == nil || doc.Type == "" {
+		errData = &errortypes.ErrorData{
+			Error:   "invalid_identity",
+			Message: "Identity document is invalid",
+		}
+		return
+	}
+
+	if len(pubKey) > settings.System.SshPubKeyLen {
+		err = errortypes.ParseError{
+			errors.New("ssh: Public key too long"),
+		}
+		return
+	}
+
+	agnt, err := useragent.Parse(db, r)
+	if err != nil {
+		return
+	}
+
+	cert = &Certificate{
+		Id:               bson.NewObjectID(),
+		AuthorityIds:     []bson.ObjectID{},
+		Timestamp:        time.Now(),
+		PubKey:           pubKey,
+		Certificates:     []string{},
+		CertificatesInfo: []*Info{},
+		Agent:            agnt,
+	}
+
+	authrs, err := authority.GetHostIdentity(db, doc.Type)
+	if err != nil {
+		return
+	}
+
+	for _, authr := range authrs {
+		ident, e := authr.HostIdentityVerify(db, doc, hostname,
+			pubKey)
+		if e != nil {
+			logrus.WithFields(logrus.Fields{
+				"authority_id": authr.Id.Hex(),
+				"hostname":     hostname,
+				"error":        e,
+			}).Error("ssh: Host identity verification failed")
+			continue
+		}
+
+		if !authr.HostnameValidate(hostname, port, pubKey) {
+			continue
+		}
+
+		crt, certStr, e := authr.CreateHostCertificate(db, hostname, pubKey)
+		if e != nil {
+			err = e
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"authority_id": authr.Id.Hex(),
+			"hostname":     hostname,
+			"provider":     ident.Provider,
+			"account":      ident.Account,
+			"instance_id":  ident.InstanceId,
+		}).Info("ssh: Host identity certificate issued")
+
+		info := &Info{
+			Expires:    time.Unix(int64(crt.ValidBefore), 0),
+			Serial:     fmt.Sprintf("%d", crt.Serial),
+			Principals: crt.ValidPrincipals,
+			Extensions: []string{},
+		}
+
+		for permission := range crt.Permissions.Extensions {
+			info.Extensions = append(info.Extensions, permission)
+		}
+
+		cert.AuthorityIds = append(cert.AuthorityIds, authr.Id)
+		cert.Certificates = append(cert.Certificates, certStr)
+		cert.CertificatesInfo = append(cert.CertificatesInfo, info)
+	}
+
+	if len(cert.Certificates) == 0 {
+		errData = &errortypes.ErrorData{
+			Error:   "certificate_unavailable",
+			Message: "No certificates are available",
+		}
+		return
+	}
+
+	err = cert.Insert(db)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
 func NewBastionHostCertificate(db *database.Database, hostname,
 	pubKey string, authr *authority.Authority) (
 	cert *Certificate, err error) {
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
 	"github.com/pritunl/pritunl-zero/authority"
 	"github.com/pritunl/pritunl-zero/bastion"
 	"github.com/pritunl/pritunl-zero/database"
@@ -21,6 +22,10 @@ func bastionEnabled() bool {
 }
 
 func bastionInit() (err error) {
+	if settings.System.BastionNative {
+		return
+	}
+
 	logrus.WithFields(logrus.Fields{
 		"docker_image": settings.System.BastionDockerImage,
 	}).Info("sync: Pulling bastion server docker image")
@@ -56,7 +61,9 @@ func bastionSync() (err error) {
 	}
 
 	for _, bast := range bastion.GetAll() {
-		curContainers = append(curContainers, bast.Container)
+		if bast.Container != "" {
+			curContainers = append(curContainers, bast.Container)
+		}
 
 		if !curAuthrs.Contains(bast.Authority) {
 			e := bast.Stop()
@@ -68,9 +75,12 @@ func bastionSync() (err error) {
 		}
 	}
 
-	containers, err := bastion.DockerGetRunning()
-	if err != nil {
-		return
+	containers := map[string]bson.ObjectID{}
+	if !settings.System.BastionNative {
+		containers, err = bastion.DockerGetRunning()
+		if err != nil {
+			return
+		}
 	}
 
 	for containerId, authrId := range containers {
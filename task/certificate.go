@@ -0,0 +1,171 @@
+package task
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/alert"
+	"github.com/pritunl/pritunl-zero/alertevent"
+	"github.com/pritunl/pritunl-zero/certificate"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/node"
+	"github.com/pritunl/pritunl-zero/settings"
+)
+
+var certificateCheck = &Task{
+	Name:    "certificate_check",
+	Version: 1,
+	Hours: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23},
+	Minutes: []int{30},
+	Handler: certificateCheckHandler,
+}
+
+func certificateExpireLevel(expiresOn time.Time) (
+	level int, message string) {
+
+	if expiresOn.IsZero() {
+		return
+	}
+
+	remaining := time.Until(expiresOn)
+	days := int(math.Floor(remaining.Hours() / 24))
+
+	if remaining <= 0 {
+		level = alert.High
+		message = fmt.Sprintf("Certificate expired on %s",
+			expiresOn.Format("2006-01-02"))
+		return
+	} else if days < settings.Certificate.ExpireAlertHighDays {
+		level = alert.High
+	} else if days < settings.Certificate.ExpireAlertMediumDays {
+		level = alert.Medium
+	} else if days < settings.Certificate.ExpireAlertLowDays {
+		level = alert.Low
+	} else {
+		return
+	}
+
+	message = fmt.Sprintf("Certificate expires in %d days on %s",
+		days, expiresOn.Format("2006-01-02"))
+
+	return
+}
+
+func certificateAlert(source bson.ObjectID, sourceName, resource string,
+	level int, message string) {
+
+	active := []bson.ObjectID{}
+
+	if level != 0 {
+		active = append(active, bson.NilObjectID)
+		alertevent.New(
+			settings.Certificate.AlertRoles,
+			source,
+			bson.NilObjectID,
+			resource,
+			"Certificate",
+			sourceName,
+			resource,
+			message,
+			level,
+			time.Duration(settings.Certificate.AlertFrequency)*time.Second,
+		)
+	}
+
+	alertevent.Clear(source, resource, []string{resource}, active)
+}
+
+func certificateCheckHandler(db *database.Database) (err error) {
+	if settings.Certificate.DisableExpireAlerts {
+		return
+	}
+
+	certs, err := certificate.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	certsMap := map[bson.ObjectID]*certificate.Certificate{}
+	for _, cert := range certs {
+		certsMap[cert.Id] = cert
+
+		expiresOn := time.Time{}
+		if cert.Info != nil {
+			expiresOn = cert.Info.ExpiresOn
+		}
+
+		level, message := certificateExpireLevel(expiresOn)
+		certificateAlert(cert.Id, cert.Name, alert.CertificateExpire,
+			level, message)
+
+		level = 0
+		message = ""
+		if cert.Type == certificate.LetsEncrypt &&
+			settings.Certificate.AcmeFailureAlerts > 0 &&
+			cert.AcmeFailures > 0 &&
+			cert.AcmeFailures >= settings.Certificate.AcmeFailureAlerts {
+
+			level = alert.Medium
+			message = fmt.Sprintf(
+				"Certificate renewal failed %d consecutive times",
+				cert.AcmeFailures,
+			)
+		}
+		certificateAlert(cert.Id, cert.Name, alert.CertificateRenewFailed,
+			level, message)
+	}
+
+	nodes, err := node.GetAll(db)
+	if err != nil {
+		return
+	}
+
+	for _, nde := range nodes {
+		if nde.Protocol != "https" {
+			continue
+		}
+
+		expiresOn := time.Time{}
+		if len(nde.Certificates) != 0 {
+			for _, certId := range nde.Certificates {
+				cert := certsMap[certId]
+				if cert == nil || cert.Type != certificate.LetsEncrypt ||
+					cert.Info == nil || cert.Info.ExpiresOn.IsZero() {
+
+					continue
+				}
+
+				if expiresOn.IsZero() || cert.Info.ExpiresOn.Before(
+					expiresOn) {
+
+					expiresOn = cert.Info.ExpiresOn
+				}
+			}
+		} else if nde.SelfCertificate != "" {
+			block, _ := pem.Decode([]byte(nde.SelfCertificate))
+			if block != nil {
+				selfCert, e := x509.ParseCertificate(block.Bytes)
+				if e == nil {
+					expiresOn = selfCert.NotAfter
+				}
+			}
+		} else {
+			continue
+		}
+
+		level, message := certificateExpireLevel(expiresOn)
+		certificateAlert(nde.Id, nde.Name, alert.CertificateExpire,
+			level, message)
+	}
+
+	return
+}
+
+func init() {
+	register(certificateCheck)
+}
@@ -0,0 +1,38 @@
+package task
+
+import (
+	"github.com/pritunl/pritunl-zero/command"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/sirupsen/logrus"
+)
+
+var commandExpire = &Task{
+	Name:    "command_expire",
+	Version: 1,
+	Hours: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23},
+	Minutes: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14,
+		15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29,
+		30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40, 41, 42, 43, 44,
+		45, 46, 47, 48, 49, 50, 51, 52, 53, 54, 55, 56, 57, 58, 59},
+	Handler: commandExpireHandler,
+}
+
+func commandExpireHandler(db *database.Database) (err error) {
+	count, err := command.ExpireRuns(db)
+	if err != nil {
+		return
+	}
+
+	if count > 0 {
+		logrus.WithFields(logrus.Fields{
+			"count": count,
+		}).Info("task: Expired endpoint command runs")
+	}
+
+	return
+}
+
+func init() {
+	register(commandExpire)
+}
@@ -0,0 +1,42 @@
+package task
+
+import (
+	"github.com/pritunl/pritunl-zero/alert"
+	"github.com/pritunl/pritunl-zero/alertevent"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/sirupsen/logrus"
+)
+
+var incidentResolve = &Task{
+	Name:    "incident_resolve",
+	Version: 1,
+	Hours: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23},
+	Minutes: []int{0, 5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55},
+	Handler: incidentResolveHandler,
+}
+
+func incidentResolveHandler(db *database.Database) (err error) {
+	// Event alerts have no clear condition and resolve once no longer seen,
+	// certificate alerts also resolve once the certificate is removed
+	count, err := alertevent.ResolveStale(db, []string{
+		alert.KmsgKeyword,
+		alert.CertificateExpire,
+		alert.CertificateRenewFailed,
+	})
+	if err != nil {
+		return
+	}
+
+	if count > 0 {
+		logrus.WithFields(logrus.Fields{
+			"count": count,
+		}).Info("task: Resolved stale alert incidents")
+	}
+
+	return
+}
+
+func init() {
+	register(incidentResolve)
+}
@@ -0,0 +1,44 @@
+package task
+
+import (
+	"time"
+
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/recording"
+	"github.com/pritunl/pritunl-zero/settings"
+	"github.com/sirupsen/logrus"
+)
+
+var recordingClean = &Task{
+	Name:    "recording_clean",
+	Version: 1,
+	Hours: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23},
+	Minutes: []int{35},
+	Handler: recordingCleanHandler,
+}
+
+func recordingCleanHandler(db *database.Database) (err error) {
+	ttl := settings.System.BastionRecordingTtl
+	if ttl <= 0 {
+		return
+	}
+
+	count, err := recording.RemoveExpired(
+		db, time.Duration(ttl)*24*time.Hour)
+	if err != nil {
+		return
+	}
+
+	if count > 0 {
+		logrus.WithFields(logrus.Fields{
+			"count": count,
+		}).Info("task: Removed expired session recordings")
+	}
+
+	return
+}
+
+func init() {
+	register(recordingClean)
+}
@@ -0,0 +1,33 @@
+package task
+
+import (
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/endpoints"
+)
+
+var endpointRollup = &Task{
+	Name:    "endpoint_rollup",
+	Version: 1,
+	Hours: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12,
+		13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23},
+	Minutes: []int{1, 6, 11, 16, 21, 26, 31, 36, 41, 46, 51, 56},
+	Handler: endpointRollupHandler,
+}
+
+func endpointRollupHandler(db *database.Database) (err error) {
+	err = endpoints.Rollup(db)
+	if err != nil {
+		return
+	}
+
+	err = endpoints.Prune(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func init() {
+	register(endpointRollup)
+}
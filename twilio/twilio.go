@@ -5,6 +5,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/twilio/twilio-go"
+	"github.com/twilio/twilio-go/client"
 
 	"github.com/dropbox/godropbox/errors"
 	"github.com/pritunl/pritunl-zero/errortypes"
@@ -19,9 +20,15 @@ type TwimlSay struct {
 	Message string   `xml:",chardata"`
 }
 
+type TwimlMessage struct {
+	XMLName xml.Name `xml:"Message"`
+	Message string   `xml:",chardata"`
+}
+
 type TwimlResponse struct {
-	XMLName xml.Name  `xml:"Response"`
-	Say     *TwimlSay `xml:"Say"`
+	XMLName xml.Name      `xml:"Response"`
+	Say     *TwimlSay     `xml:"Say"`
+	Message *TwimlMessage `xml:"Message"`
 }
 
 func PhoneCall(number, message string) (err error) {
@@ -117,3 +124,34 @@ func TextMessage(number, message string) (err error) {
 
 	return
 }
+
+func ValidateRequest(url string, params map[string]string,
+	signature string) bool {
+
+	if settings.System.TwilioSecret == "" || signature == "" {
+		return false
+	}
+
+	validator := client.NewRequestValidator(settings.System.TwilioSecret)
+
+	return validator.Validate(url, params, signature)
+}
+
+func MessageResponse(message string) (data []byte, err error) {
+	twiml := &TwimlResponse{}
+	if message != "" {
+		twiml.Message = &TwimlMessage{
+			Message: FilterStrMessage(message, 800),
+		}
+	}
+
+	data, err = xml.Marshal(twiml)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "twilio: Failed to marshal twiml message"),
+		}
+		return
+	}
+
+	return
+}
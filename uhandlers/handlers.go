@@ -26,6 +26,9 @@ func Register(engine *gin.Engine) {
 	dbGroup := engine.Group("")
 	dbGroup.Use(middlewear.Database)
 
+	limitGroup := dbGroup.Group("")
+	limitGroup.Use(middlewear.RateLimitAuth)
+
 	authrGroup := dbGroup.Group("")
 	authrGroup.Use(middlewear.HasAuthority)
 
@@ -44,15 +47,15 @@ func Register(engine *gin.Engine) {
 	engine.NoRoute(middlewear.NotFound)
 
 	engine.GET("/auth/state", authStateGet)
-	dbGroup.POST("/auth/session", authSessionPost)
-	dbGroup.POST("/auth/secondary", authSecondaryPost)
-	dbGroup.GET("/auth/request", authRequestGet)
-	dbGroup.GET("/auth/callback", authCallbackGet)
+	limitGroup.POST("/auth/session", authSessionPost)
+	limitGroup.POST("/auth/secondary", authSecondaryPost)
+	limitGroup.GET("/auth/request", authRequestGet)
+	limitGroup.GET("/auth/callback", authCallbackGet)
 	engine.GET("/auth/u2f/app.json", authU2fAppGet)
 	dbGroup.GET("/auth/webauthn/request", authWanRequestGet)
-	dbGroup.POST("/auth/webauthn/respond", authWanRespondPost)
+	limitGroup.POST("/auth/webauthn/respond", authWanRespondPost)
 	dbGroup.GET("/auth/webauthn/register", authWanRegisterGet)
-	dbGroup.POST("/auth/webauthn/register", authWanRegisterPost)
+	limitGroup.POST("/auth/webauthn/register", authWanRegisterPost)
 	sessGroup.GET("/logout", logoutGet)
 	sessGroup.GET("/logout_all", logoutAllGet)
 
@@ -85,6 +88,8 @@ func Register(engine *gin.Engine) {
 	authrGroup.PUT("/ssh/challenge", sshChallengePut)
 	authrGroup.POST("/ssh/challenge", sshChallengePost)
 	authrGroup.POST("/ssh/host", sshHostPost)
+	authrGroup.POST("/ssh/host/identity", sshHostIdentityPost)
+	authrGroup.POST("/ssh/host/bundle", sshHostBundlePost)
 
 	engine.GET("/robots.txt", middlewear.RobotsGet)
 
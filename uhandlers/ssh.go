@@ -13,6 +13,7 @@ import (
 	"github.com/pritunl/pritunl-zero/device"
 	"github.com/pritunl/pritunl-zero/errortypes"
 	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/identity"
 	"github.com/pritunl/pritunl-zero/secondary"
 	"github.com/pritunl/pritunl-zero/ssh"
 	"github.com/pritunl/pritunl-zero/utils"
@@ -691,3 +692,92 @@ func sshHostPost(c *gin.Context) {
 
 	c.JSON(200, resp)
 }
+
+type sshHostIdentityData struct {
+	Hostname  string             `json:"hostname"`
+	Port      int                `json:"port"`
+	Identity  *identity.Document `json:"identity"`
+	PublicKey string             `json:"public_key"`
+}
+
+func sshHostIdentityPost(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	data := &sshHostIdentityData{}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	hostname := domainRe.ReplaceAllString(data.Hostname, "")
+
+	cert, errData, err := ssh.NewHostIdentityCertificate(db, hostname,
+		data.Port, data.Identity, c.Request, data.PublicKey)
+	if err != nil {
+		switch err.(type) {
+		case *database.NotFoundError:
+			utils.AbortWithStatus(c, 404)
+			break
+		default:
+			utils.AbortWithError(c, 500, err)
+		}
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	resp := &sshHostCertificateData{
+		Certificates: cert.Certificates,
+	}
+
+	c.JSON(200, resp)
+}
+
+type sshHostBundleData struct {
+	Hostname string   `json:"hostname"`
+	Tokens   []string `json:"tokens"`
+}
+
+func sshHostBundlePost(c *gin.Context) {
+	db := c.MustGet("db").(*database.Database)
+	data := &sshHostBundleData{}
+
+	err := c.Bind(data)
+	if err != nil {
+		err = &errortypes.ParseError{
+			errors.Wrap(err, "handler: Bind error"),
+		}
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	hostname := domainRe.ReplaceAllString(data.Hostname, "")
+
+	bundle, errData, err := ssh.GetHostBundle(db, hostname, data.Tokens)
+	if err != nil {
+		utils.AbortWithError(c, 500, err)
+		return
+	}
+
+	if errData != nil {
+		c.JSON(400, errData)
+		return
+	}
+
+	etag := "\"" + bundle.Hash + "\""
+	c.Header("ETag", etag)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(304)
+		return
+	}
+
+	c.JSON(200, bundle)
+}
@@ -20,24 +20,28 @@ import (
 )
 
 type User struct {
-	Id              bson.ObjectID         `bson:"_id,omitempty" json:"id"`
-	Type            string                `bson:"type" json:"type"`
-	Provider        bson.ObjectID         `bson:"provider" json:"provider"`
-	Username        string                `bson:"username" json:"username"`
-	Password        string                `bson:"password" json:"-"`
-	DefaultPassword string                `bson:"default_password" json:"-"`
-	Token           string                `bson:"token" json:"token"`
-	Secret          string                `bson:"secret" json:"secret"`
-	Theme           string                `bson:"theme" json:"-"`
-	EditorTheme     string                `bson:"editor_theme" json:"-"`
-	LastActive      time.Time             `bson:"last_active" json:"last_active"`
-	LastSync        time.Time             `bson:"last_sync" json:"last_sync"`
-	Roles           []string              `bson:"roles" json:"roles"`
-	Administrator   string                `bson:"administrator" json:"administrator"`
-	Disabled        bool                  `bson:"disabled" json:"disabled"`
-	ActiveUntil     time.Time             `bson:"active_until" json:"active_until"`
-	Permissions     []string              `bson:"permissions" json:"permissions"`
-	WanCredentials  []webauthn.Credential `bson:"-" json:"-"`
+	Id                bson.ObjectID         `bson:"_id,omitempty" json:"id"`
+	Type              string                `bson:"type" json:"type"`
+	Provider          bson.ObjectID         `bson:"provider" json:"provider"`
+	Username          string                `bson:"username" json:"username"`
+	Password          string                `bson:"password" json:"-"`
+	DefaultPassword   string                `bson:"default_password" json:"-"`
+	Token             string                `bson:"token" json:"token"`
+	Secret            string                `bson:"secret" json:"secret"`
+	Theme             string                `bson:"theme" json:"-"`
+	EditorTheme       string                `bson:"editor_theme" json:"-"`
+	LastActive        time.Time             `bson:"last_active" json:"last_active"`
+	LastSync          time.Time             `bson:"last_sync" json:"last_sync"`
+	Roles             []string              `bson:"roles" json:"roles"`
+	Administrator     string                `bson:"administrator" json:"administrator"`
+	Disabled          bool                  `bson:"disabled" json:"disabled"`
+	ActiveUntil       time.Time             `bson:"active_until" json:"active_until"`
+	Locked            bool                  `bson:"locked" json:"locked"`
+	LockedUntil       time.Time             `bson:"locked_until" json:"locked_until"`
+	LoginFailures     int                   `bson:"login_failures" json:"login_failures"`
+	LoginFailureStart time.Time             `bson:"login_failure_start" json:"-"`
+	Permissions       []string              `bson:"permissions" json:"permissions"`
+	WanCredentials    []webauthn.Credential `bson:"-" json:"-"`
 }
 
 func (u *User) Validate(db *database.Database) (
@@ -123,6 +127,13 @@ func (u *User) Format() {
 	u.Roles = roles
 }
 
+// Lock is active until the locked until time or until an administrator
+// unlocks the user when unset
+func (u *User) IsLocked() bool {
+	return u.Locked && (u.LockedUntil.IsZero() ||
+		time.Now().Before(u.LockedUntil))
+}
+
 func (u *User) Commit(db *database.Database) (err error) {
 	coll := db.Users()
 
@@ -213,6 +213,18 @@ func Remove(db *database.Database, userIds []bson.ObjectID) (
 		return
 	}
 
+	coll = db.ClientCertificates()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"user_id": &bson.M{
+			"$in": userIds,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
 	coll = db.Users()
 
 	_, err = coll.DeleteMany(db, &bson.M{
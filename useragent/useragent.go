@@ -344,6 +344,39 @@ func Parse(db *database.Database, r *http.Request) (agnt *Agent, err error) {
 	return
 }
 
+func ParseAddr(db *database.Database, ip string) (
+	agnt *Agent, err error) {
+
+	if settings.System.Demo {
+		return
+	}
+
+	ge, err := geo.Get(db, ip)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{
+			"error": err,
+		}).Error("agent: Failed to get geo IP information")
+		err = nil
+		return
+	}
+
+	agnt = &Agent{
+		Ip:            ip,
+		Isp:           ge.Isp,
+		Continent:     ge.Continent,
+		ContinentCode: ge.ContinentCode,
+		Country:       ge.Country,
+		CountryCode:   ge.CountryCode,
+		Region:        ge.Region,
+		RegionCode:    ge.RegionCode,
+		City:          ge.City,
+		Longitude:     ge.Longitude,
+		Latitude:      ge.Latitude,
+	}
+
+	return
+}
+
 func (a *Agent) Diff(agnt *Agent) bool {
 	if a.OperatingSystem != agnt.OperatingSystem ||
 		a.Browser != agnt.Browser ||
@@ -0,0 +1,69 @@
+package view
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/event"
+	"github.com/pritunl/pritunl-zero/requires"
+)
+
+var (
+	members          map[bson.ObjectID][]bson.ObjectID
+	membersTimestamp time.Time
+	membersLock      = sync.Mutex{}
+)
+
+const (
+	cacheTtl = 30 * time.Second
+)
+
+// Views of each endpoint for views targeted by a check or alert, the
+// membership of all endpoints is computed once per cache ttl
+func getMembers(db *database.Database) (
+	mbrs map[bson.ObjectID][]bson.ObjectID, err error) {
+
+	membersLock.Lock()
+	defer membersLock.Unlock()
+
+	if members != nil && time.Since(membersTimestamp) < cacheTtl {
+		mbrs = members
+		return
+	}
+
+	mbrs, err = getMembership(db)
+	if err != nil {
+		return
+	}
+
+	members = mbrs
+	membersTimestamp = time.Now()
+
+	return
+}
+
+func ClearCache() {
+	membersLock.Lock()
+	members = nil
+	membersLock.Unlock()
+}
+
+func callback(evt *event.EventPublish) {
+	ClearCache()
+}
+
+func init() {
+	module := requires.New("view")
+	module.After("settings")
+	module.Before("event")
+
+	module.Handler = func() (err error) {
+		event.Register("view.change", callback)
+		event.Register("alert.change", callback)
+		event.Register("check.change", callback)
+		event.Register("endpoint.change", callback)
+		return
+	}
+}
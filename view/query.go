@@ -0,0 +1,526 @@
+package view
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dropbox/godropbox/errors"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/alertevent"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+)
+
+const (
+	tokenTerm = iota
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+const (
+	AlertOpen = "open"
+	AlertNone = "none"
+
+	maxDepth = 32
+)
+
+var tagKeyReg = regexp.MustCompile("^[a-zA-Z0-9_-]+$")
+
+var queryFields = map[string]string{
+	"name":           "name",
+	"role":           "roles",
+	"platform":       "data.platform",
+	"version":        "data.version",
+	"virtualization": "data.virtualization",
+	"hostname":       "data.hostname",
+}
+
+type token struct {
+	Type  int
+	Value string
+}
+
+// Query node, a term when Field is set otherwise an and/or of the
+// children
+type node struct {
+	Op       int
+	Not      bool
+	Field    string
+	Key      string
+	Value    string
+	Children []*node
+}
+
+type parser struct {
+	tokens []*token
+	pos    int
+	depth  int
+}
+
+func tokenize(query string) (tokens []*token, err error) {
+	tokens = []*token{}
+	runes := []rune(query)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			i += 1
+			continue
+		case r == '(':
+			tokens = append(tokens, &token{Type: tokenOpen})
+			i += 1
+			continue
+		case r == ')':
+			tokens = append(tokens, &token{Type: tokenClose})
+			i += 1
+			continue
+		case r == '-':
+			tokens = append(tokens, &token{Type: tokenNot})
+			i += 1
+			continue
+		}
+
+		value := []rune{}
+		quoted := false
+		for i < len(runes) {
+			r = runes[i]
+			if r == '"' {
+				quoted = !quoted
+				i += 1
+				continue
+			}
+			if !quoted && (r == ' ' || r == '\t' || r == '\n' ||
+				r == '(' || r == ')') {
+
+				break
+			}
+			value = append(value, r)
+			i += 1
+		}
+
+		if quoted {
+			err = &errortypes.ParseError{
+				errors.New("view: Unterminated quote in query"),
+			}
+			return
+		}
+
+		switch string(value) {
+		case "AND":
+			tokens = append(tokens, &token{Type: tokenAnd})
+			break
+		case "OR":
+			tokens = append(tokens, &token{Type: tokenOr})
+			break
+		case "NOT":
+			tokens = append(tokens, &token{Type: tokenNot})
+			break
+		default:
+			tokens = append(tokens, &token{
+				Type:  tokenTerm,
+				Value: string(value),
+			})
+		}
+	}
+
+	return
+}
+
+func (p *parser) peek() *token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) parseOr() (nde *node, err error) {
+	nde = &node{
+		Op: tokenOr,
+	}
+
+	for {
+		child, e := p.parseAnd()
+		if e != nil {
+			err = e
+			return
+		}
+		nde.Children = append(nde.Children, child)
+
+		tok := p.peek()
+		if tok == nil || tok.Type != tokenOr {
+			break
+		}
+		p.pos += 1
+	}
+
+	if len(nde.Children) == 1 {
+		nde = nde.Children[0]
+	}
+
+	return
+}
+
+func (p *parser) parseAnd() (nde *node, err error) {
+	nde = &node{
+		Op: tokenAnd,
+	}
+
+	for {
+		tok := p.peek()
+		if tok == nil || tok.Type == tokenOr || tok.Type == tokenClose {
+			break
+		}
+		if tok.Type == tokenAnd {
+			p.pos += 1
+			continue
+		}
+
+		child, e := p.parseUnary()
+		if e != nil {
+			err = e
+			return
+		}
+		nde.Children = append(nde.Children, child)
+	}
+
+	if len(nde.Children) == 0 {
+		err = &errortypes.ParseError{
+			errors.New("view: Empty query expression"),
+		}
+		return
+	}
+
+	if len(nde.Children) == 1 {
+		nde = nde.Children[0]
+	}
+
+	return
+}
+
+func (p *parser) parseUnary() (nde *node, err error) {
+	tok := p.peek()
+	p.pos += 1
+
+	if tok.Type == tokenNot || tok.Type == tokenOpen {
+		p.depth += 1
+		defer func() {
+			p.depth -= 1
+		}()
+
+		if p.depth > maxDepth {
+			err = &errortypes.ParseError{
+				errors.New("view: Query nested too deeply"),
+			}
+			return
+		}
+	}
+
+	switch tok.Type {
+	case tokenNot:
+		if p.peek() == nil {
+			err = &errortypes.ParseError{
+				errors.New("view: Missing expression after negation"),
+			}
+			return
+		}
+
+		nde, err = p.parseUnary()
+		if err != nil {
+			return
+		}
+		nde = &node{
+			Op:       tokenAnd,
+			Not:      true,
+			Children: []*node{nde},
+		}
+		return
+	case tokenOpen:
+		nde, err = p.parseOr()
+		if err != nil {
+			return
+		}
+
+		tok = p.peek()
+		if tok == nil || tok.Type != tokenClose {
+			err = &errortypes.ParseError{
+				errors.New("view: Missing closing parenthesis"),
+			}
+			return
+		}
+		p.pos += 1
+		return
+	case tokenTerm:
+		nde, err = parseTerm(tok.Value)
+		return
+	default:
+		err = &errortypes.ParseError{
+			errors.New("view: Unexpected token in query"),
+		}
+		return
+	}
+}
+
+func parseTerm(value string) (nde *node, err error) {
+	nde = &node{
+		Op: tokenTerm,
+	}
+
+	index := strings.Index(value, ":")
+	if index == -1 {
+		nde.Field = "name"
+		nde.Value = value
+		return
+	}
+
+	nde.Field = strings.ToLower(value[:index])
+	nde.Value = value[index+1:]
+
+	switch nde.Field {
+	case "tag":
+		index = strings.Index(nde.Value, "=")
+		if index == -1 {
+			nde.Key = nde.Value
+			nde.Value = ""
+		} else {
+			nde.Key = nde.Value[:index]
+			nde.Value = nde.Value[index+1:]
+		}
+
+		if !tagKeyReg.MatchString(nde.Key) {
+			err = &errortypes.ParseError{
+				errors.Newf("view: Invalid tag key '%s'", nde.Key),
+			}
+			return
+		}
+		break
+	case "alert":
+		if nde.Value == "" {
+			err = &errortypes.ParseError{
+				errors.New("view: Missing alert state"),
+			}
+			return
+		}
+
+		switch strings.ToLower(nde.Value) {
+		case alertevent.Firing, alertevent.Acknowledged, AlertOpen, AlertNone:
+			break
+		default:
+			err = &errortypes.ParseError{
+				errors.Newf("view: Unknown alert state '%s'", nde.Value),
+			}
+			return
+		}
+		break
+	default:
+		if queryFields[nde.Field] == "" {
+			err = &errortypes.ParseError{
+				errors.Newf("view: Unknown query field '%s'", nde.Field),
+			}
+			return
+		}
+
+		if nde.Value == "" {
+			err = &errortypes.ParseError{
+				errors.Newf("view: Missing value for '%s'", nde.Field),
+			}
+			return
+		}
+	}
+
+	return
+}
+
+// Parse a fleet query such as
+// `tag:env=prod platform:ubuntu* -(role:db OR alert:firing)`. Terms are
+// combined with AND unless separated by OR, a leading - or NOT negates.
+func parse(query string) (nde *node, err error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return
+	}
+
+	tokens, err := tokenize(query)
+	if err != nil {
+		return
+	}
+
+	prsr := &parser{
+		tokens: tokens,
+	}
+
+	nde, err = prsr.parseOr()
+	if err != nil {
+		return
+	}
+
+	if prsr.peek() != nil {
+		err = &errortypes.ParseError{
+			errors.New("view: Unexpected closing parenthesis"),
+		}
+		return
+	}
+
+	return
+}
+
+func matchValue(value string) interface{} {
+	parts := strings.Split(value, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	return &bson.M{
+		"$regex":   fmt.Sprintf("^%s$", strings.Join(parts, ".*")),
+		"$options": "i",
+	}
+}
+
+func alertSources(db *database.Database, state string) (
+	sourceIds []bson.ObjectID, err error) {
+
+	query := &bson.M{}
+
+	switch state {
+	case alertevent.Firing, alertevent.Acknowledged:
+		(*query)["state"] = state
+		break
+	case AlertOpen, AlertNone:
+		break
+	}
+
+	sourceIds, err = alertevent.GetOpenSources(db, query)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (n *node) compile(db *database.Database) (
+	query *bson.M, err error) {
+
+	switch n.Op {
+	case tokenTerm:
+		switch n.Field {
+		case "tag":
+			if n.Value == "" {
+				query = &bson.M{
+					"tags." + n.Key: &bson.M{
+						"$exists": true,
+					},
+				}
+			} else {
+				query = &bson.M{
+					"tags." + n.Key: matchValue(n.Value),
+				}
+			}
+			break
+		case "alert":
+			sourceIds, e := alertSources(db, strings.ToLower(n.Value))
+			if e != nil {
+				err = e
+				return
+			}
+
+			if strings.ToLower(n.Value) == AlertNone {
+				query = &bson.M{
+					"_id": &bson.M{
+						"$nin": sourceIds,
+					},
+				}
+			} else {
+				query = &bson.M{
+					"_id": &bson.M{
+						"$in": sourceIds,
+					},
+				}
+			}
+			break
+		case "name":
+			if !strings.Contains(n.Value, "*") {
+				query = &bson.M{
+					"name": &bson.M{
+						"$regex": fmt.Sprintf(".*%s.*",
+							regexp.QuoteMeta(n.Value)),
+						"$options": "i",
+					},
+				}
+				break
+			}
+
+			query = &bson.M{
+				"name": matchValue(n.Value),
+			}
+			break
+		default:
+			query = &bson.M{
+				queryFields[n.Field]: matchValue(n.Value),
+			}
+		}
+		break
+	default:
+		children := []*bson.M{}
+		for _, child := range n.Children {
+			childQuery, e := child.compile(db)
+			if e != nil {
+				err = e
+				return
+			}
+			children = append(children, childQuery)
+		}
+
+		if n.Not {
+			query = &bson.M{
+				"$nor": children,
+			}
+		} else if n.Op == tokenOr {
+			query = &bson.M{
+				"$or": children,
+			}
+		} else {
+			query = &bson.M{
+				"$and": children,
+			}
+		}
+	}
+
+	return
+}
+
+// Validate the syntax of a fleet query
+func ParseQuery(query string) (err error) {
+	_, err = parse(query)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+// Compile a fleet query into an endpoints collection query
+func CompileQuery(db *database.Database, query string) (
+	dbQuery *bson.M, err error) {
+
+	nde, err := parse(query)
+	if err != nil {
+		return
+	}
+
+	if nde == nil {
+		dbQuery = &bson.M{}
+		return
+	}
+
+	dbQuery, err = nde.compile(db)
+	if err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,317 @@
+package view
+
+import (
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/mongo-go-driver/v2/mongo/options"
+	"github.com/pritunl/pritunl-zero/database"
+)
+
+func Get(db *database.Database, viewId bson.ObjectID) (
+	vw *View, err error) {
+
+	coll := db.Views()
+	vw = &View{}
+
+	err = coll.FindOneId(viewId, vw)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func GetMulti(db *database.Database, viewIds []bson.ObjectID) (
+	views []*View, err error) {
+
+	coll := db.Views()
+	views = []*View{}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{
+			"_id": &bson.M{
+				"$in": viewIds,
+			},
+		},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		vw := &View{}
+		err = cursor.Decode(vw)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		views = append(views, vw)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAll(db *database.Database) (views []*View, err error) {
+	coll := db.Views()
+	views = []*View{}
+
+	cursor, err := coll.Find(
+		db,
+		&bson.M{},
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		vw := &View{}
+		err = cursor.Decode(vw)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		views = append(views, vw)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+func GetAllPaged(db *database.Database, query *bson.M,
+	page, pageCount int64) (views []*View, count int64, err error) {
+
+	coll := db.Views()
+	views = []*View{}
+
+	if len(*query) == 0 {
+		count, err = coll.EstimatedDocumentCount(db)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	} else {
+		count, err = coll.CountDocuments(db, query)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	if pageCount == 0 {
+		pageCount = 20
+	}
+	maxPage := count / pageCount
+	if count == pageCount {
+		maxPage = 0
+	}
+	page = min(page, maxPage)
+	skip := min(page*pageCount, count)
+
+	cursor, err := coll.Find(
+		db,
+		query,
+		options.Find().
+			SetSort(bson.D{{"name", 1}}).
+			SetSkip(skip).
+			SetLimit(pageCount),
+	)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+	defer cursor.Close(db)
+
+	for cursor.Next(db) {
+		vw := &View{}
+		err = cursor.Decode(vw)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		views = append(views, vw)
+	}
+
+	err = cursor.Err()
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}
+
+// Views matching the endpoint, only views targeted by a check or alert
+// are evaluated
+func GetMatching(db *database.Database, endpointId bson.ObjectID) (
+	viewIds []bson.ObjectID, err error) {
+
+	mbrs, err := getMembers(db)
+	if err != nil {
+		return
+	}
+
+	viewIds = mbrs[endpointId]
+	if viewIds == nil {
+		viewIds = []bson.ObjectID{}
+	}
+
+	return
+}
+
+func getMembership(db *database.Database) (
+	mbrs map[bson.ObjectID][]bson.ObjectID, err error) {
+
+	mbrs = map[bson.ObjectID][]bson.ObjectID{}
+
+	targeted := []bson.ObjectID{}
+	for _, coll := range []*database.Collection{db.Alerts(), db.Checks()} {
+		ids := []bson.ObjectID{}
+		err = coll.Distinct(
+			db,
+			"views",
+			&bson.M{},
+		).Decode(&ids)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		targeted = append(targeted, ids...)
+	}
+
+	if len(targeted) == 0 {
+		return
+	}
+
+	views, err := GetMulti(db, targeted)
+	if err != nil {
+		return
+	}
+
+	coll := db.Endpoints()
+	for _, vw := range views {
+		query, e := vw.Compile(db)
+		if e != nil {
+			err = e
+			return
+		}
+
+		endpointIds := []bson.ObjectID{}
+		err = coll.Distinct(db, "_id", query).Decode(&endpointIds)
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+
+		for _, endpointId := range endpointIds {
+			mbrs[endpointId] = append(mbrs[endpointId], vw.Id)
+		}
+	}
+
+	return
+}
+
+// Endpoints query matching endpoints with one of the roles or in one of
+// the views
+func GetTargetsQuery(db *database.Database, roles []string,
+	viewIds []bson.ObjectID) (query *bson.M, err error) {
+
+	if roles == nil {
+		roles = []string{}
+	}
+
+	queries := []*bson.M{
+		&bson.M{
+			"roles": &bson.M{
+				"$in": roles,
+			},
+		},
+	}
+
+	if len(viewIds) > 0 {
+		views, e := GetMulti(db, viewIds)
+		if e != nil {
+			err = e
+			return
+		}
+
+		for _, vw := range views {
+			vwQuery, e := vw.Compile(db)
+			if e != nil {
+				err = e
+				return
+			}
+
+			queries = append(queries, vwQuery)
+		}
+	}
+
+	query = &bson.M{
+		"$or": queries,
+	}
+
+	return
+}
+
+func Remove(db *database.Database, viewId bson.ObjectID) (err error) {
+	err = RemoveMulti(db, []bson.ObjectID{viewId})
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func RemoveMulti(db *database.Database, viewIds []bson.ObjectID) (
+	err error) {
+
+	coll := db.Views()
+
+	_, err = coll.DeleteMany(db, &bson.M{
+		"_id": &bson.M{
+			"$in": viewIds,
+		},
+	})
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	for _, coll = range []*database.Collection{db.Alerts(), db.Checks()} {
+		_, err = coll.UpdateMany(db, &bson.M{
+			"views": &bson.M{
+				"$in": viewIds,
+			},
+		}, &bson.M{
+			"$pull": &bson.M{
+				"views": &bson.M{
+					"$in": viewIds,
+				},
+			},
+		})
+		if err != nil {
+			err = database.ParseError(err)
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,93 @@
+package view
+
+import (
+	"github.com/dropbox/godropbox/container/set"
+	"github.com/pritunl/mongo-go-driver/v2/bson"
+	"github.com/pritunl/pritunl-zero/database"
+	"github.com/pritunl/pritunl-zero/errortypes"
+	"github.com/pritunl/pritunl-zero/utils"
+)
+
+type View struct {
+	Id      bson.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name    string        `bson:"name" json:"name"`
+	Comment string        `bson:"comment" json:"comment"`
+	Query   string        `bson:"query" json:"query"`
+}
+
+func (v *View) Validate(db *database.Database) (
+	errData *errortypes.ErrorData, err error) {
+
+	v.Name = utils.FilterName(v.Name)
+
+	if v.Id.IsZero() {
+		v.Id, err = utils.RandObjectId()
+		if err != nil {
+			return
+		}
+	}
+
+	if len(v.Query) > 4096 {
+		errData = &errortypes.ErrorData{
+			Error:   "view_query_invalid",
+			Message: "View query too long",
+		}
+		return
+	}
+
+	e := ParseQuery(v.Query)
+	if e != nil {
+		errData = &errortypes.ErrorData{
+			Error:   "view_query_invalid",
+			Message: errortypes.GetErrorMessage(e),
+		}
+		return
+	}
+
+	return
+}
+
+func (v *View) Compile(db *database.Database) (query *bson.M, err error) {
+	query, err = CompileQuery(db, v.Query)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (v *View) Commit(db *database.Database) (err error) {
+	coll := db.Views()
+
+	err = coll.Commit(v.Id, v)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (v *View) CommitFields(db *database.Database, fields set.Set) (
+	err error) {
+
+	coll := db.Views()
+
+	err = coll.CommitFields(v.Id, v, fields)
+	if err != nil {
+		return
+	}
+
+	return
+}
+
+func (v *View) Insert(db *database.Database) (err error) {
+	coll := db.Views()
+
+	_, err = coll.InsertOne(db, v)
+	if err != nil {
+		err = database.ParseError(err)
+		return
+	}
+
+	return
+}